@@ -0,0 +1,32 @@
+// When builder auth is enabled, the session's CSRF token is readable in a cookie.
+// Send it with every state-changing request (htmx and fetch) and enable sign out.
+(function () {
+    const match = document.cookie.match(/(?:^|;\s*)builder_csrf=([^;]+)/);
+    if (!match) return;
+    const token = decodeURIComponent(match[1]);
+
+    document.addEventListener('htmx:configRequest', function (evt) {
+        evt.detail.headers['X-CSRF-Token'] = token;
+    });
+
+    const nativeFetch = window.fetch;
+    window.fetch = function (input, init = {}) {
+        const method = (init.method || (input instanceof Request ? input.method : 'GET')).toUpperCase();
+        if (method !== 'GET' && method !== 'HEAD') {
+            const headers = new Headers(init.headers || (input instanceof Request ? input.headers : undefined));
+            headers.set('X-CSRF-Token', token);
+            init = { ...init, headers };
+        }
+        return nativeFetch(input, init);
+    };
+
+    document.addEventListener('DOMContentLoaded', function () {
+        document.querySelectorAll('.logout-btn').forEach(btn => {
+            btn.hidden = false;
+            btn.addEventListener('click', async function () {
+                await fetch('/logout', { method: 'POST' });
+                window.location.href = '/login';
+            });
+        });
+    });
+})();
@@ -0,0 +1,58 @@
+// Background jobs (site generation, image processing) run on the server; their
+// progress is streamed from /api/jobs/events. builderJobs lets pages follow them.
+(function () {
+    const listeners = [];
+    let source = null;
+    let jobs = [];
+
+    function connect() {
+        // EventSource reconnects by itself when the connection drops
+        source = new EventSource('/api/jobs/events');
+        source.addEventListener('jobs', function (evt) {
+            jobs = JSON.parse(evt.data);
+            listeners.forEach(fn => fn(jobs));
+        });
+    }
+
+    function finished(job) {
+        return job.status !== 'running';
+    }
+
+    window.builderJobs = {
+        // onChange calls fn with the job list (newest first) whenever a job changes
+        onChange(fn) {
+            listeners.push(fn);
+            if (!source) connect();
+            else fn(jobs);
+        },
+
+        // wait resolves with the job once it finished, calling onProgress meanwhile
+        wait(id, onProgress) {
+            return new Promise(resolve => {
+                const check = list => {
+                    const job = list.find(j => j.id === id);
+                    if (!job) return;
+                    if (finished(job)) {
+                        const i = listeners.indexOf(check);
+                        if (i >= 0) listeners.splice(i, 1);
+                        resolve(job);
+                    } else if (onProgress) {
+                        onProgress(job);
+                    }
+                };
+                this.onChange(check);
+            });
+        },
+
+        async cancel(id) {
+            await fetch(`/api/jobs/cancel?id=${encodeURIComponent(id)}`, { method: 'POST' });
+        },
+
+        // progressText describes a job's progress, e.g. "3/7 · project portraits"
+        progressText(job) {
+            if (!job.total) return job.status === 'running' ? 'starting…' : job.status;
+            const step = job.step && job.step !== 'done' ? ` · ${job.step}` : '';
+            return `${job.done}/${job.total}${step}`;
+        }
+    };
+})();
@@ -31,6 +31,9 @@
         initializeNavbar();
         initializeScrollBehavior();
         initializeRevealAnimations();
+        initializeGalleryVideos();
+        initializeLightbox();
+        initializeProjectFilters();
     });
 
     /**
@@ -140,6 +143,230 @@
         }, 230);
     }
 
+    /**
+     * Stop looping gallery videos for visitors who prefer reduced motion,
+     * leaving the poster and the controls to play them
+     */
+    function initializeGalleryVideos() {
+        if (!window.matchMedia('(prefers-reduced-motion: reduce)').matches) return;
+        document.querySelectorAll('.gallery-item video[autoplay]').forEach(video => {
+            video.removeAttribute('autoplay');
+            video.pause();
+            video.controls = true;
+        });
+    }
+
+    /**
+     * Full-screen viewer for the photos of a project page. The photos, in grid
+     * order, come from the page's lightbox data; #photo-<hash> opens one directly.
+     */
+    function initializeLightbox() {
+        const dataElement = document.getElementById('lightbox-data');
+        if (!dataElement) return;
+        const photos = JSON.parse(dataElement.textContent) || [];
+        if (photos.length === 0) return;
+
+        const lightbox = document.createElement('div');
+        lightbox.className = 'lightbox';
+        lightbox.setAttribute('role', 'dialog');
+        lightbox.setAttribute('aria-modal', 'true');
+        lightbox.setAttribute('aria-label', 'Photo viewer');
+        lightbox.innerHTML = `
+            <div class="lightbox-stage"></div>
+            <button type="button" class="lightbox-button lightbox-close" aria-label="Close">&times;</button>
+            <button type="button" class="lightbox-button lightbox-prev" aria-label="Previous photo">&lsaquo;</button>
+            <button type="button" class="lightbox-button lightbox-next" aria-label="Next photo">&rsaquo;</button>
+            <div class="lightbox-bar">
+                <span class="lightbox-counter"></span>
+                <button type="button" class="lightbox-info" aria-pressed="true">Info</button>
+                <a class="lightbox-permalink">Link</a>
+            </div>
+            <div class="lightbox-details"></div>`;
+        document.body.appendChild(lightbox);
+
+        const stage = lightbox.querySelector('.lightbox-stage');
+        const details = lightbox.querySelector('.lightbox-details');
+        const infoButton = lightbox.querySelector('.lightbox-info');
+        let current = -1;
+        let lastFocus = null;
+        let showDetails = true;
+
+        function indexOf(hashId) {
+            return photos.findIndex(photo => photo.id === hashId);
+        }
+
+        function show(index) {
+            const photo = photos[index];
+            current = index;
+            stage.replaceChildren();
+
+            let media;
+            if (photo.video) {
+                media = document.createElement('video');
+                media.src = photo.video;
+                media.poster = photo.src;
+                media.controls = true;
+                media.autoplay = true;
+                media.playsInline = true;
+            } else {
+                media = document.createElement('img');
+                media.src = photo.src;
+                media.alt = photo.alt;
+            }
+            stage.appendChild(media);
+
+            // Fetch the neighbours so that navigating does not wait for them
+            [index - 1, index + 1].forEach(i => {
+                if (photos[i] && !photos[i].video) new Image().src = photos[i].src;
+            });
+
+            lightbox.querySelector('.lightbox-prev').hidden = index === 0;
+            lightbox.querySelector('.lightbox-next').hidden = index === photos.length - 1;
+            lightbox.querySelector('.lightbox-counter').textContent = `${index + 1} / ${photos.length}`;
+            lightbox.querySelector('.lightbox-permalink').href = photo.url;
+            renderDetails(photo);
+
+            history.replaceState(null, '', '#photo-' + photo.id);
+        }
+
+        function renderDetails(photo) {
+            details.replaceChildren();
+            const exif = photo.exif || {};
+            const settings = [exif.focalLength, exif.aperture, exif.exposureTime, exif.iso].filter(Boolean).join(' · ');
+            const lines = [
+                [photo.caption, 'lightbox-caption'],
+                [[exif.camera, exif.lens].filter(Boolean).join(' · '), 'lightbox-exif'],
+                [settings, 'lightbox-exif']
+            ];
+            lines.forEach(([text, className]) => {
+                if (!text) return;
+                const p = document.createElement('p');
+                p.className = className;
+                p.textContent = text;
+                details.appendChild(p);
+            });
+            infoButton.hidden = details.childElementCount === 0;
+            details.hidden = !showDetails || details.childElementCount === 0;
+        }
+
+        function open(index) {
+            if (current === -1) {
+                lastFocus = document.activeElement;
+                lightbox.classList.add('open');
+                document.documentElement.classList.add('lightbox-open');
+            }
+            show(index);
+            lightbox.querySelector('.lightbox-close').focus();
+        }
+
+        function close() {
+            if (current === -1) return;
+            current = -1;
+            stage.replaceChildren(); // Stops videos
+            lightbox.classList.remove('open');
+            document.documentElement.classList.remove('lightbox-open');
+            history.replaceState(null, '', location.pathname + location.search);
+            if (lastFocus) lastFocus.focus();
+        }
+
+        function step(delta) {
+            const index = current + delta;
+            if (current !== -1 && index >= 0 && index < photos.length) show(index);
+        }
+
+        function openFromHash() {
+            const match = location.hash.match(/^#photo-([0-9a-f]+)$/);
+            const index = match ? indexOf(match[1]) : -1;
+            if (index !== -1) {
+                open(index);
+            } else {
+                close();
+            }
+        }
+
+        // Open from the grid, except when using a video's own controls
+        document.querySelectorAll('.gallery-item[data-photo]').forEach(item => {
+            item.addEventListener('click', e => {
+                if (e.target.closest('video[controls]')) return;
+                const index = indexOf(item.dataset.photo);
+                if (index !== -1) open(index);
+            });
+        });
+
+        lightbox.querySelector('.lightbox-close').addEventListener('click', close);
+        lightbox.querySelector('.lightbox-prev').addEventListener('click', () => step(-1));
+        lightbox.querySelector('.lightbox-next').addEventListener('click', () => step(1));
+        infoButton.addEventListener('click', () => {
+            showDetails = !showDetails;
+            infoButton.setAttribute('aria-pressed', String(showDetails));
+            renderDetails(photos[current]);
+        });
+        stage.addEventListener('click', e => {
+            if (e.target === stage) close(); // Click on the backdrop
+        });
+
+        // The visible controls of the lightbox, in tab order
+        function focusable() {
+            return Array.from(lightbox.querySelectorAll('button, a[href], video[controls]'))
+                .filter(el => !el.hidden && el.getClientRects().length > 0);
+        }
+
+        // Keep focus inside the open lightbox: Tab wraps around its controls
+        // and focus moving to the page behind is pulled back
+        function trapFocus(e) {
+            const elements = focusable();
+            if (elements.length === 0) return;
+            const first = elements[0];
+            const last = elements[elements.length - 1];
+            if (e.shiftKey && (document.activeElement === first || !lightbox.contains(document.activeElement))) {
+                last.focus();
+            } else if (!e.shiftKey && (document.activeElement === last || !lightbox.contains(document.activeElement))) {
+                first.focus();
+            } else {
+                return;
+            }
+            e.preventDefault();
+        }
+        document.addEventListener('focusin', e => {
+            if (current !== -1 && !lightbox.contains(e.target)) {
+                lightbox.querySelector('.lightbox-close').focus();
+            }
+        });
+
+        document.addEventListener('keydown', e => {
+            if (current === -1) return;
+            switch (e.key) {
+                case 'Tab': trapFocus(e); return;
+                case 'Escape': close(); break;
+                case 'ArrowLeft': step(-1); break;
+                case 'ArrowRight': step(1); break;
+                case 'i': infoButton.click(); break;
+                default: return;
+            }
+            e.preventDefault();
+        });
+
+        // Swipe left and right to navigate, down to close
+        let touchStart = null;
+        lightbox.addEventListener('touchstart', e => {
+            touchStart = e.touches.length === 1 ? { x: e.touches[0].clientX, y: e.touches[0].clientY } : null;
+        }, { passive: true });
+        lightbox.addEventListener('touchend', e => {
+            if (!touchStart) return;
+            const dx = e.changedTouches[0].clientX - touchStart.x;
+            const dy = e.changedTouches[0].clientY - touchStart.y;
+            touchStart = null;
+            if (Math.abs(dx) > 50 && Math.abs(dx) > Math.abs(dy)) {
+                step(dx < 0 ? 1 : -1);
+            } else if (dy > 80 && dy > Math.abs(dx)) {
+                close();
+            }
+        });
+
+        window.addEventListener('hashchange', openFromHash);
+        openFromHash();
+    }
+
     /**
      * Initialize reveal animations for page elements
      */
@@ -262,4 +489,26 @@
         }
     }
 
+    /**
+     * Initialize category filters of the project menus: show only the
+     * projects of the selected category
+     */
+    function initializeProjectFilters() {
+        document.querySelectorAll('.project-filters').forEach(filters => {
+            const container = filters.parentElement;
+            filters.addEventListener('click', function(e) {
+                const button = e.target.closest('.project-filter');
+                if (!button) return;
+
+                filters.querySelectorAll('.project-filter').forEach(b => {
+                    b.classList.toggle('active', b === button);
+                });
+                const category = button.dataset.filter;
+                container.querySelectorAll('[data-category]').forEach(item => {
+                    item.hidden = category !== '' && item.dataset.category !== category;
+                });
+            });
+        });
+    }
+
 })();
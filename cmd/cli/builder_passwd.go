@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/builder"
+)
+
+var passwdUsersFile string
+
+var builderPasswdCmd = &cobra.Command{
+	Use:   "passwd <username>",
+	Short: "Add a builder user or change their password",
+	Long: `Store a bcrypt hash of the password read from standard input in the users file,
+adding the user if needed. Start the builder with --users to require sign-in:
+
+  echo 'a long passphrase' | builder builder passwd alice --users builder-users.yaml
+  builder builder serve --users builder-users.yaml --bind 0.0.0.0`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintf(os.Stderr, "Password for %s: ", args[0])
+		password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && password == "" {
+			fmt.Printf("Error reading password: %v\n", err)
+			os.Exit(1)
+		}
+		password = strings.TrimRight(password, "\r\n")
+		if len(password) < 8 {
+			fmt.Println("Error: password must be at least 8 characters")
+			os.Exit(1)
+		}
+
+		if err := builder.SetUserPassword(passwdUsersFile, args[0], password); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Saved password for %s in %s\n", args[0], passwdUsersFile)
+	},
+}
+
+func init() {
+	builderCmd.AddCommand(builderPasswdCmd)
+
+	builderPasswdCmd.Flags().StringVar(&passwdUsersFile, "users", "builder-users.yaml", "Users file")
+}
@@ -2,9 +2,11 @@ package cli
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -12,13 +14,19 @@ import (
 	"github.com/spf13/cobra"
 	"go.lorenzomilicia.dev/photography-portfolio-builder/assets"
 	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/builder"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/util"
 )
 
 var (
-	builderPort       int
-	builderDebug      bool
-	builderContentDir string
-	builderOutputDir  string
+	builderPort        int
+	builderDebug       bool
+	builderContentDir  string
+	builderOutputDir   string
+	builderBind        string
+	builderUsersFile   string
+	builderToken       string
+	builderAutoProcess bool
+	builderBehindProxy bool
 )
 
 var builderServeCmd = &cobra.Command{
@@ -63,6 +71,9 @@ var builderServeCmd = &cobra.Command{
 			photosDir = filepath.Join(workDir, photosDir)
 		}
 
+		// The builder repairs unreadable content files from their backups
+		util.SetRestoreBackups(true)
+
 		// Create builder server
 		log.Info().Msg("Initializing server")
 		srv, err := builder.NewServer(assets.TemplatesFS, assets.StaticFS, contentDir, photosDir, outputDir)
@@ -70,18 +81,43 @@ var builderServeCmd = &cobra.Command{
 			log.Fatal().Err(err).Msg("Failed to create server")
 		}
 
+		// Optional sign-in with a users file and/or a shared token
+		if builderToken == "" {
+			builderToken = os.Getenv("BUILDER_TOKEN")
+		}
+		auth, err := builder.NewAuth(builderUsersFile, builderToken)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to set up authentication")
+		}
+		if auth != nil {
+			auth.SetBehindProxy(builderBehindProxy)
+		}
+		srv.SetAuth(auth)
+		if auth == nil && !isLoopback(builderBind) {
+			log.Warn().Str("bind", builderBind).Msg("Builder is reachable from the network without authentication; use --users or --token")
+		}
+
+		// Optionally process new and changed photos in the background
+		srv.SetAutoProcess(builderAutoProcess)
+		if builderAutoProcess {
+			if err := srv.ProcessPending(); err != nil {
+				log.Error().Err(err).Msg("Failed to check for unprocessed photos")
+			}
+		}
+
 		// Setup routes
 		mux := http.NewServeMux()
 		srv.RegisterRoutes(mux)
 
 		// Start server
-		addr := fmt.Sprintf(":%d", builderPort)
+		addr := net.JoinHostPort(builderBind, strconv.Itoa(builderPort))
 		log.Info().
-			Str("address", fmt.Sprintf("http://localhost%s", addr)).
+			Str("address", fmt.Sprintf("http://%s", addr)).
 			Int("port", builderPort).
+			Bool("auth", auth != nil).
 			Msg("Server listening")
 
-		if err := http.ListenAndServe(addr, mux); err != nil {
+		if err := http.ListenAndServe(addr, srv.WithAuth(mux)); err != nil {
 			log.Fatal().Err(err).Msg("Server failed")
 		}
 	},
@@ -94,4 +130,18 @@ func init() {
 	builderServeCmd.Flags().BoolVar(&builderDebug, "debug", false, "Enable debug logging")
 	builderServeCmd.Flags().StringVarP(&builderContentDir, "content", "c", "content", "Content directory")
 	builderServeCmd.Flags().StringVarP(&builderOutputDir, "output", "o", "dist", "Output directory where processed images are stored")
+	builderServeCmd.Flags().StringVar(&builderBind, "bind", "localhost", "Address to listen on (0.0.0.0 for all interfaces)")
+	builderServeCmd.Flags().StringVar(&builderUsersFile, "users", "", "Users file with bcrypt passwords (see builder passwd); enables sign-in")
+	builderServeCmd.Flags().BoolVar(&builderAutoProcess, "auto-process", false, "Process uploaded, replaced and moved photos (and unprocessed photos at startup) in the background")
+	builderServeCmd.Flags().StringVar(&builderToken, "token", "", "Shared access token; enables sign-in (default: $BUILDER_TOKEN)")
+	builderServeCmd.Flags().BoolVar(&builderBehindProxy, "behind-proxy", false, "Trust X-Forwarded-Proto and X-Forwarded-For from a reverse proxy (only when the builder is not reachable directly)")
+}
+
+// isLoopback reports whether a bind address only accepts local connections
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/chai2010/webp"
+	"github.com/disintegration/imaging"
+	"github.com/spf13/cobra"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/processing"
+)
+
+var (
+	compareWidths           string
+	compareQualities        string
+	compareQualityCurve     string
+	compareSharpenAmount    float64
+	compareSharpenRadius    float64
+	compareSharpenThreshold float64
+)
+
+var imagesCompareCmd = &cobra.Command{
+	Use:   "compare <image>",
+	Short: "Compare encoding settings on a sample image",
+	Long: `Encode a sample image at each variant width with different quality (and
+sharpening) settings, and print the resulting file size and SSIM.
+
+SSIM is measured against the lossless, unsharpened resize at the same width:
+1.0 means identical, values above ~0.98 are usually visually lossless.
+
+Example usage:
+  builder images compare photos/my-project/sample.jpg --qualities 70,80,90 --sharpen-amount 0.5
+  builder images compare sample.jpg --quality-curve 480=90,1920=80`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		widths, err := parseIntList(compareWidths)
+		if err != nil {
+			fmt.Printf("Error: invalid --widths: %v\n", err)
+			os.Exit(1)
+		}
+		qualities, err := parseIntList(compareQualities)
+		if err != nil {
+			fmt.Printf("Error: invalid --qualities: %v\n", err)
+			os.Exit(1)
+		}
+		curve, err := processing.ParseQualityCurve(compareQualityCurve)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		img, err := imaging.Open(args[0], imaging.AutoOrientation(true))
+		if err != nil {
+			fmt.Printf("Error opening %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+
+		plain := processing.NewProcessor(processing.ProcessConfig{Widths: widths})
+		sharpen := sharpenConfig(compareSharpenAmount, compareSharpenRadius, compareSharpenThreshold)
+
+		type setting struct {
+			label     string
+			processor *processing.Processor
+		}
+		settings := []setting{{label: "off", processor: plain}}
+		if sharpen != nil {
+			settings = append(settings, setting{
+				label:     fmt.Sprintf("%.2f/%.2f/%.0f", sharpen.Amount, sharpen.Radius, sharpen.Threshold),
+				processor: processing.NewProcessor(processing.ProcessConfig{Widths: widths, Sharpen: sharpen}),
+			})
+		}
+
+		bounds := img.Bounds()
+		fmt.Printf("Source: %s (%dx%d)\n\n", args[0], bounds.Dx(), bounds.Dy())
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, "Width\tQuality\tSharpen\tSize (KB)\tSSIM\t")
+
+		for _, width := range widths {
+			height := int(float64(width) * float64(bounds.Dy()) / float64(bounds.Dx()))
+			reference := plain.RenderVariant(img, width, height)
+
+			// Qualities to test at this width: the explicit list, plus the curve value
+			widthQualities := append([]int{}, qualities...)
+			if len(curve) > 0 {
+				widthQualities = append(widthQualities, curve.QualityFor(width, 0))
+			}
+
+			for _, s := range settings {
+				rendered := s.processor.RenderVariant(img, width, height)
+				for _, quality := range widthQualities {
+					size, score, err := encodeAndScore(rendered, reference, quality)
+					if err != nil {
+						fmt.Printf("Error encoding %dw at quality %d: %v\n", width, quality, err)
+						os.Exit(1)
+					}
+					fmt.Fprintf(w, "%dw\t%d\t%s\t%.1f\t%.4f\t\n", width, quality, s.label, float64(size)/1024, score)
+				}
+			}
+		}
+		w.Flush()
+	},
+}
+
+// encodeAndScore encodes img as WebP and returns the encoded size and the SSIM
+// of the decoded result against reference
+func encodeAndScore(img, reference image.Image, quality int) (int, float64, error) {
+	var buf bytes.Buffer
+	if err := webp.Encode(&buf, img, &webp.Options{Quality: float32(quality)}); err != nil {
+		return 0, 0, err
+	}
+	size := buf.Len()
+
+	decoded, err := webp.Decode(&buf)
+	if err != nil {
+		return 0, 0, err
+	}
+	score, err := processing.SSIM(reference, decoded)
+	if err != nil {
+		return 0, 0, err
+	}
+	return size, score, nil
+}
+
+// parseIntList parses a comma-separated list of positive integers
+func parseIntList(s string) ([]int, error) {
+	var values []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, err := strconv.Atoi(strings.TrimSuffix(part, "w"))
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("%q is not a positive integer", part)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func init() {
+	imagesCmd.AddCommand(imagesCompareCmd)
+
+	imagesCompareCmd.Flags().StringVar(&compareWidths, "widths", "480,800,1200,1920", "Comma-separated variant widths to test")
+	imagesCompareCmd.Flags().StringVar(&compareQualities, "qualities", "70,80,85,90", "Comma-separated WebP qualities to test")
+	imagesCompareCmd.Flags().StringVar(&compareQualityCurve, "quality-curve", "", "Also test a per-width quality curve, e.g. '480=90,1920=80'")
+	imagesCompareCmd.Flags().Float64Var(&compareSharpenAmount, "sharpen-amount", 0, "Also test an unsharp mask with this strength (0 disables)")
+	imagesCompareCmd.Flags().Float64Var(&compareSharpenRadius, "sharpen-radius", 0.5, "Unsharp mask blur radius (sigma) in pixels")
+	imagesCompareCmd.Flags().Float64Var(&compareSharpenThreshold, "sharpen-threshold", 0, "Minimum pixel difference (0-255) before sharpening applies")
+}
@@ -6,32 +6,80 @@ import (
 	"path/filepath"
 
 	"github.com/spf13/cobra"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
 	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/processing"
 )
 
 var inputDir string
 var outputDir string
 var force bool
+var processContentDir string
+var processQuality int
+var processQualityCurve string
+var processSharpenAmount float64
+var processSharpenRadius float64
+var processSharpenThreshold float64
+var processFFmpeg string
 
 var processCmd = &cobra.Command{
 	Use:   "process",
 	Short: "Process images for the website",
-	Long:  `Scan a directory for projects and images, strip EXIF data, resize, and convert them for the website.`,
+	Long:  `Scan a directory for projects and images, strip EXIF data, resize, and convert them for the website. Video clips are transcoded with ffmpeg and get a poster frame.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Printf("Processing images from: %s\n", inputDir)
 		fmt.Printf("Output directory: %s\n", outputDir)
 
-		// Initialize processor
-		processor := processing.NewProcessor(processing.ProcessConfig{
-			Widths:             []int{480, 800, 1200, 1920},
-			Quality:            85,
-			Force:              force,
-			GenerateThumbnails: true,
-			ThumbnailWidth:     300,
-		})
+		qualityCurve, err := processing.ParseQualityCurve(processQualityCurve)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		sharpen := sharpenConfig(processSharpenAmount, processSharpenRadius, processSharpenThreshold)
+
+		// The flags override the processing settings in site.yaml, which the builder uses too
+		flags := cmd.Flags()
+		overrideQuality := flags.Changed("quality")
+		overrideCurve := flags.Changed("quality-curve")
+		overrideSharpen := flags.Changed("sharpen-amount") || flags.Changed("sharpen-radius") || flags.Changed("sharpen-threshold")
+		if overrideQuality || overrideCurve || overrideSharpen {
+			fmt.Println("Warning: quality and sharpening flags override the processing settings in site.yaml; the builder keeps using site.yaml and will report these variants as outdated")
+		}
+
+		contentMgr := content.NewManagerWithPhotosDir(processContentDir, inputDir)
+
+		// One processor per project, since watermark settings can be overridden per project
+		processors := make(map[string]*processing.Processor)
+		processorFor := func(slug string) (*processing.Processor, error) {
+			if processor, ok := processors[slug]; ok {
+				return processor, nil
+			}
+			config, err := processing.ProjectConfig(contentMgr, slug, processing.ProcessConfig{
+				Widths:             processing.DefaultWidths,
+				Quality:            processQuality,
+				Force:              force,
+				GenerateThumbnails: true,
+				ThumbnailWidth:     300,
+				FFmpeg:             processFFmpeg,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if overrideQuality {
+				config.Quality = processQuality
+			}
+			if overrideCurve {
+				config.QualityCurve = qualityCurve
+			}
+			if overrideSharpen {
+				config.Sharpen = sharpen
+			}
+			processor := processing.NewProcessor(config)
+			processors[slug] = processor
+			return processor, nil
+		}
 
 		// Walk through input directory
-		err := filepath.Walk(inputDir, func(path string, info os.FileInfo, err error) error {
+		err = filepath.Walk(inputDir, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
@@ -45,6 +93,12 @@ var processCmd = &cobra.Command{
 				rel, _ := filepath.Rel(inputDir, filepath.Dir(path))
 				outputDirForImage := filepath.Join(outputDir, rel)
 
+				processor, err := processorFor(filepath.ToSlash(rel))
+				if err != nil {
+					fmt.Printf("Error loading settings for %s: %v\n", rel, err)
+					return nil
+				}
+
 				// Create destination - both variants and thumbnails go to outputDir
 				dst := &processing.Destination{
 					OutputDir: outputDirForImage,
@@ -65,9 +119,20 @@ var processCmd = &cobra.Command{
 	},
 }
 
+// sharpenConfig builds the unsharp mask settings from flags, or nil when disabled
+func sharpenConfig(amount, radius, threshold float64) *processing.SharpenConfig {
+	if amount <= 0 {
+		return nil
+	}
+	if radius <= 0 {
+		radius = 0.5
+	}
+	return &processing.SharpenConfig{Amount: amount, Radius: radius, Threshold: threshold}
+}
+
 func isImage(path string) bool {
 	ext := filepath.Ext(path)
-	return ext == ".jpg" || ext == ".jpeg" || ext == ".png"
+	return ext == ".jpg" || ext == ".jpeg" || ext == ".png" || content.IsVideo(path)
 }
 
 func init() {
@@ -76,4 +141,11 @@ func init() {
 	processCmd.Flags().StringVarP(&inputDir, "input", "i", "photos", "Input directory containing project subfolders")
 	processCmd.Flags().StringVarP(&outputDir, "output", "o", "dist/images", "Output directory for processed images")
 	processCmd.Flags().BoolVar(&force, "force", false, "Overwrite existing files even if cached")
+	processCmd.Flags().IntVarP(&processQuality, "quality", "q", 85, "WebP quality for variants and thumbnails (1-100); overrides processing.quality in site.yaml")
+	processCmd.Flags().StringVar(&processQualityCurve, "quality-curve", "", "Per-width variant quality, e.g. '480=90,1920=80' (interpolated between points); overrides site.yaml")
+	processCmd.Flags().Float64Var(&processSharpenAmount, "sharpen-amount", 0, "Unsharp mask strength applied after resizing (0 disables, e.g. 0.5); overrides site.yaml")
+	processCmd.Flags().Float64Var(&processSharpenRadius, "sharpen-radius", 0.5, "Unsharp mask blur radius (sigma) in pixels")
+	processCmd.Flags().Float64Var(&processSharpenThreshold, "sharpen-threshold", 0, "Minimum pixel difference (0-255) before sharpening applies")
+	processCmd.Flags().StringVar(&processFFmpeg, "ffmpeg", "ffmpeg", "ffmpeg binary used to transcode video clips (.mp4, .mov)")
+	processCmd.Flags().StringVarP(&processContentDir, "content", "c", "content", "Content directory (for watermark, crop and layout-aware width settings)")
 }
@@ -125,6 +125,11 @@ Example usage:
 				return nil
 			}
 
+			// Skip bookkeeping files such as the variant settings fingerprint
+			if strings.HasPrefix(info.Name(), ".") {
+				return nil
+			}
+
 			// Optionally skip thumbnail files stored in .thumbs folders
 			if uploadSkipThumbs {
 				if strings.HasPrefix(relPath, ".thumbs") || strings.Contains(relPath, "/.thumbs/") {
@@ -0,0 +1,15 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var layoutCmd = &cobra.Command{
+	Use:   "layout",
+	Short: "Project layout commands",
+	Long:  `Parent command for all project layout operations.`,
+}
+
+func init() {
+	rootCmd.AddCommand(layoutCmd)
+}
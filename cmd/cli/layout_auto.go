@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/layouts"
+)
+
+var layoutContentDir string
+var layoutPhotosDir string
+var layoutStyle string
+var layoutForce bool
+
+var layoutAutoCmd = &cobra.Command{
+	Use:   "auto <slug>",
+	Short: "Generate a project layout from photo aspect ratios",
+	Long: `Arrange all photos of a project on a 12-column desktop grid and a 6-column mobile grid.
+Styles: justified (full-width rows of equal height), masonry (columns) and grid (equal cells).
+Photos already placed keep their order. Focal points and the mobile crop are preserved.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		slug := args[0]
+
+		style, err := layouts.ParseStyle(layoutStyle)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		contentMgr := content.NewManagerWithPhotosDir(layoutContentDir, layoutPhotosDir)
+		if _, err := contentMgr.GetProject(slug); err != nil {
+			fmt.Printf("Error: project %s not found: %v\n", slug, err)
+			os.Exit(1)
+		}
+
+		current, err := contentMgr.GetLayout(slug)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				fmt.Printf("Error loading layout: %v\n", err)
+				os.Exit(1)
+			}
+			current = &content.LayoutConfig{}
+		}
+		if (len(current.Placements) > 0 || len(current.MobilePlacements) > 0) && !layoutForce {
+			fmt.Printf("Error: %s already has a layout; use --force to replace it\n", slug)
+			os.Exit(1)
+		}
+
+		photos, err := contentMgr.ListPhotos(slug)
+		if err != nil {
+			fmt.Printf("Error listing photos: %v\n", err)
+			os.Exit(1)
+		}
+		if len(photos) == 0 {
+			fmt.Printf("No photos found for %s\n", slug)
+			return
+		}
+
+		layout, err := layouts.AutoLayout(layouts.PlacementOrder(photos, current.Placements), style)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		layout.MobileCrop = current.MobileCrop
+		layout.Photos = current.Photos
+		layout.Blocks = current.Blocks // Unplaced, to be added back in the editor
+
+		if err := contentMgr.UpdateLayout(slug, layout); err != nil {
+			fmt.Printf("Error saving layout: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Arranged %d photos in a %s layout for %s\n", len(photos), style, slug)
+		fmt.Printf("Fine-tune it in the builder layout editor: /layout/%s\n", slug)
+	},
+}
+
+func init() {
+	layoutCmd.AddCommand(layoutAutoCmd)
+
+	layoutAutoCmd.Flags().StringVarP(&layoutContentDir, "content", "c", "content", "Content directory")
+	layoutAutoCmd.Flags().StringVarP(&layoutPhotosDir, "photos", "i", "photos", "Photos directory containing project subfolders")
+	layoutAutoCmd.Flags().StringVar(&layoutStyle, "style", "justified", "Layout style: justified, masonry or grid")
+	layoutAutoCmd.Flags().BoolVar(&layoutForce, "force", false, "Replace an existing layout")
+}
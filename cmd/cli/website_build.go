@@ -9,6 +9,7 @@ import (
 	"github.com/spf13/cobra"
 	"go.lorenzomilicia.dev/photography-portfolio-builder/assets"
 	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/generator"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/util"
 )
 
 var host string
@@ -67,6 +68,10 @@ var websiteBuildCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		for _, recovery := range util.Recoveries() {
+			fmt.Printf("Warning: %s could not be read (%s); built from its backup %s%s, the file was left as it is\n", recovery.Path, recovery.Err, recovery.Path, util.BackupSuffix)
+		}
+
 		fmt.Println("Website build complete!")
 	},
 }
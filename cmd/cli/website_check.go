@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/assets"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/check"
+)
+
+var checkContentDir string
+var checkPhotosDir string
+var checkImagesDir string
+var checkDistDir string
+var checkTemplatesDir string
+var checkJSON bool
+
+var websiteCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Lint content, processed images and a built site",
+	Long: `Check the content directory for problems that would break the deployed site:
+  - invalid project and home page layouts (out of bounds, overlapping, unknown photos or projects)
+  - hero photos that are not photos of their project
+  - projects listed in the site.yaml project order that do not exist
+  - placed and hero photos without processed variants (skipped with --images "")
+  - favicons referenced by templates but missing from <content>/favicon
+  - with --dist, links and assets of the built site that do not resolve to a file
+
+Photo references are only checked for projects whose photos directory exists.
+Exits with status 1 when an error is found; warnings do not fail the check.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if checkTemplatesDir == "" {
+			checkTemplatesDir = filepath.Join(checkContentDir, "templates")
+		}
+		siteTemplates, err := fs.Sub(assets.TemplatesFS, "templates/site")
+		if err != nil {
+			fmt.Printf("Error loading templates: %v\n", err)
+			os.Exit(1)
+		}
+
+		report, err := check.Run(check.Options{
+			ContentDir: checkContentDir,
+			PhotosDir:  checkPhotosDir,
+			ImagesDir:  checkImagesDir,
+			DistDir:    checkDistDir,
+			Templates:  []fs.FS{siteTemplates, os.DirFS(checkTemplatesDir)},
+		})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if checkJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.Encode(report)
+		} else {
+			for _, issue := range report.Issues {
+				where := issue.File
+				if where == "" {
+					where = issue.Project
+				}
+				if where != "" {
+					where += ": "
+				}
+				fmt.Printf("%s [%s] %s%s\n", issue.Severity, issue.Code, where, issue.Message)
+			}
+			fmt.Printf("Checked %d project(s): %d error(s), %d warning(s)\n", report.Projects, report.Errors, report.Warnings)
+		}
+
+		if report.Errors > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	websiteCmd.AddCommand(websiteCheckCmd)
+
+	websiteCheckCmd.Flags().StringVarP(&checkContentDir, "content", "c", "content", "Content directory")
+	websiteCheckCmd.Flags().StringVarP(&checkPhotosDir, "photos", "i", "photos", "Photos directory containing project subfolders")
+	websiteCheckCmd.Flags().StringVar(&checkImagesDir, "images", "dist/images", "Processed images directory (empty to skip the variant check)")
+	websiteCheckCmd.Flags().StringVarP(&checkDistDir, "dist", "d", "", "Built site directory whose links are checked (empty to skip)")
+	websiteCheckCmd.Flags().StringVarP(&checkTemplatesDir, "templates", "t", "", "Custom templates directory (default: <content>/templates)")
+	websiteCheckCmd.Flags().BoolVar(&checkJSON, "json", false, "Print the report as JSON")
+}
@@ -9,7 +9,10 @@ require (
 	github.com/disintegration/imaging v1.6.2
 	github.com/rs/zerolog v1.34.0
 	github.com/spf13/cobra v1.10.2
+	github.com/yuin/goldmark v1.8.2
+	golang.org/x/crypto v0.46.0
 	golang.org/x/image v0.34.0
+	golang.org/x/text v0.32.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -38,5 +41,5 @@ require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.19 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
-	golang.org/x/sys v0.12.0 // indirect
+	golang.org/x/sys v0.39.0 // indirect
 )
@@ -0,0 +1,433 @@
+package builder
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/util"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	sessionCookie = "builder_session"
+	csrfCookie    = "builder_csrf" // Readable by scripts, which send it back in csrfHeader
+	csrfHeader    = "X-CSRF-Token"
+	sessionTTL    = 12 * time.Hour
+
+	loginCSRFCookie  = "builder_login_csrf" // Double-submitted with the sign-in form
+	loginFreeFails   = 5                    // Failed sign-ins per address before backing off
+	loginMaxBackoff  = 15 * time.Minute
+	loginForgetAfter = time.Hour // Failures are forgotten after an hour without one
+)
+
+// User is a builder account in the users file
+type User struct {
+	Username     string `yaml:"username"`
+	PasswordHash string `yaml:"password_hash"` // bcrypt
+}
+
+// UsersFile is the YAML file listing builder accounts
+type UsersFile struct {
+	Users []User `yaml:"users"`
+}
+
+// Auth protects the builder with accounts from a users file or a shared token.
+// Signed-in browsers get a session cookie; state-changing requests must carry
+// the session's CSRF token.
+type Auth struct {
+	users     map[string][]byte // Username -> bcrypt hash
+	token     string
+	dummyHash []byte // Compared for unknown users, so they take as long as wrong passwords
+	proxied   bool   // Behind a trusted reverse proxy, whose X-Forwarded-* headers are believed
+
+	mu       sync.Mutex
+	sessions map[string]*session       // By session ID
+	failures map[string]*loginFailures // Failed sign-ins by client address
+}
+
+// loginFailures counts an address's failed sign-ins; after loginFreeFails,
+// each further failure doubles the wait before the next attempt
+type loginFailures struct {
+	count int
+	last  time.Time
+	until time.Time // No attempts before this time
+}
+
+type session struct {
+	username string
+	csrf     string
+	expires  time.Time
+}
+
+// NewAuth loads the users file and/or sets the shared token. Returns nil when
+// both are empty, meaning the builder is open.
+func NewAuth(usersFile, token string) (*Auth, error) {
+	if usersFile == "" && token == "" {
+		return nil, nil
+	}
+
+	auth := &Auth{users: make(map[string][]byte), token: token, sessions: make(map[string]*session), failures: make(map[string]*loginFailures)}
+	if usersFile != "" {
+		var file UsersFile
+		if err := util.LoadYAML(usersFile, &file); err != nil {
+			return nil, fmt.Errorf("failed to load users file: %w", err)
+		}
+		for _, user := range file.Users {
+			if user.Username == "" || user.PasswordHash == "" {
+				return nil, fmt.Errorf("users file has an entry without username or password_hash")
+			}
+			auth.users[user.Username] = []byte(user.PasswordHash)
+		}
+		if len(auth.users) == 0 && token == "" {
+			return nil, fmt.Errorf("users file %s has no users", usersFile)
+		}
+	}
+
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte(randomToken()[:32]), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	auth.dummyHash = dummyHash
+	return auth, nil
+}
+
+// SetUserPassword adds a user to the users file, or changes their password
+func SetUserPassword(usersFile, username, password string) error {
+	if username == "" || password == "" {
+		return fmt.Errorf("username and password are required")
+	}
+
+	var file UsersFile
+	if err := util.LoadYAML(usersFile, &file); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to load users file: %w", err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	updated := false
+	for i := range file.Users {
+		if file.Users[i].Username == username {
+			file.Users[i].PasswordHash = string(hash)
+			updated = true
+		}
+	}
+	if !updated {
+		file.Users = append(file.Users, User{Username: username, PasswordHash: string(hash)})
+	}
+
+	if err := util.SaveYAML(usersFile, &file); err != nil {
+		return fmt.Errorf("failed to save users file: %w", err)
+	}
+	return os.Chmod(usersFile, 0600)
+}
+
+// SetBehindProxy trusts the X-Forwarded-Proto and X-Forwarded-For headers, which
+// only a reverse proxy in front of the builder can be relied on to set
+func (a *Auth) SetBehindProxy(proxied bool) {
+	a.proxied = proxied
+}
+
+// usesToken reports whether the shared token is accepted
+func (a *Auth) usesToken() bool {
+	return a.token != ""
+}
+
+// checkCredentials returns the user signing in with a username and password,
+// or with the shared token (as password, without username)
+func (a *Auth) checkCredentials(username, password string) (string, bool) {
+	if username == "" {
+		if a.usesToken() && subtle.ConstantTimeCompare([]byte(password), []byte(a.token)) == 1 {
+			return "token", true
+		}
+		return "", false
+	}
+	hash, ok := a.users[username]
+	if !ok {
+		bcrypt.CompareHashAndPassword(a.dummyHash, []byte(password))
+		return "", false
+	}
+	return username, bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
+
+// startSession creates a session and sets its cookies
+func (a *Auth) startSession(w http.ResponseWriter, r *http.Request, username string) {
+	id, csrf := randomToken(), randomToken()
+	expires := time.Now().Add(sessionTTL)
+
+	a.mu.Lock()
+	for sid, s := range a.sessions {
+		if time.Now().After(s.expires) {
+			delete(a.sessions, sid)
+		}
+	}
+	a.sessions[id] = &session{username: username, csrf: csrf, expires: expires}
+	a.mu.Unlock()
+
+	secure := a.secure(r)
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: id, Path: "/", Expires: expires, HttpOnly: true, Secure: secure, SameSite: http.SameSiteLaxMode})
+	http.SetCookie(w, &http.Cookie{Name: csrfCookie, Value: csrf, Path: "/", Expires: expires, Secure: secure, SameSite: http.SameSiteStrictMode})
+}
+
+// secure reports whether the request reached the builder over HTTPS
+func (a *Auth) secure(r *http.Request) bool {
+	return r.TLS != nil || (a.proxied && r.Header.Get("X-Forwarded-Proto") == "https")
+}
+
+// clientAddr returns the address sign-in failures are counted for: the
+// connecting host, or the address the trusted proxy appended last
+func (a *Auth) clientAddr(r *http.Request) string {
+	if a.proxied {
+		forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if addr := strings.TrimSpace(forwarded[len(forwarded)-1]); addr != "" {
+			return addr
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// loginWait returns how long the address must wait before signing in again
+func (a *Auth) loginWait(addr string) time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, ok := a.failures[addr]
+	if !ok {
+		return 0
+	}
+	return time.Until(f.until)
+}
+
+// loginFailed records a failed sign-in and starts the address's backoff once
+// it has used its free attempts
+func (a *Auth) loginFailed(addr string) {
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for key, f := range a.failures {
+		if now.Sub(f.last) > loginForgetAfter {
+			delete(a.failures, key)
+		}
+	}
+
+	f, ok := a.failures[addr]
+	if !ok {
+		f = &loginFailures{}
+		a.failures[addr] = f
+	}
+	f.count++
+	f.last = now
+	if f.count >= loginFreeFails {
+		backoff := loginMaxBackoff
+		if shift := f.count - loginFreeFails; shift < 10 {
+			backoff = min(time.Second<<shift, loginMaxBackoff)
+		}
+		f.until = now.Add(backoff)
+	}
+}
+
+// loginSucceeded forgets the address's failed sign-ins
+func (a *Auth) loginSucceeded(addr string) {
+	a.mu.Lock()
+	delete(a.failures, addr)
+	a.mu.Unlock()
+}
+
+// endSession removes the request's session and clears its cookies
+func (a *Auth) endSession(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		a.mu.Lock()
+		delete(a.sessions, cookie.Value)
+		a.mu.Unlock()
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1})
+	http.SetCookie(w, &http.Cookie{Name: csrfCookie, Value: "", Path: "/", MaxAge: -1})
+}
+
+// sessionFor returns the request's valid session, if any
+func (a *Auth) sessionFor(r *http.Request) *session {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s, ok := a.sessions[cookie.Value]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(s.expires) {
+		delete(a.sessions, cookie.Value)
+		return nil
+	}
+	return s
+}
+
+// bearerToken reports whether the request carries the shared token in an
+// Authorization header (for scripts; no cookies, so no CSRF check)
+func (a *Auth) bearerToken(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && a.usesToken() && subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1
+}
+
+// WithAuth wraps the builder routes so that they require a signed-in session
+// (or the bearer token) and a CSRF token on state-changing requests. Without
+// auth configured, handler is returned unchanged.
+func (s *Server) WithAuth(handler http.Handler) http.Handler {
+	if s.auth == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The login page and its stylesheet are public
+		if r.URL.Path == "/login" || strings.HasPrefix(r.URL.Path, "/static/builder/") {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if s.auth.bearerToken(r) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		sess := s.auth.sessionFor(r)
+		if sess == nil {
+			switch {
+			case r.Header.Get("HX-Request") != "":
+				w.Header().Set("HX-Redirect", "/login")
+				http.Error(w, "Sign in required", http.StatusUnauthorized)
+			case strings.HasPrefix(r.URL.Path, "/api/"):
+				http.Error(w, "Sign in required", http.StatusUnauthorized)
+			default:
+				http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+			}
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			token := r.Header.Get(csrfHeader)
+			if token == "" && strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+				// Plain forms; multipart bodies are left for the handler to read
+				if err := r.ParseForm(); err == nil {
+					token = r.PostForm.Get("csrf_token")
+				}
+			}
+			if subtle.ConstantTimeCompare([]byte(token), []byte(sess.csrf)) != 1 {
+				log.Warn().Str("path", r.URL.Path).Str("user", sess.username).Msg("Rejected request with invalid CSRF token")
+				http.Error(w, "Invalid CSRF token; reload the page", http.StatusForbidden)
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// handleLogin shows the sign-in form and starts a session on valid
+// credentials. The form carries its own CSRF token, and an address that keeps
+// failing has to wait longer and longer between attempts.
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if s.auth == nil {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	next := r.FormValue("next")
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		next = "/" // Only redirect within the builder
+	}
+	data := map[string]interface{}{
+		"Next":      next,
+		"Users":     len(s.auth.users) > 0,
+		"UsesToken": s.auth.usesToken(),
+	}
+
+	status := http.StatusOK
+	if r.Method == http.MethodPost {
+		status = s.signIn(w, r, next, data)
+		if status == http.StatusSeeOther {
+			return
+		}
+	}
+
+	// A fresh form token for every form shown
+	csrf := randomToken()
+	http.SetCookie(w, &http.Cookie{Name: loginCSRFCookie, Value: csrf, Path: "/login", HttpOnly: true, Secure: s.auth.secure(r), SameSite: http.SameSiteStrictMode})
+	data["CSRFToken"] = csrf
+	w.WriteHeader(status)
+
+	if err := s.templates.ExecuteTemplate(w, "login.html", data); err != nil {
+		log.Error().Err(err).Msg("Template execution failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// signIn checks a posted sign-in form. It starts the session and redirects
+// to next, returning http.StatusSeeOther, or sets the form error and returns
+// the status to show the form again with.
+func (s *Server) signIn(w http.ResponseWriter, r *http.Request, next string, data map[string]interface{}) int {
+	cookie, err := r.Cookie(loginCSRFCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(r.PostFormValue("csrf_token")), []byte(cookie.Value)) != 1 {
+		log.Warn().Str("remote", r.RemoteAddr).Msg("Rejected sign-in with invalid CSRF token")
+		data["Error"] = "The sign-in form expired; please try again"
+		return http.StatusForbidden
+	}
+
+	addr := s.auth.clientAddr(r)
+	if wait := s.auth.loginWait(addr); wait > 0 {
+		seconds := int(wait.Seconds()) + 1
+		w.Header().Set("Retry-After", fmt.Sprint(seconds))
+		data["Error"] = fmt.Sprintf("Too many failed sign-ins; try again in %d seconds", seconds)
+		return http.StatusTooManyRequests
+	}
+
+	username := strings.TrimSpace(r.PostFormValue("username"))
+	data["Username"] = username
+	user, ok := s.auth.checkCredentials(username, r.PostFormValue("password"))
+	if !ok {
+		s.auth.loginFailed(addr)
+		log.Warn().Str("user", username).Str("remote", addr).Msg("Failed sign-in")
+		data["Error"] = "Invalid credentials"
+		return http.StatusUnauthorized
+	}
+
+	s.auth.loginSucceeded(addr)
+	s.auth.startSession(w, r, user)
+	http.SetCookie(w, &http.Cookie{Name: loginCSRFCookie, Value: "", Path: "/login", MaxAge: -1})
+	log.Info().Str("user", user).Str("remote", addr).Msg("Signed in")
+	http.Redirect(w, r, next, http.StatusSeeOther)
+	return http.StatusSeeOther
+}
+
+// handleLogout ends the session
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.auth != nil {
+		s.auth.endSession(w, r)
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// randomToken returns 32 random bytes, hex encoded
+func randomToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,300 @@
+package builder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newAuthServer returns the routes of a test server that requires sign-in as
+// alice (password "secret") or with the token "shared-token"
+func newAuthServer(t *testing.T) (*Server, http.Handler) {
+	t.Helper()
+	usersFile := filepath.Join(t.TempDir(), "users.yaml")
+	if err := SetUserPassword(usersFile, "alice", "secret"); err != nil {
+		t.Fatalf("SetUserPassword failed: %v", err)
+	}
+	auth, err := NewAuth(usersFile, "shared-token")
+	if err != nil {
+		t.Fatalf("NewAuth failed: %v", err)
+	}
+
+	s := newTestServer(t)
+	s.SetAuth(auth)
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	return s, s.WithAuth(mux)
+}
+
+// signIn shows the sign-in form from remoteAddr and posts it with the given
+// credentials and the form's CSRF token
+func signIn(h http.Handler, remoteAddr, username, password string) *httptest.ResponseRecorder {
+	form := httptest.NewRecorder()
+	h.ServeHTTP(form, httptest.NewRequest(http.MethodGet, "/login", nil))
+	var csrf *http.Cookie
+	for _, cookie := range form.Result().Cookies() {
+		if cookie.Name == loginCSRFCookie {
+			csrf = cookie
+		}
+	}
+
+	values := url.Values{"username": {username}, "password": {password}, "next": {"/config"}}
+	if csrf != nil {
+		values.Set("csrf_token", csrf.Value)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = remoteAddr
+	if csrf != nil {
+		req.AddCookie(csrf)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// cookies returns the response's cookies by name
+func cookies(rec *httptest.ResponseRecorder) map[string]*http.Cookie {
+	byName := make(map[string]*http.Cookie)
+	for _, cookie := range rec.Result().Cookies() {
+		byName[cookie.Name] = cookie
+	}
+	return byName
+}
+
+// TestAuthRequiresSignIn checks how requests without a session are turned away
+func TestAuthRequiresSignIn(t *testing.T) {
+	_, h := newAuthServer(t)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/login?next=%2Fconfig" {
+		t.Errorf("Expected a redirect to the sign-in page, got %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/project/delete", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for the API, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/projects", nil)
+	req.Header.Set("HX-Request", "true")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("HX-Redirect") != "/login" {
+		t.Errorf("Expected 401 with HX-Redirect for htmx, got %d to %q", rec.Code, rec.Header().Get("HX-Redirect"))
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the sign-in page to be public, got %d", rec.Code)
+	}
+}
+
+// TestLogin checks signing in with right and wrong passwords and form tokens
+func TestLogin(t *testing.T) {
+	_, h := newAuthServer(t)
+
+	rec := signIn(h, "192.0.2.1:1234", "alice", "wrong")
+	if rec.Code != http.StatusUnauthorized || cookies(rec)[sessionCookie] != nil {
+		t.Errorf("Expected 401 without a session for a wrong password, got %d", rec.Code)
+	}
+	rec = signIn(h, "192.0.2.1:1234", "mallory", "secret")
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an unknown user, got %d", rec.Code)
+	}
+
+	rec = signIn(h, "192.0.2.1:1234", "alice", "secret")
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/config" {
+		t.Fatalf("Expected a redirect to next, got %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+	session := cookies(rec)[sessionCookie]
+	if session == nil || !session.HttpOnly || cookies(rec)[csrfCookie] == nil {
+		t.Fatalf("Expected session and CSRF cookies, got %v", rec.Result().Cookies())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/config", nil)
+	req.AddCookie(session)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the signed-in session to be accepted, got %d", rec.Code)
+	}
+
+	// The sign-in form itself needs its token
+	values := url.Values{"username": {"alice"}, "password": {"secret"}}
+	for name, cookie := range map[string]*http.Cookie{
+		"missing":    nil,
+		"mismatched": {Name: loginCSRFCookie, Value: "other"},
+	} {
+		values.Set("csrf_token", "forged")
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden || cookies(rec)[sessionCookie] != nil {
+			t.Errorf("Expected 403 for a %s form token, got %d", name, rec.Code)
+		}
+	}
+}
+
+// TestLoginThrottle checks that repeated failures from an address slow it down
+func TestLoginThrottle(t *testing.T) {
+	_, h := newAuthServer(t)
+
+	for i := 0; i < loginFreeFails; i++ {
+		if rec := signIn(h, "192.0.2.1:1234", "alice", "wrong"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("Attempt %d: expected 401, got %d", i, rec.Code)
+		}
+	}
+	rec := signIn(h, "192.0.2.1:5678", "alice", "secret")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 429 with Retry-After after %d failures, got %d", loginFreeFails, rec.Code)
+	}
+	if rec := signIn(h, "192.0.2.2:1234", "alice", "secret"); rec.Code != http.StatusSeeOther {
+		t.Errorf("Expected another address to sign in, got %d", rec.Code)
+	}
+}
+
+// TestSessionExpiry checks that expired sessions are turned away
+func TestSessionExpiry(t *testing.T) {
+	s, h := newAuthServer(t)
+	session := cookies(signIn(h, "192.0.2.1:1234", "alice", "secret"))[sessionCookie]
+
+	s.auth.mu.Lock()
+	s.auth.sessions[session.Value].expires = time.Now().Add(-time.Second)
+	s.auth.mu.Unlock()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/jobs", nil)
+	req.AddCookie(session)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an expired session, got %d", rec.Code)
+	}
+}
+
+// TestCSRF checks that state-changing requests need the session's CSRF token
+func TestCSRF(t *testing.T) {
+	_, h := newAuthServer(t)
+	signedIn := cookies(signIn(h, "192.0.2.1:1234", "alice", "secret"))
+	session, csrf := signedIn[sessionCookie], signedIn[csrfCookie]
+
+	post := func(header, field string) int {
+		body := url.Values{}
+		if field != "" {
+			body.Set("csrf_token", field)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/recoveries/dismiss", strings.NewReader(body.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if header != "" {
+			req.Header.Set(csrfHeader, header)
+		}
+		req.AddCookie(session)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := post("", ""); code != http.StatusForbidden {
+		t.Errorf("Expected 403 without a token, got %d", code)
+	}
+	if code := post("forged", ""); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a mismatched header token, got %d", code)
+	}
+	if code := post("", "forged"); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a mismatched form token, got %d", code)
+	}
+	if code := post(csrf.Value, ""); code != http.StatusOK {
+		t.Errorf("Expected the header token to be accepted, got %d", code)
+	}
+	if code := post("", csrf.Value); code != http.StatusOK {
+		t.Errorf("Expected the form token to be accepted, got %d", code)
+	}
+}
+
+// TestTokenAuth checks the shared token as bearer token and as password
+func TestTokenAuth(t *testing.T) {
+	_, h := newAuthServer(t)
+
+	for token, want := range map[string]int{"shared-token": http.StatusOK, "wrong": http.StatusUnauthorized} {
+		req := httptest.NewRequest(http.MethodPost, "/api/recoveries/dismiss", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("Bearer %s: expected %d, got %d", token, want, rec.Code)
+		}
+	}
+
+	if rec := signIn(h, "192.0.2.1:1234", "", "shared-token"); rec.Code != http.StatusSeeOther {
+		t.Errorf("Expected the token to sign in without a username, got %d", rec.Code)
+	}
+	if rec := signIn(h, "192.0.2.1:1234", "", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong token, got %d", rec.Code)
+	}
+}
+
+// TestBehindProxy checks that forwarded headers are only believed behind a proxy
+func TestBehindProxy(t *testing.T) {
+	s, h := newAuthServer(t)
+	forwarded := func(remoteAddr string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/login", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("X-Forwarded-For", "198.51.100.7, 203.0.113.9")
+		return req
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, forwarded("192.0.2.1:1234"))
+	if cookies(rec)[loginCSRFCookie].Secure {
+		t.Error("Expected X-Forwarded-Proto to be ignored without --behind-proxy")
+	}
+	if addr := s.auth.clientAddr(forwarded("192.0.2.1:1234")); addr != "192.0.2.1" {
+		t.Errorf("Expected the connecting address without --behind-proxy, got %s", addr)
+	}
+
+	s.auth.SetBehindProxy(true)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, forwarded("127.0.0.1:1234"))
+	if !cookies(rec)[loginCSRFCookie].Secure {
+		t.Error("Expected X-Forwarded-Proto to mark cookies Secure behind a proxy")
+	}
+	if addr := s.auth.clientAddr(forwarded("127.0.0.1:1234")); addr != "203.0.113.9" {
+		t.Errorf("Expected the address appended by the proxy, got %s", addr)
+	}
+}
+
+// TestStateChangingRoutesRejectGet checks that no route that changes content
+// can be reached with a GET, which skips the CSRF check
+func TestStateChangingRoutesRejectGet(t *testing.T) {
+	_, h := newAuthServer(t)
+	session := cookies(signIn(h, "192.0.2.1:1234", "alice", "secret"))[sessionCookie]
+
+	for _, path := range []string{
+		"/api/project/create", "/api/project/update", "/api/project/delete", "/api/project/rename",
+		"/api/project/duplicate", "/api/project/photos/upload", "/api/project/photos/delete",
+		"/api/project/photos/replace", "/api/project/photos/move", "/api/project/photos/process",
+		"/api/project/layout/update", "/api/project/layout/derive-mobile", "/api/project/layout/history/restore",
+		"/api/index/layout/update", "/api/generate", "/api/jobs/cancel", "/api/config/update",
+		"/api/recoveries/dismiss", "/logout",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path+"?slug=test", nil)
+		req.AddCookie(session)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("GET %s: expected 405, got %d", path, rec.Code)
+		}
+	}
+}
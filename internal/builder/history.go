@@ -0,0 +1,120 @@
+package builder
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+)
+
+// handleLayoutHistory lists a project's layout revisions, newest first
+func (s *Server) handleLayoutHistory(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+	if slug == "" {
+		http.Error(w, "Project slug is required", http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := s.contentMgr.ListLayoutRevisions(slug)
+	if err != nil {
+		log.Error().Err(err).Str("slug", slug).Msg("Failed to list layout revisions")
+		http.Error(w, "Failed to list layout revisions", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"revisions": revisions})
+}
+
+// handleLayoutRevision returns the layout of one revision
+func (s *Server) handleLayoutRevision(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+	id := r.URL.Query().Get("id")
+	if slug == "" || id == "" {
+		http.Error(w, "Project slug and revision id are required", http.StatusBadRequest)
+		return
+	}
+
+	layout, ok := s.loadRevision(w, slug, id)
+	if !ok {
+		return
+	}
+	writeJSON(w, layout)
+}
+
+// handleLayoutDiff lists the placement changes between two revisions. Without
+// "to", the revision is compared with the current layout.
+func (s *Server) handleLayoutDiff(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+	fromID := r.URL.Query().Get("from")
+	if slug == "" || fromID == "" {
+		http.Error(w, "Project slug and from revision are required", http.StatusBadRequest)
+		return
+	}
+
+	from, ok := s.loadRevision(w, slug, fromID)
+	if !ok {
+		return
+	}
+
+	var to *content.LayoutConfig
+	if toID := r.URL.Query().Get("to"); toID != "" {
+		if to, ok = s.loadRevision(w, slug, toID); !ok {
+			return
+		}
+	} else {
+		current, err := s.contentMgr.GetLayout(slug)
+		if err != nil {
+			log.Error().Err(err).Str("slug", slug).Msg("Failed to load layout")
+			http.Error(w, "Failed to load layout", http.StatusInternalServerError)
+			return
+		}
+		to = current
+	}
+
+	writeJSON(w, map[string]interface{}{"changes": content.DiffLayouts(from, to)})
+}
+
+// handleLayoutRestore makes a revision the current layout
+func (s *Server) handleLayoutRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	slug := r.URL.Query().Get("slug")
+	id := r.URL.Query().Get("id")
+	if slug == "" || id == "" {
+		http.Error(w, "Project slug and revision id are required", http.StatusBadRequest)
+		return
+	}
+
+	layout, version, err := s.contentMgr.RestoreLayoutRevision(slug, id)
+	if err != nil {
+		if errors.Is(err, content.ErrRevisionNotFound) {
+			http.Error(w, "Revision not found", http.StatusNotFound)
+			return
+		}
+		log.Error().Err(err).Str("slug", slug).Str("revision", id).Msg("Failed to restore layout")
+		http.Error(w, "Failed to restore layout", http.StatusInternalServerError)
+		return
+	}
+
+	log.Info().Str("slug", slug).Str("revision", id).Msg("Layout restored")
+	setVersion(w, version)
+	writeJSON(w, layout)
+}
+
+// loadRevision loads a layout revision, writing an error response on failure
+func (s *Server) loadRevision(w http.ResponseWriter, slug, id string) (*content.LayoutConfig, bool) {
+	layout, err := s.contentMgr.GetLayoutRevision(slug, id)
+	if err != nil {
+		if errors.Is(err, content.ErrRevisionNotFound) {
+			http.Error(w, "Revision not found", http.StatusNotFound)
+			return nil, false
+		}
+		log.Error().Err(err).Str("slug", slug).Str("revision", id).Msg("Failed to load layout revision")
+		http.Error(w, "Failed to load layout revision", http.StatusInternalServerError)
+		return nil, false
+	}
+	return layout, true
+}
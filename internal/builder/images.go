@@ -0,0 +1,202 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/processing"
+)
+
+// PhotoProcessing is shown for photos waiting in a running processing job, in
+// addition to the processing.Status values
+const PhotoProcessing = "processing"
+
+// builderProcessConfig is the processing used for images processed from the
+// builder, before the settings in site.yaml are applied (see
+// processing.ProjectConfig), as images process does
+var builderProcessConfig = processing.ProcessConfig{
+	Widths:             processing.DefaultWidths,
+	Quality:            85,
+	GenerateThumbnails: true,
+	ThumbnailWidth:     300,
+}
+
+// SetAutoProcess makes the builder process photos in the background as soon as
+// they are uploaded, replaced or moved
+func (s *Server) SetAutoProcess(autoProcess bool) {
+	s.autoProcess = autoProcess
+}
+
+// ProcessPending starts processing jobs for the projects that have photos
+// without up-to-date variants or thumbnails
+func (s *Server) ProcessPending() error {
+	projects, err := s.contentMgr.ListProjects()
+	if err != nil {
+		return fmt.Errorf("failed to list projects: %w", err)
+	}
+	for _, project := range projects {
+		photos, err := s.contentMgr.ListPhotos(project.Slug)
+		if err != nil {
+			return fmt.Errorf("failed to list photos: %w", err)
+		}
+		if pending := countPending(s.photoStatuses(project.Slug, photos)); pending > 0 {
+			log.Info().Str("slug", project.Slug).Int("photos", pending).Msg("Processing photos with missing or outdated images")
+			if _, _, err := s.startProcessing(project.Slug); err != nil {
+				log.Warn().Err(err).Str("slug", project.Slug).Msg("Failed to start processing")
+			}
+		}
+	}
+	return nil
+}
+
+// processChanged processes a project's photos after they changed, when automatic
+// processing is enabled
+func (s *Server) processChanged(slug string) {
+	if !s.autoProcess {
+		return
+	}
+	if _, _, err := s.startProcessing(slug); err != nil {
+		log.Warn().Err(err).Str("slug", slug).Msg("Failed to start processing")
+	}
+}
+
+// startProcessing starts a background job processing a project's photos. If one
+// is running, it runs again when done to pick up photos added since it started.
+// It is not started while the project is being renamed.
+func (s *Server) startProcessing(slug string) (Job, bool, error) {
+	return s.jobs.startOrRerun(&Job{Kind: JobProcess, Title: "Image processing for " + slug, Project: slug, key: JobProcess + ":" + slug},
+		func(ctx context.Context, progress func(step string, done, total int)) error {
+			return s.processProject(ctx, slug, progress)
+		})
+}
+
+// handlePhotoProcess starts processing the photos of a project into the
+// variants and thumbnails the site and the builder use
+func (s *Server) handlePhotoProcess(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	slug := r.URL.Query().Get("slug")
+	if slug == "" {
+		http.Error(w, "Project slug is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.contentMgr.GetProject(slug); err != nil {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	}
+
+	job, started, err := s.startProcessing(slug)
+	if err != nil {
+		triggerMessage(w, "error", "Image processing cannot start while the project is being renamed", false)
+		return
+	}
+	writeJobStarted(w, job, started)
+}
+
+// projectProcessor returns a processor with the project's settings
+func (s *Server) projectProcessor(slug string) (*processing.Processor, error) {
+	config, err := processing.ProjectConfig(s.contentMgr, slug, builderProcessConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load processing settings: %w", err)
+	}
+	return processing.NewProcessor(config), nil
+}
+
+// processProject processes the photos of a project whose variants or thumbnail
+// are missing or outdated
+func (s *Server) processProject(ctx context.Context, slug string, progress func(step string, done, total int)) error {
+	photos, err := s.contentMgr.ListPhotos(slug)
+	if err != nil {
+		return fmt.Errorf("failed to list photos: %w", err)
+	}
+	processor, err := s.projectProcessor(slug)
+	if err != nil {
+		return err
+	}
+	dst := s.imagesDestination(slug)
+
+	var pending []*content.PhotoInfo
+	for _, photo := range photos {
+		status, err := mediaStatus(processor, dst, photo)
+		if err != nil {
+			return err
+		}
+		if status != processing.StatusProcessed {
+			pending = append(pending, photo)
+		}
+	}
+
+	var failed int
+	var firstErr error
+	for i, photo := range pending {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		progress(photo.Filename, i, len(pending))
+		if err := processor.ProcessImageContext(ctx, &processing.FileSource{Path: photo.Path}, dst); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Error().Err(err).Str("slug", slug).Str("filename", photo.Filename).Msg("Failed to process photo")
+			failed++
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", photo.Filename, err)
+			}
+		}
+	}
+	progress("done", len(pending), len(pending))
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d photos failed: %w", failed, len(pending), firstErr)
+	}
+	return nil
+}
+
+// photoStatuses returns the processing status of each photo by hash ID. Photos
+// not up to date are reported as processing while a job for the project runs.
+func (s *Server) photoStatuses(slug string, photos []*content.PhotoInfo) map[string]string {
+	statuses := make(map[string]string, len(photos))
+	processor, err := s.projectProcessor(slug)
+	if err != nil {
+		log.Warn().Err(err).Str("slug", slug).Msg("Failed to check processed images")
+		return statuses
+	}
+	dst := s.imagesDestination(slug)
+	running := s.jobs.running(JobProcess + ":" + slug)
+	for _, photo := range photos {
+		status, err := mediaStatus(processor, dst, photo)
+		if err != nil {
+			log.Warn().Err(err).Str("slug", slug).Msg("Failed to check processed images")
+			return statuses
+		}
+		if running && status != processing.StatusProcessed {
+			status = PhotoProcessing
+		}
+		statuses[photo.HashID] = status
+	}
+	return statuses
+}
+
+// mediaStatus returns the processing status of a photo or video clip
+func mediaStatus(processor *processing.Processor, dst *processing.Destination, photo *content.PhotoInfo) (string, error) {
+	if photo.Video {
+		return processor.VideoStatus(dst, photo.HashID)
+	}
+	return processor.Status(dst, photo.HashID)
+}
+
+// countPending counts the photos that are not processed yet
+func countPending(statuses map[string]string) int {
+	pending := 0
+	for _, status := range statuses {
+		if status != processing.StatusProcessed {
+			pending++
+		}
+	}
+	return pending
+}
@@ -0,0 +1,144 @@
+package builder
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+)
+
+// handleIndexLayoutEditor shows the layout editor for the home page hero grid.
+// Projects with a hero photo take the place of photos, keyed by project slug.
+func (s *Server) handleIndexLayoutEditor(w http.ResponseWriter, r *http.Request) {
+	indexLayout, version, err := s.contentMgr.GetIndexLayoutVersion()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load index layout")
+		indexLayout = &content.IndexLayoutConfig{GridWidth: 12}
+	}
+	if indexLayout.GridWidth == 0 {
+		indexLayout.GridWidth = 12
+	}
+
+	heroes, err := s.projectHeroes()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list project heroes")
+		http.Error(w, "Failed to list projects", http.StatusInternalServerError)
+		return
+	}
+
+	// The editor works with photo placements; project slugs stand in for hash IDs
+	layout := &content.LayoutConfig{
+		GridWidth:        indexLayout.GridWidth,
+		Placements:       toPhotoPlacements(indexLayout.Placements),
+		MobileGridWidth:  indexLayout.MobileGridWidth,
+		MobilePlacements: toPhotoPlacements(indexLayout.MobilePlacements),
+	}
+
+	data := map[string]interface{}{
+		"Mode":    "index",
+		"Project": map[string]string{"Title": "Home Page", "Slug": ""},
+		"Photos":  heroes,
+		"Layout":  layout,
+		"Version": version,
+	}
+	if err := s.templates.ExecuteTemplate(w, "layout-editor.html", data); err != nil {
+		log.Printf("Template error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// handleIndexLayoutGet returns the home page hero grid layout
+func (s *Server) handleIndexLayoutGet(w http.ResponseWriter, r *http.Request) {
+	layout, version, err := s.contentMgr.GetIndexLayoutVersion()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load index layout")
+		http.Error(w, "Failed to load index layout", http.StatusInternalServerError)
+		return
+	}
+	setVersion(w, version)
+	writeJSON(w, layout)
+}
+
+// handleIndexLayoutUpdate validates and saves the home page hero grid layout
+func (s *Server) handleIndexLayoutUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var layout content.IndexLayoutConfig
+	if err := json.NewDecoder(r.Body).Decode(&layout); err != nil {
+		log.Error().Err(err).Msg("Failed to decode index layout")
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Placements must reference projects that have a hero photo
+	heroes, err := s.projectHeroes()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list project heroes")
+		http.Error(w, "Failed to list projects", http.StatusInternalServerError)
+		return
+	}
+	withHero := make(map[string]bool, len(heroes))
+	for _, hero := range heroes {
+		withHero[hero.HashID] = true
+	}
+	if errs := content.ValidateIndexLayout(&layout, withHero); len(errs) > 0 {
+		writeLayoutErrors(w, errs)
+		return
+	}
+
+	version, err := s.contentMgr.SaveIndexLayoutIfVersion(&layout, ifMatch(r))
+	if err != nil {
+		if errors.Is(err, content.ErrVersionConflict) {
+			log.Warn().Msg("Rejected index layout update based on a stale version")
+			writeVersionConflict(w)
+			return
+		}
+		log.Error().Err(err).Msg("Failed to save index layout")
+		http.Error(w, "Failed to save index layout", http.StatusInternalServerError)
+		return
+	}
+
+	log.Info().Int("gridWidth", layout.GridWidth).Int("placements", len(layout.Placements)).Msg("Index layout updated")
+	setVersion(w, version)
+	writeJSON(w, map[string]string{"message": "Index layout updated successfully", "version": version})
+}
+
+// projectHeroes returns the hero photo of every project that has one, with the
+// project slug as hash ID and the project title as file name
+func (s *Server) projectHeroes() ([]*content.PhotoInfo, error) {
+	projects, err := s.contentMgr.ListProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	heroes := []*content.PhotoInfo{}
+	for _, project := range projects {
+		if project.HeroPhoto == "" {
+			continue
+		}
+		photo, err := s.contentMgr.GetPhoto(project.Slug, project.HeroPhoto)
+		if err != nil {
+			log.Warn().Err(err).Str("slug", project.Slug).Str("hero", project.HeroPhoto).Msg("Hero photo not found")
+			continue
+		}
+		hero := *photo
+		hero.HashID = project.Slug
+		hero.Filename = project.Title
+		heroes = append(heroes, &hero)
+	}
+	return heroes, nil
+}
+
+// toPhotoPlacements converts index placements to photo placements keyed by project slug
+func toPhotoPlacements(placements []content.IndexHeroPlacement) []content.PhotoPlacement {
+	result := make([]content.PhotoPlacement, len(placements))
+	for i, p := range placements {
+		result[i] = content.PhotoPlacement{Filename: p.ProjectSlug, Position: p.Position}
+	}
+	return result
+}
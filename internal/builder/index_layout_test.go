@@ -0,0 +1,94 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+)
+
+// TestIndexLayoutEndpoints checks saving, validation and version conflicts of the home page grid
+func TestIndexLayoutEndpoints(t *testing.T) {
+	s := newTestServer(t)
+	withHero, err := s.contentMgr.CreateProject("Portraits", "")
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 30, 20)), nil); err != nil {
+		t.Fatal(err)
+	}
+	photo, _, err := s.contentMgr.AddPhoto(withHero.Slug, "hero.jpg", &buf)
+	if err != nil {
+		t.Fatalf("AddPhoto failed: %v", err)
+	}
+	if err := s.contentMgr.UpdateProjectMeta(withHero.Slug, func(p *content.ProjectMetadata) error {
+		p.HeroPhoto = photo.HashID
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateProjectMeta failed: %v", err)
+	}
+	withoutHero, err := s.contentMgr.CreateProject("Streets", "")
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+
+	update := func(slug, version string) *httptest.ResponseRecorder {
+		layout := content.IndexLayoutConfig{
+			GridWidth: 12,
+			Placements: []content.IndexHeroPlacement{
+				{ProjectSlug: slug, Position: content.GridPosition{TopLeftX: 1, TopLeftY: 1, BottomRightX: 6, BottomRightY: 2}},
+			},
+		}
+		body, _ := json.Marshal(layout)
+		req := httptest.NewRequest(http.MethodPost, "/api/index/layout/update", bytes.NewReader(body))
+		if version != "" {
+			req.Header.Set("If-Match", `"`+version+`"`)
+		}
+		rec := httptest.NewRecorder()
+		s.handleIndexLayoutUpdate(rec, req)
+		return rec
+	}
+	get := func() (*content.IndexLayoutConfig, string) {
+		rec := httptest.NewRecorder()
+		s.handleIndexLayoutGet(rec, httptest.NewRequest(http.MethodGet, "/api/index/layout/get", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Get failed: %d %s", rec.Code, rec.Body)
+		}
+		var layout content.IndexLayoutConfig
+		if err := json.Unmarshal(rec.Body.Bytes(), &layout); err != nil {
+			t.Fatal(err)
+		}
+		return &layout, rec.Header().Get("ETag")
+	}
+
+	if rec := update(withoutHero.Slug, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected projects without a hero photo to be rejected, got %d", rec.Code)
+	}
+	if rec := update(withHero.Slug, ""); rec.Code != http.StatusOK {
+		t.Fatalf("Update failed: %d %s", rec.Code, rec.Body)
+	}
+	layout, etag := get()
+	if len(layout.Placements) != 1 || layout.Placements[0].ProjectSlug != withHero.Slug || etag == "" {
+		t.Errorf("Expected the saved layout with a version, got %+v (ETag %q)", layout, etag)
+	}
+
+	if rec := update(withHero.Slug, "stale"); rec.Code != http.StatusConflict {
+		t.Errorf("Expected a stale version to conflict, got %d", rec.Code)
+	}
+	version := etag[1 : len(etag)-1]
+	if rec := update(withHero.Slug, version); rec.Code != http.StatusOK {
+		t.Errorf("Expected the current version to be accepted, got %d %s", rec.Code, rec.Body)
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleIndexLayoutUpdate(rec, httptest.NewRequest(http.MethodGet, "/api/index/layout/update", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected GET to be rejected, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,361 @@
+package builder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Job kinds
+const (
+	JobGenerate = "generate"
+	JobProcess  = "process"
+)
+
+// Job states
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// errJobHeld is returned when a job is started while its key is held (see hold)
+var errJobHeld = errors.New("job cannot start now")
+
+// jobHistoryLimit is the number of finished jobs kept for the status panel
+const jobHistoryLimit = 20
+
+// Job is a background task started from the builder, such as site generation
+// or image processing. Progress counts pages, images or other steps.
+type Job struct {
+	ID       string     `json:"id"`
+	Kind     string     `json:"kind"`
+	Title    string     `json:"title"`
+	Project  string     `json:"project,omitempty"`
+	Status   string     `json:"status"`
+	Step     string     `json:"step,omitempty"`
+	Done     int        `json:"done"`
+	Total    int        `json:"total"`
+	Error    string     `json:"error,omitempty"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+
+	key    string // Jobs with the same key do not run at the same time
+	rerun  bool   // Run again once done, for work queued while running
+	cancel context.CancelFunc
+}
+
+// jobRunner does the work of a job, reporting progress as it goes
+type jobRunner func(ctx context.Context, progress func(step string, done, total int)) error
+
+// jobManager runs jobs and notifies subscribers of their changes
+type jobManager struct {
+	mu          sync.Mutex
+	nextID      int
+	jobs        []*Job // Oldest first
+	held        map[string]int
+	subscribers map[chan struct{}]bool
+}
+
+func newJobManager() *jobManager {
+	return &jobManager{held: make(map[string]int), subscribers: make(map[chan struct{}]bool)}
+}
+
+// startOrRerun runs a job in the background. If a job with the same key is
+// running, that job is returned instead and started is false; it then runs
+// once more when done, so that it picks up work added in the meantime. A job
+// whose key is held is not started and errJobHeld is returned.
+func (m *jobManager) startOrRerun(job *Job, run jobRunner) (snapshot Job, started bool, err error) {
+	m.mu.Lock()
+	if m.held[job.key] > 0 {
+		m.mu.Unlock()
+		return Job{}, false, errJobHeld
+	}
+	for _, existing := range m.jobs {
+		if existing.key == job.key && existing.Status == JobRunning {
+			existing.rerun = true
+			snapshot := *existing
+			m.mu.Unlock()
+			return snapshot, false, nil
+		}
+	}
+	m.nextID++
+	ctx, cancel := context.WithCancel(context.Background())
+	job.ID = strconv.Itoa(m.nextID)
+	job.Status = JobRunning
+	job.Started = time.Now()
+	job.cancel = cancel
+	m.jobs = append(m.jobs, job)
+	m.prune()
+	snapshot = *job
+	m.mu.Unlock()
+	m.notify()
+
+	log.Info().Str("job", job.ID).Str("kind", job.Kind).Str("project", job.Project).Msg("Job started")
+	go func() {
+		defer cancel()
+		progress := func(step string, done, total int) {
+			m.update(func() {
+				job.Step, job.Done, job.Total = step, done, total
+			})
+		}
+		var err error
+		for again := true; again; {
+			err = run(ctx, progress)
+			m.update(func() {
+				// Checked under the lock so that no rerun request is missed
+				again = err == nil && job.rerun
+				job.rerun = false
+				if again {
+					return
+				}
+				now := time.Now()
+				job.Finished = &now
+				switch {
+				case err == nil:
+					job.Status = JobSucceeded
+				case errors.Is(err, context.Canceled):
+					job.Status = JobCancelled
+				default:
+					job.Status = JobFailed
+					job.Error = err.Error()
+				}
+			})
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Error().Err(err).Str("job", job.ID).Str("kind", job.Kind).Msg("Job failed")
+		} else {
+			log.Info().Str("job", job.ID).Str("kind", job.Kind).Bool("cancelled", err != nil).Msg("Job finished")
+		}
+	}()
+	return snapshot, true, nil
+}
+
+// hold keeps jobs with the keys from starting until release is called, for
+// work that must not overlap them. It fails if one of them is running.
+func (m *jobManager) hold(keys ...string) (release func(), ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		for _, key := range keys {
+			if job.key == key && job.Status == JobRunning {
+				return nil, false
+			}
+		}
+	}
+	for _, key := range keys {
+		m.held[key]++
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			for _, key := range keys {
+				if m.held[key]--; m.held[key] == 0 {
+					delete(m.held, key)
+				}
+			}
+		})
+	}, true
+}
+
+// prune drops the oldest finished jobs beyond jobHistoryLimit. The caller must hold mu.
+func (m *jobManager) prune() {
+	for len(m.jobs) > jobHistoryLimit {
+		i := 0
+		for i < len(m.jobs) && m.jobs[i].Status == JobRunning {
+			i++
+		}
+		if i == len(m.jobs) {
+			return
+		}
+		m.jobs = append(m.jobs[:i], m.jobs[i+1:]...)
+	}
+}
+
+// update applies a change to a job under the lock and notifies subscribers
+func (m *jobManager) update(change func()) {
+	m.mu.Lock()
+	change()
+	m.mu.Unlock()
+	m.notify()
+}
+
+// cancelJob asks a running job to stop. Returns false for unknown or finished jobs.
+func (m *jobManager) cancelJob(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.ID == id && job.Status == JobRunning {
+			job.cancel()
+			return true
+		}
+	}
+	return false
+}
+
+// running reports whether a job with the key is running
+func (m *jobManager) running(key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.key == key && job.Status == JobRunning {
+			return true
+		}
+	}
+	return false
+}
+
+// list returns copies of the jobs, newest first
+func (m *jobManager) list() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]Job, 0, len(m.jobs))
+	for i := len(m.jobs) - 1; i >= 0; i-- {
+		jobs = append(jobs, *m.jobs[i])
+	}
+	return jobs
+}
+
+// get returns a copy of a job
+func (m *jobManager) get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.ID == id {
+			return *job, true
+		}
+	}
+	return Job{}, false
+}
+
+// subscribe returns a channel that receives a value after jobs change, and the
+// function that unsubscribes it. Changes in quick succession are coalesced.
+func (m *jobManager) subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	m.mu.Lock()
+	m.subscribers[ch] = true
+	m.mu.Unlock()
+	return ch, func() {
+		m.mu.Lock()
+		delete(m.subscribers, ch)
+		m.mu.Unlock()
+	}
+}
+
+func (m *jobManager) notify() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for ch := range m.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default: // Already has a pending notification
+		}
+	}
+}
+
+// handleJobs returns the recent jobs, newest first, or one job with ?id=
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if id := r.URL.Query().Get("id"); id != "" {
+		job, ok := s.jobs.get(id)
+		if !ok {
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, job)
+		return
+	}
+	writeJSON(w, s.jobs.list())
+}
+
+// handleJobEvents streams the job list as server-sent events whenever a job
+// starts, progresses or finishes
+func (s *Server) handleJobEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	changes, unsubscribe := s.jobs.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Do not let nginx buffer the stream
+
+	send := func() bool {
+		data, _ := json.Marshal(s.jobs.list())
+		if _, err := fmt.Fprintf(w, "event: jobs\ndata: %s\n\n", data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+	if !send() {
+		return
+	}
+
+	keepAlive := time.NewTicker(25 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changes:
+			if !send() {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// handleJobCancel cancels a running job
+func (s *Server) handleJobCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.URL.Query().Get("id")
+	if !s.jobs.cancelJob(id) {
+		http.Error(w, "No running job with this ID", http.StatusNotFound)
+		return
+	}
+	log.Info().Str("job", id).Msg("Job cancellation requested")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// writeJobStarted responds with the job, and an HX-Trigger toast for htmx requests
+func writeJobStarted(w http.ResponseWriter, job Job, started bool) {
+	message := job.Title + " started"
+	if !started {
+		message = job.Title + " is already running; it will run again to include your changes"
+	}
+	events := map[string]interface{}{
+		"showMessage": map[string]string{"type": "info", "message": message},
+		"jobStarted":  job,
+	}
+	eventJSON, _ := json.Marshal(events)
+	w.Header().Set("HX-Trigger", string(eventJSON))
+	if started {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job)
+		return
+	}
+	writeJSON(w, job)
+}
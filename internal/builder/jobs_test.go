@@ -0,0 +1,92 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestStartOrRerun checks that a job requested while it runs runs once more
+// instead of being dropped
+func TestStartOrRerun(t *testing.T) {
+	m := newJobManager()
+	var runs atomic.Int32
+	release := make(chan struct{})
+	run := func(ctx context.Context, progress func(step string, done, total int)) error {
+		if runs.Add(1) == 1 {
+			<-release
+		}
+		return nil
+	}
+
+	first, started, _ := m.startOrRerun(&Job{Kind: JobGenerate, key: JobGenerate}, run)
+	if !started {
+		t.Fatal("Expected the first job to start")
+	}
+	for i := 0; i < 2; i++ {
+		if job, started, _ := m.startOrRerun(&Job{Kind: JobGenerate, key: JobGenerate}, run); started || job.ID != first.ID {
+			t.Fatalf("Expected the running job %s to be returned, got %s (started %v)", first.ID, job.ID, started)
+		}
+	}
+	close(release)
+
+	waitForJob(t, m, first.ID)
+	if job, _ := m.get(first.ID); job.Status != JobSucceeded {
+		t.Errorf("Expected the job to succeed, got %+v", job)
+	}
+	if n := runs.Load(); n != 2 {
+		t.Errorf("Expected the job to run twice (requests while running are merged), got %d", n)
+	}
+}
+
+// TestHold checks that held keys cannot start jobs until released, and that
+// keys of running jobs cannot be held
+func TestHold(t *testing.T) {
+	m := newJobManager()
+	release := make(chan struct{})
+	run := func(ctx context.Context, progress func(step string, done, total int)) error {
+		<-release
+		return nil
+	}
+
+	unhold, ok := m.hold("a", "b")
+	if !ok {
+		t.Fatal("Expected idle keys to be held")
+	}
+	if _, started, err := m.startOrRerun(&Job{key: "b"}, run); started || !errors.Is(err, errJobHeld) {
+		t.Fatalf("Expected errJobHeld for a held key, got started %v, %v", started, err)
+	}
+	unhold()
+	unhold() // Releasing twice is harmless
+
+	job, started, err := m.startOrRerun(&Job{key: "b"}, run)
+	if !started || err != nil {
+		t.Fatalf("Expected the job to start once released, got started %v, %v", started, err)
+	}
+	if _, ok := m.hold("b"); ok {
+		t.Error("Expected the key of a running job not to be held")
+	}
+	close(release)
+	waitForJob(t, m, job.ID)
+	if _, ok := m.hold("b"); !ok {
+		t.Error("Expected the key to be held once the job finished")
+	}
+}
+
+// waitForJob waits for a job to finish
+func waitForJob(t *testing.T, m *jobManager, id string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, _ := m.get(id)
+		if job.Status != JobRunning {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Job did not finish: %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
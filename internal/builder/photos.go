@@ -0,0 +1,143 @@
+package builder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+)
+
+// handlePhotoDelete removes a photo from a project along with its placements and
+// processed images. The response body is empty so htmx can drop the photo item.
+func (s *Server) handlePhotoDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	slug := r.URL.Query().Get("slug")
+	hashID := r.URL.Query().Get("hash")
+	if slug == "" || hashID == "" {
+		http.Error(w, "Project slug and photo hash are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.contentMgr.DeletePhoto(slug, hashID); err != nil {
+		log.Error().Err(err).Str("slug", slug).Str("hashId", hashID).Msg("Failed to delete photo")
+		triggerMessage(w, "error", fmt.Sprintf("Failed to delete photo: %v", err), false)
+		return
+	}
+	if err := s.imagesDestination(slug).RemovePhoto(hashID); err != nil {
+		log.Warn().Err(err).Str("slug", slug).Str("hashId", hashID).Msg("Failed to remove processed images")
+	}
+
+	log.Info().Str("slug", slug).Str("hashId", hashID).Msg("Photo deleted")
+	triggerMessage(w, "success", "Photo deleted", true)
+}
+
+// handlePhotoReplace replaces a photo with an uploaded file, keeping its layout
+// placements, settings and hero reference. It accepts the same whole-file and
+// chunked requests as handlePhotoUpload, with exactly one file.
+func (s *Server) handlePhotoReplace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	slug := r.URL.Query().Get("slug")
+	hashID := r.URL.Query().Get("hash")
+	if slug == "" || hashID == "" {
+		http.Error(w, "Project slug and photo hash are required", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.contentMgr.GetPhoto(slug, hashID); err != nil {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	}
+
+	s.serveUpload(w, r, slug, false, func(filename string, file io.Reader) uploadResult {
+		return s.replacePhoto(slug, hashID, filename, file)
+	})
+}
+
+// replacePhoto replaces the photo's content and swaps its processed images
+func (s *Server) replacePhoto(slug, hashID, filename string, r io.Reader) uploadResult {
+	photo, err := s.contentMgr.ReplacePhoto(slug, hashID, filename, r)
+	if err != nil {
+		if !errors.Is(err, content.ErrUnsupportedFormat) && !errors.Is(err, content.ErrDuplicatePhoto) {
+			log.Error().Err(err).Str("slug", slug).Str("hashId", hashID).Msg("Failed to replace photo")
+		}
+		return uploadResult{Filename: filename, Error: err.Error()}
+	}
+
+	result := uploadResult{Filename: photo.Filename, HashID: photo.HashID, ThumbPath: photo.ThumbPath}
+	if photo.HashID == hashID {
+		result.Duplicate = true // Same content as the current photo
+		return result
+	}
+
+	dst := s.imagesDestination(slug)
+	if err := dst.RemovePhoto(hashID); err != nil {
+		log.Warn().Err(err).Str("slug", slug).Str("hashId", hashID).Msg("Failed to remove processed images")
+	}
+	if err := s.generateThumbnail(slug, photo); err != nil {
+		log.Error().Err(err).Str("slug", slug).Str("filename", photo.Filename).Msg("Failed to generate thumbnail")
+		result.Error = "replaced, but the thumbnail could not be generated"
+	}
+
+	log.Info().Str("slug", slug).Str("old", hashID).Str("hashId", photo.HashID).Msg("Photo replaced")
+	s.processChanged(slug)
+	return result
+}
+
+// handlePhotoMove moves a photo to another project, carrying over its desktop
+// position, settings and processed images
+func (s *Server) handlePhotoMove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	slug := r.URL.Query().Get("slug")
+	hashID := r.URL.Query().Get("hash")
+	target := r.FormValue("target")
+	if slug == "" || hashID == "" || target == "" {
+		http.Error(w, "Project slug, photo hash and target project are required", http.StatusBadRequest)
+		return
+	}
+
+	photo, err := s.contentMgr.MovePhoto(slug, hashID, target)
+	if err != nil {
+		log.Error().Err(err).Str("slug", slug).Str("hashId", hashID).Str("target", target).Msg("Failed to move photo")
+		triggerMessage(w, "error", fmt.Sprintf("Failed to move photo: %v", err), false)
+		return
+	}
+	if err := s.imagesDestination(slug).MovePhoto(hashID, s.imagesDestination(target)); err != nil {
+		log.Warn().Err(err).Str("slug", slug).Str("hashId", hashID).Msg("Failed to move processed images")
+	}
+
+	log.Info().Str("slug", slug).Str("hashId", hashID).Str("target", target).Msg("Photo moved")
+	s.processChanged(target) // The target project may use other settings, e.g. its own watermark
+	triggerMessage(w, "success", fmt.Sprintf("Moved %s to %s", photo.Filename, target), true)
+}
+
+// triggerMessage responds with a showMessage toast. Unless swap is set, htmx is
+// told to leave the page as it is.
+func triggerMessage(w http.ResponseWriter, messageType, message string, swap bool) {
+	events := map[string]interface{}{
+		"showMessage": map[string]string{
+			"type":    messageType,
+			"message": message,
+		},
+	}
+	eventJSON, _ := json.Marshal(events)
+	w.Header().Set("HX-Trigger", string(eventJSON))
+	if !swap {
+		w.Header().Set("HX-Reswap", "none")
+	}
+	w.WriteHeader(http.StatusOK)
+}
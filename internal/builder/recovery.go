@@ -0,0 +1,30 @@
+package builder
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/util"
+)
+
+// handleRecoveries lists content files that were unreadable and restored from
+// their backups, so the user can check them
+func (s *Server) handleRecoveries(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{
+		"Recoveries": util.Recoveries(),
+	}
+	if err := s.templates.ExecuteTemplate(w, "recoveries.html", data); err != nil {
+		log.Error().Err(err).Msg("Template execution failed")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// handleRecoveriesDismiss hides the recovery notice
+func (s *Server) handleRecoveriesDismiss(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	util.DismissRecoveries()
+	w.WriteHeader(http.StatusOK)
+}
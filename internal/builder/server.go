@@ -2,7 +2,9 @@ package builder
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io/fs"
@@ -11,10 +13,13 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/rs/zerolog/log"
 	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
 	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/generator"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/layouts"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/util"
 )
 
 // Server represents the builder HTTP server
@@ -24,6 +29,13 @@ type Server struct {
 	contentMgr *content.Manager
 	generator  *generator.Generator
 	outputDir  string
+	auth       *Auth // nil when the builder is open
+
+	uploadsMu sync.Mutex
+	uploads   map[string]*chunkedUpload // In-progress chunked photo uploads by upload ID
+
+	jobs        *jobManager // Background generation and image processing
+	autoProcess bool        // Process photos as soon as they change
 }
 
 // NewServer creates a new builder server with content and photos in separate directories
@@ -60,9 +72,16 @@ func NewServer(templatesFS, staticFS fs.FS, contentDir, photosDir, outputDir str
 		contentMgr: contentMgr,
 		generator:  gen,
 		outputDir:  outputDir,
+		uploads:    make(map[string]*chunkedUpload),
+		jobs:       newJobManager(),
 	}, nil
 }
 
+// SetAuth requires sign-in for the routes wrapped by WithAuth
+func (s *Server) SetAuth(auth *Auth) {
+	s.auth = auth
+}
+
 // RegisterRoutes registers all HTTP routes
 func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	// Serve static files for builder
@@ -104,17 +123,40 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/project/create", s.handleProjectCreate)
 	mux.HandleFunc("/api/project/update", s.handleProjectUpdate)
 	mux.HandleFunc("/api/project/delete", s.handleProjectDelete)
+	mux.HandleFunc("/api/project/rename", s.handleProjectRename)
+	mux.HandleFunc("/api/project/duplicate", s.handleProjectDuplicate)
 	mux.HandleFunc("/api/project/photos/list", s.handlePhotoList)
+	mux.HandleFunc("/api/project/photos/upload", s.handlePhotoUpload)
+	mux.HandleFunc("/api/project/photos/delete", s.handlePhotoDelete)
+	mux.HandleFunc("/api/project/photos/replace", s.handlePhotoReplace)
+	mux.HandleFunc("/api/project/photos/move", s.handlePhotoMove)
+	mux.HandleFunc("/api/project/photos/process", s.handlePhotoProcess)
 	mux.HandleFunc("/api/project/layout/get", s.handleLayoutGet)
 	mux.HandleFunc("/api/project/layout/update", s.handleLayoutUpdate)
+	mux.HandleFunc("/api/project/layout/auto", s.handleLayoutAuto)
+	mux.HandleFunc("/api/project/layout/derive-mobile", s.handleLayoutDeriveMobile)
+	mux.HandleFunc("/api/project/layout/history", s.handleLayoutHistory)
+	mux.HandleFunc("/api/project/layout/history/get", s.handleLayoutRevision)
+	mux.HandleFunc("/api/project/layout/history/diff", s.handleLayoutDiff)
+	mux.HandleFunc("/api/project/layout/history/restore", s.handleLayoutRestore)
+	mux.HandleFunc("/api/index/layout/get", s.handleIndexLayoutGet)
+	mux.HandleFunc("/api/index/layout/update", s.handleIndexLayoutUpdate)
 	mux.HandleFunc("/api/generate", s.handleGenerate)
+	mux.HandleFunc("/api/jobs", s.handleJobs)
+	mux.HandleFunc("/api/jobs/events", s.handleJobEvents)
+	mux.HandleFunc("/api/jobs/cancel", s.handleJobCancel)
 	mux.HandleFunc("/api/config/update", s.handleConfigUpdate)
+	mux.HandleFunc("/api/recoveries", s.handleRecoveries)
+	mux.HandleFunc("/api/recoveries/dismiss", s.handleRecoveriesDismiss)
 
 	// Builder UI routes
+	mux.HandleFunc("/login", s.handleLogin)
+	mux.HandleFunc("/logout", s.handleLogout)
 	mux.HandleFunc("/projects", s.handleProjectList)
 	mux.HandleFunc("/project/new", s.handleProjectNew)
 	mux.HandleFunc("/project/", s.handleProjectView)
 	mux.HandleFunc("/layout/", s.handleLayoutEditor)
+	mux.HandleFunc("/index-layout", s.handleIndexLayoutEditor)
 	mux.HandleFunc("/config", s.handleConfigView)
 
 	// Catch-all: serve index for any unmatched route (SPA-like behavior)
@@ -194,10 +236,22 @@ func (s *Server) handleProjectView(w http.ResponseWriter, r *http.Request) {
 		layout = &content.LayoutConfig{GridWidth: 12, Placements: []content.PhotoPlacement{}}
 	}
 
+	// Suggested while editing the category and tags
+	categories, tags, err := s.contentMgr.ListTerms()
+	if err != nil {
+		log.Printf("Error listing tags: %v", err)
+	}
+
+	statuses := s.photoStatuses(slug, photos)
 	data := map[string]interface{}{
-		"Project": project,
-		"Photos":  photos,
-		"Layout":  layout,
+		"Project":       project,
+		"Photos":        photos,
+		"Layout":        layout,
+		"OtherProjects": s.otherProjects(slug),
+		"Statuses":      statuses,
+		"Pending":       countPending(statuses),
+		"Categories":    categories,
+		"Tags":          tags,
 	}
 
 	// If this is not an htmx request (direct navigation), return full page with content
@@ -248,16 +302,18 @@ func (s *Server) handleLayoutEditor(w http.ResponseWriter, r *http.Request) {
 		photos = []*content.PhotoInfo{}
 	}
 
-	layout, err := s.contentMgr.GetLayout(slug)
+	layout, version, err := s.contentMgr.GetLayoutVersion(slug)
 	if err != nil {
 		log.Printf("Error loading layout: %v", err)
 		layout = &content.LayoutConfig{GridWidth: 12, Placements: []content.PhotoPlacement{}}
 	}
 
 	data := map[string]interface{}{
+		"Mode":    "project",
 		"Project": project,
 		"Photos":  photos,
 		"Layout":  layout,
+		"Version": version,
 	}
 
 	if err := s.templates.ExecuteTemplate(w, "layout-editor.html", data); err != nil {
@@ -280,15 +336,16 @@ func (s *Server) handleProjectCreate(w http.ResponseWriter, r *http.Request) {
 
 	title := r.FormValue("title")
 	description := r.FormValue("description")
+	slug := strings.TrimSpace(r.FormValue("slug")) // Optional, derived from the title when empty
 
 	if title == "" {
 		http.Error(w, "Title is required", http.StatusBadRequest)
 		return
 	}
 
-	log.Info().Str("title", title).Msg("Creating new project")
+	log.Info().Str("title", title).Str("slug", slug).Msg("Creating new project")
 
-	project, err := s.contentMgr.CreateProject(title, description)
+	project, err := s.contentMgr.CreateProjectWithSlug(title, description, slug)
 	if err != nil {
 		log.Error().Err(err).Str("title", title).Msg("Failed to create project")
 		// Return 200 OK even on error so htmx renders the content, or use hx-swap-oob.
@@ -326,18 +383,16 @@ func (s *Server) handleProjectUpdate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	slug := r.FormValue("slug")
-	title := r.FormValue("title")
-	description := r.FormValue("description")
-	hidden := r.FormValue("hidden") == "on"
-
-	if slug == "" || title == "" {
+	if slug == "" || r.FormValue("title") == "" {
 		http.Error(w, "Slug and title are required", http.StatusBadRequest)
 		return
 	}
 
-	if err := s.contentMgr.UpdateProject(slug, title, description, hidden); err != nil {
-		log.Printf("Error updating project: %v", err)
-		http.Error(w, "Failed to update project", http.StatusInternalServerError)
+	if err := s.contentMgr.UpdateProjectMeta(slug, func(meta *content.ProjectMetadata) error {
+		return applyProjectForm(meta, r)
+	}); err != nil {
+		log.Error().Err(err).Str("slug", slug).Msg("Failed to update project")
+		triggerMessage(w, "error", fmt.Sprintf("Failed to update project: %v", err), false)
 		return
 	}
 
@@ -345,6 +400,43 @@ func (s *Server) handleProjectUpdate(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "Project updated successfully")
 }
 
+// applyProjectForm copies the project editor's fields into meta
+func applyProjectForm(meta *content.ProjectMetadata, r *http.Request) error {
+	meta.Title = r.FormValue("title")
+	meta.Description = r.FormValue("description")
+	meta.Hidden = r.FormValue("hidden") == "on"
+	meta.Body = strings.TrimSpace(r.FormValue("body"))
+	meta.BodyPosition = r.FormValue("body_position")
+	meta.Date = strings.TrimSpace(r.FormValue("date"))
+	meta.Category = strings.TrimSpace(r.FormValue("category"))
+	meta.Tags = content.NormalizeTags(r.Form["tags"])
+
+	credits := &content.Credits{
+		Client:    strings.TrimSpace(r.FormValue("credit_client")),
+		Agency:    strings.TrimSpace(r.FormValue("credit_agency")),
+		Assistant: strings.TrimSpace(r.FormValue("credit_assistant")),
+		Location:  strings.TrimSpace(r.FormValue("credit_location")),
+	}
+	meta.Credits = nil
+	if len(credits.Entries()) > 0 {
+		meta.Credits = credits
+	}
+
+	theme := &content.ProjectTheme{Background: strings.TrimSpace(r.FormValue("theme_background"))}
+	if gap := strings.TrimSpace(r.FormValue("theme_grid_gap")); gap != "" {
+		n, err := strconv.Atoi(gap)
+		if err != nil {
+			return fmt.Errorf("invalid grid gap %q: expected a number of pixels", gap)
+		}
+		theme.GridGap = &n
+	}
+	meta.Theme = nil
+	if theme.Background != "" || theme.GridGap != nil {
+		meta.Theme = theme
+	}
+	return nil
+}
+
 // handleProjectDelete deletes a project
 func (s *Server) handleProjectDelete(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -368,6 +460,98 @@ func (s *Server) handleProjectDelete(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// handleProjectRename changes a project's slug. Its processed images move along,
+// and the old address redirects to the new one on the generated site.
+func (s *Server) handleProjectRename(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	slug := r.FormValue("slug")
+	newSlug := strings.TrimSpace(r.FormValue("new_slug"))
+	if slug == "" || newSlug == "" {
+		http.Error(w, "Slug and new slug are required", http.StatusBadRequest)
+		return
+	}
+	// Processed images are written under the slugs while a job runs, so none may
+	// run or start until the images have moved
+	release, ok := s.jobs.hold(JobProcess+":"+slug, JobProcess+":"+newSlug)
+	if !ok {
+		triggerMessage(w, "error", "Wait for image processing to finish before renaming the project", false)
+		return
+	}
+	project, err := s.contentMgr.RenameProject(slug, newSlug)
+	if err != nil {
+		release()
+		log.Error().Err(err).Str("slug", slug).Str("newSlug", newSlug).Msg("Failed to rename project")
+		triggerMessage(w, "error", fmt.Sprintf("Failed to rename project: %v", err), false)
+		return
+	}
+	moveErr := moveDir(s.imagesDestination(slug).OutputDir, s.imagesDestination(newSlug).OutputDir)
+	release()
+	if moveErr != nil {
+		log.Warn().Err(moveErr).Str("slug", newSlug).Msg("Failed to move processed images")
+		s.processChanged(newSlug)
+	}
+
+	log.Info().Str("slug", slug).Str("newSlug", newSlug).Msg("Project renamed")
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/project/%s", project.Slug))
+	writeJSON(w, project)
+}
+
+// handleProjectDuplicate copies a project, with its photos, layout and processed
+// images, to a new hidden project
+func (s *Server) handleProjectDuplicate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	slug := r.FormValue("slug")
+	if slug == "" {
+		http.Error(w, "Slug is required", http.StatusBadRequest)
+		return
+	}
+
+	project, err := s.contentMgr.DuplicateProject(slug, strings.TrimSpace(r.FormValue("new_slug")), strings.TrimSpace(r.FormValue("title")))
+	if err != nil {
+		log.Error().Err(err).Str("slug", slug).Msg("Failed to duplicate project")
+		triggerMessage(w, "error", fmt.Sprintf("Failed to duplicate project: %v", err), false)
+		return
+	}
+	src := s.imagesDestination(slug).OutputDir
+	if _, err := os.Stat(src); err == nil {
+		if err := util.CopyDir(src, s.imagesDestination(project.Slug).OutputDir); err != nil {
+			log.Warn().Err(err).Str("slug", project.Slug).Msg("Failed to copy processed images")
+			s.processChanged(project.Slug)
+		}
+	}
+
+	log.Info().Str("slug", slug).Str("copy", project.Slug).Msg("Project duplicated")
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/project/%s", project.Slug))
+	writeJSON(w, project)
+}
+
+// moveDir moves a directory, replacing what is at dst. A missing src is not an error.
+func moveDir(src, dst string) error {
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	return os.Rename(src, dst)
+}
+
 // handlePhotoList returns the photo list for a project
 func (s *Server) handlePhotoList(w http.ResponseWriter, r *http.Request) {
 	slug := r.URL.Query().Get("slug")
@@ -383,9 +567,13 @@ func (s *Server) handlePhotoList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	statuses := s.photoStatuses(slug, photos)
 	data := map[string]interface{}{
-		"Photos":  photos,
-		"Project": map[string]string{"Slug": slug},
+		"Photos":        photos,
+		"Project":       map[string]string{"Slug": slug},
+		"OtherProjects": s.otherProjects(slug),
+		"Statuses":      statuses,
+		"Pending":       countPending(statuses),
 	}
 
 	if err := s.templates.ExecuteTemplate(w, "photo-list.html", data); err != nil {
@@ -394,6 +582,19 @@ func (s *Server) handlePhotoList(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// otherProjects returns the projects other than slug, the targets for moving photos
+func (s *Server) otherProjects(slug string) []*content.ProjectMetadata {
+	var others []*content.ProjectMetadata
+	if projects, err := s.contentMgr.ListProjects(); err == nil {
+		for _, p := range projects {
+			if p.Slug != slug {
+				others = append(others, p)
+			}
+		}
+	}
+	return others
+}
+
 // handleLayoutGet returns the layout configuration
 func (s *Server) handleLayoutGet(w http.ResponseWriter, r *http.Request) {
 	slug := r.URL.Query().Get("slug")
@@ -402,13 +603,14 @@ func (s *Server) handleLayoutGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	layout, err := s.contentMgr.GetLayout(slug)
+	layout, version, err := s.contentMgr.GetLayoutVersion(slug)
 	if err != nil {
 		log.Printf("Error loading layout: %v", err)
 		http.Error(w, "Failed to load layout", http.StatusInternalServerError)
 		return
 	}
 
+	setVersion(w, version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(layout)
 }
@@ -433,26 +635,26 @@ func (s *Server) handleLayoutUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate grid width
-	if layout.GridWidth < 1 || layout.GridWidth > 24 {
-		http.Error(w, "Grid width must be between 1 and 24", http.StatusBadRequest)
+	// Reject out-of-bounds or overlapping placements and unknown photos
+	photos, err := s.contentMgr.ListPhotos(slug)
+	if err != nil {
+		log.Error().Err(err).Str("slug", slug).Msg("Failed to list photos")
+		http.Error(w, "Failed to list photos", http.StatusInternalServerError)
+		return
+	}
+	if errs := content.ValidateLayout(&layout, content.PhotoIDs(photos)); len(errs) > 0 {
+		writeLayoutErrors(w, errs)
 		return
 	}
 
-	// Validate placements
-	for _, placement := range layout.Placements {
-		pos := placement.Position
-		// Expect 1-based indices (inclusive)
-		if pos.TopLeftX < 1 || pos.TopLeftX > layout.GridWidth ||
-			pos.BottomRightX < 1 || pos.BottomRightX > layout.GridWidth ||
-			pos.TopLeftY < 1 || pos.BottomRightY < 1 ||
-			pos.TopLeftX > pos.BottomRightX || pos.TopLeftY > pos.BottomRightY {
-			http.Error(w, fmt.Sprintf("Invalid position for %s", placement.Filename), http.StatusBadRequest)
+	// With If-Match, only save over the version the editor loaded
+	version, err := s.contentMgr.UpdateLayoutIfVersion(slug, &layout, ifMatch(r))
+	if err != nil {
+		if errors.Is(err, content.ErrVersionConflict) {
+			log.Warn().Str("slug", slug).Msg("Rejected layout update based on a stale version")
+			writeVersionConflict(w)
 			return
 		}
-	}
-
-	if err := s.contentMgr.UpdateLayout(slug, &layout); err != nil {
 		log.Error().Err(err).Str("slug", slug).Msg("Failed to update layout")
 		http.Error(w, "Failed to update layout", http.StatusInternalServerError)
 		return
@@ -460,51 +662,140 @@ func (s *Server) handleLayoutUpdate(w http.ResponseWriter, r *http.Request) {
 
 	log.Info().Str("slug", slug).Int("gridWidth", layout.GridWidth).Int("placements", len(layout.Placements)).Msg("Layout updated")
 
+	setVersion(w, version)
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Layout updated successfully"})
+	json.NewEncoder(w).Encode(map[string]string{"message": "Layout updated successfully", "version": version})
 }
 
-// handleGenerate triggers static site generation
-func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+// ifMatch returns the layout version a request was based on, from its If-Match
+// header, or "" to save unconditionally
+func ifMatch(r *http.Request) string {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "*" {
+		return ""
+	}
+	return strings.Trim(strings.TrimPrefix(value, "W/"), `"`)
+}
+
+// setVersion sends a layout version as the response's ETag
+func setVersion(w http.ResponseWriter, version string) {
+	if version != "" {
+		w.Header().Set("ETag", `"`+version+`"`)
+	}
+}
+
+// writeVersionConflict responds with 409 when a layout was saved by someone
+// else since the editor loaded it
+func writeVersionConflict(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": "The layout was changed elsewhere since it was loaded; reload the editor to get the latest version",
+	})
+}
+
+// writeLayoutErrors responds with 400 and the layout problems as JSON, so the
+// editor can point at the offending placements
+func writeLayoutErrors(w http.ResponseWriter, errs content.LayoutErrors) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  fmt.Sprintf("Invalid layout: %v", errs),
+		"errors": errs,
+	})
+}
+
+// handleLayoutDeriveMobile returns a mobile layout derived from the desktop
+// placements in the request body. The layout is not saved.
+func (s *Server) handleLayoutDeriveMobile(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	log.Info().Msg("Starting site generation")
+	var layout content.LayoutConfig
+	if err := json.NewDecoder(r.Body).Decode(&layout); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if layout.GridWidth < 1 || layout.GridWidth > 24 {
+		http.Error(w, "Grid width must be between 1 and 24", http.StatusBadRequest)
+		return
+	}
+	if layout.MobileGridWidth < 1 || layout.MobileGridWidth > 24 {
+		layout.MobileGridWidth = layouts.AutoMobileGridWidth
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(content.LayoutConfig{
+		GridWidth:        layout.GridWidth,
+		Placements:       layout.Placements,
+		MobileGridWidth:  layout.MobileGridWidth,
+		MobilePlacements: layouts.DeriveMobile(&layout, layout.MobileGridWidth),
+	})
+}
 
-	// Generate with /preview base URL for local preview (no external image host)
-	if err := s.generator.Generate("/preview", ""); err != nil {
-		log.Error().Err(err).Msg("Site generation failed")
+// handleLayoutAuto returns an automatically generated desktop and mobile layout
+// for all photos of a project. The layout is not saved, so it can be adjusted in
+// the editor first.
+func (s *Server) handleLayoutAuto(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+	if slug == "" {
+		http.Error(w, "Project slug is required", http.StatusBadRequest)
+		return
+	}
 
-		// Send error toast trigger
-		events := map[string]interface{}{
-			"showMessage": map[string]string{
-				"type":    "error",
-				"message": fmt.Sprintf("Failed to generate site: %v", err),
-			},
-		}
+	style, err := layouts.ParseStyle(r.URL.Query().Get("style"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		eventJSON, _ := json.Marshal(events)
-		w.Header().Set("HX-Trigger", string(eventJSON))
-		w.WriteHeader(http.StatusOK) // 200 OK so client processes events
+	photos, err := s.contentMgr.ListPhotos(slug)
+	if err != nil {
+		log.Error().Err(err).Str("slug", slug).Msg("Failed to list photos")
+		http.Error(w, "Failed to list photos", http.StatusInternalServerError)
 		return
 	}
+	if current, err := s.contentMgr.GetLayout(slug); err == nil {
+		photos = layouts.PlacementOrder(photos, current.Placements)
+	}
 
-	log.Info().Msg("Site generated successfully")
+	layout, err := layouts.AutoLayout(photos, style)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// Send success toast trigger + enable preview trigger
-	events := map[string]interface{}{
-		"showMessage": map[string]string{
-			"type":    "success",
-			"message": "Site generated successfully!",
-		},
-		"enablePreview": true,
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(layout)
+}
+
+// handleGenerate starts static site generation as a background job. While a
+// generation is running, the running job is returned instead of starting a
+// second one that would write the output directory at the same time, and it
+// generates again once done so that edits made meanwhile are included.
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	eventJSON, _ := json.Marshal(events)
-	w.Header().Set("HX-Trigger", string(eventJSON))
-	w.WriteHeader(http.StatusOK)
+	job, started, err := s.jobs.startOrRerun(&Job{Kind: JobGenerate, Title: "Site generation", key: JobGenerate},
+		func(ctx context.Context, progress func(step string, done, total int)) error {
+			s.generator.SetProgress(func(p generator.Progress) {
+				progress(p.Step, p.Done, p.Total)
+			})
+			defer s.generator.SetProgress(nil)
+
+			// Generate with /preview base URL for local preview (no external image host)
+			return s.generator.GenerateContext(ctx, "/preview", "")
+		})
+	if err != nil {
+		triggerMessage(w, "error", fmt.Sprintf("Failed to start site generation: %v", err), false)
+		return
+	}
+	writeJobStarted(w, job, started)
 }
 
 // handleConfigView renders the site configuration editor
@@ -569,13 +860,39 @@ func (s *Server) handleConfigUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	meta, err := s.contentMgr.LoadSiteMeta()
+	// Load, modify and save under the site.yaml lock, so concurrent edits are not lost
+	err := s.contentMgr.UpdateSiteMeta(func(meta *content.SiteMetadata) error {
+		applyConfigForm(meta, r)
+		return nil
+	})
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to load site config")
-		http.Error(w, "Failed to load site configuration", http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to save site config")
+
+		events := map[string]interface{}{
+			"showMessage": map[string]string{
+				"type":    "error",
+				"message": fmt.Sprintf("Failed to update config: %v", err),
+			},
+		}
+		eventJSON, _ := json.Marshal(events)
+		w.Header().Set("HX-Trigger", string(eventJSON))
+		w.WriteHeader(http.StatusOK)
 		return
 	}
 
+	events := map[string]interface{}{
+		"showMessage": map[string]string{
+			"type":    "success",
+			"message": "Configuration updated successfully!",
+		},
+	}
+	eventJSON, _ := json.Marshal(events)
+	w.Header().Set("HX-Trigger", string(eventJSON))
+	w.WriteHeader(http.StatusOK)
+}
+
+// applyConfigForm copies the configuration form values into the site metadata
+func applyConfigForm(meta *content.SiteMetadata, r *http.Request) {
 	// Basic Settings
 	meta.WebsiteName = r.FormValue("website_name")
 	meta.Copyright = r.FormValue("copyright")
@@ -625,29 +942,4 @@ func (s *Server) handleConfigUpdate(w http.ResponseWriter, r *http.Request) {
 	// But let's assume if there are keys, we renew the list.)
 	// Actually, careful: if we don't send any (e.g. no projects), it becomes empty. Correct.
 	meta.Projects = projectOrders
-
-	if err := s.contentMgr.SaveSiteMeta(meta); err != nil {
-		log.Error().Err(err).Msg("Failed to save site config")
-
-		events := map[string]interface{}{
-			"showMessage": map[string]string{
-				"type":    "error",
-				"message": fmt.Sprintf("Failed to update config: %v", err),
-			},
-		}
-		eventJSON, _ := json.Marshal(events)
-		w.Header().Set("HX-Trigger", string(eventJSON))
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
-	events := map[string]interface{}{
-		"showMessage": map[string]string{
-			"type":    "success",
-			"message": "Configuration updated successfully!",
-		},
-	}
-	eventJSON, _ := json.Marshal(events)
-	w.Header().Set("HX-Trigger", string(eventJSON))
-	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,342 @@
+package builder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/processing"
+)
+
+const (
+	// maxUploadRequestSize caps a single upload request (one file or one chunk)
+	maxUploadRequestSize = 64 << 20
+	// maxUploadSize caps the assembled size of a chunked upload
+	maxUploadSize = 2 << 30
+	// uploadMemory is how much of a multipart request is buffered in memory
+	uploadMemory = 8 << 20
+	// uploadTTL is how long a chunked upload may wait for its next chunk before it is discarded
+	uploadTTL = time.Hour
+)
+
+// uploadIDPattern restricts client-chosen upload IDs to safe file names
+var uploadIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{8,64}$`)
+
+// chunkedUpload tracks a file uploaded in several requests
+type chunkedUpload struct {
+	slug     string
+	filename string
+	path     string // Partial file in the system temp directory
+	total    int
+
+	mu        sync.Mutex // Held while a chunk is written, so other uploads do not wait
+	next      int        // Index of the next expected chunk
+	size      int64      // Bytes received so far
+	discarded bool       // Set once the upload is stored, aborted, restarted or expired
+
+	lastChunk time.Time // When the last chunk arrived. Guarded by Server.uploadsMu.
+}
+
+// uploadResult describes the outcome for one uploaded file
+type uploadResult struct {
+	Filename  string `json:"filename"`
+	HashID    string `json:"hashId,omitempty"`
+	ThumbPath string `json:"thumbPath,omitempty"`
+	Duplicate bool   `json:"duplicate,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// handlePhotoUpload stores uploaded photos in the project's photos directory and
+// generates their builder thumbnails.
+//
+// Whole files are sent as one or more "file" parts. Large files can be sent in
+// sequential chunks, one per request, with the "upload_id", "chunk_index" and
+// "total_chunks" fields; the photo is stored once the last chunk arrives.
+func (s *Server) handlePhotoUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	slug := r.URL.Query().Get("slug")
+	if slug == "" {
+		http.Error(w, "Project slug is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.contentMgr.GetProject(slug); err != nil {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	}
+
+	s.serveUpload(w, r, slug, true, func(filename string, file io.Reader) uploadResult {
+		return s.storePhoto(slug, filename, file)
+	})
+}
+
+// serveUpload reads the file(s) of an upload request and passes each complete
+// file to store. Incomplete chunked uploads are acknowledged with the index of
+// the received chunk; otherwise the results are returned as {"photos": [...]}.
+func (s *Server) serveUpload(w http.ResponseWriter, r *http.Request, slug string, multiple bool, store func(filename string, file io.Reader) uploadResult) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadRequestSize)
+	if err := r.ParseMultipartForm(uploadMemory); err != nil {
+		http.Error(w, "Invalid upload (too large or malformed)", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["file"]
+	if len(files) == 0 {
+		http.Error(w, "No file uploaded", http.StatusBadRequest)
+		return
+	}
+
+	var results []uploadResult
+	if uploadID := r.FormValue("upload_id"); uploadID != "" {
+		if len(files) != 1 {
+			http.Error(w, "Chunked uploads take one file part per request", http.StatusBadRequest)
+			return
+		}
+		result, done, status, err := s.receiveChunk(slug, uploadID, r, files[0], store)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if !done {
+			index, _ := strconv.Atoi(r.FormValue("chunk_index")) // Validated by receiveChunk
+			writeJSON(w, map[string]interface{}{"received": index})
+			return
+		}
+		results = append(results, result)
+	} else {
+		if !multiple && len(files) != 1 {
+			http.Error(w, "Exactly one file is expected", http.StatusBadRequest)
+			return
+		}
+		for _, header := range files {
+			results = append(results, storeUploadedFile(header, store))
+		}
+	}
+
+	writeJSON(w, map[string]interface{}{"photos": results})
+}
+
+// receiveChunk appends a chunk to its partial file. When the last chunk arrives
+// the assembled file is stored and done is true. Only the upload's own lock is
+// held while writing, and none while storing.
+func (s *Server) receiveChunk(slug, uploadID string, r *http.Request, header *multipart.FileHeader, store func(filename string, file io.Reader) uploadResult) (uploadResult, bool, int, error) {
+	if !uploadIDPattern.MatchString(uploadID) {
+		return uploadResult{}, false, http.StatusBadRequest, fmt.Errorf("invalid upload_id")
+	}
+	index, err := strconv.Atoi(r.FormValue("chunk_index"))
+	if err != nil || index < 0 {
+		return uploadResult{}, false, http.StatusBadRequest, fmt.Errorf("invalid chunk_index")
+	}
+	total, err := strconv.Atoi(r.FormValue("total_chunks"))
+	if err != nil || total < 1 || index >= total {
+		return uploadResult{}, false, http.StatusBadRequest, fmt.Errorf("invalid total_chunks")
+	}
+
+	upload, status, err := s.lookupUpload(slug, uploadID, header.Filename, index, total)
+	if err != nil {
+		return uploadResult{}, false, status, err
+	}
+
+	upload.mu.Lock()
+	if upload.discarded {
+		upload.mu.Unlock()
+		return uploadResult{}, false, http.StatusBadRequest, fmt.Errorf("unknown upload_id")
+	}
+	if index != upload.next {
+		upload.mu.Unlock()
+		return uploadResult{}, false, http.StatusConflict, fmt.Errorf("expected chunk %d, got %d", upload.next, index)
+	}
+	if upload.size+header.Size > maxUploadSize {
+		s.abortUpload(uploadID, upload)
+		upload.mu.Unlock()
+		return uploadResult{}, false, http.StatusRequestEntityTooLarge, fmt.Errorf("upload exceeds %d MB", maxUploadSize>>20)
+	}
+	if err := appendChunk(upload.path, header); err != nil {
+		log.Error().Err(err).Str("uploadId", uploadID).Msg("Failed to write upload chunk")
+		s.abortUpload(uploadID, upload)
+		upload.mu.Unlock()
+		return uploadResult{}, false, http.StatusInternalServerError, fmt.Errorf("failed to write chunk")
+	}
+	upload.next++
+	upload.size += header.Size
+
+	if upload.next < upload.total {
+		upload.mu.Unlock()
+		return uploadResult{}, false, http.StatusOK, nil
+	}
+
+	// Last chunk: claim the assembled file, then store it without holding a lock
+	upload.discarded = true
+	s.forgetUpload(uploadID, upload)
+	upload.mu.Unlock()
+
+	defer os.Remove(upload.path)
+	file, err := os.Open(upload.path)
+	if err != nil {
+		return uploadResult{}, false, http.StatusInternalServerError, fmt.Errorf("failed to read upload")
+	}
+	defer file.Close()
+	return store(upload.filename, file), true, http.StatusOK, nil
+}
+
+// lookupUpload returns the chunked upload a chunk belongs to, starting a new one
+// for the first chunk. Uploads without a chunk for longer than uploadTTL are
+// discarded on the way.
+func (s *Server) lookupUpload(slug, uploadID, filename string, index, total int) (*chunkedUpload, int, error) {
+	s.uploadsMu.Lock()
+	defer s.uploadsMu.Unlock()
+
+	now := time.Now()
+	s.expireUploads(now)
+
+	upload, ok := s.uploads[uploadID]
+	if index == 0 {
+		if ok {
+			go upload.discard() // Restarted upload
+		}
+		file, err := os.CreateTemp("", "portfolio-upload-*")
+		if err != nil {
+			log.Error().Err(err).Str("uploadId", uploadID).Msg("Failed to create upload file")
+			return nil, http.StatusInternalServerError, fmt.Errorf("failed to start upload")
+		}
+		file.Close()
+		upload = &chunkedUpload{slug: slug, filename: filename, path: file.Name(), total: total}
+		s.uploads[uploadID] = upload
+	} else if !ok || upload.slug != slug || upload.total != total {
+		return nil, http.StatusBadRequest, fmt.Errorf("unknown upload_id")
+	}
+	upload.lastChunk = now
+	return upload, http.StatusOK, nil
+}
+
+// expireUploads discards the uploads whose last chunk is older than uploadTTL.
+// The caller must hold uploadsMu.
+func (s *Server) expireUploads(now time.Time) {
+	for id, upload := range s.uploads {
+		if now.Sub(upload.lastChunk) > uploadTTL {
+			log.Info().Str("uploadId", id).Str("filename", upload.filename).Msg("Discarding abandoned upload")
+			delete(s.uploads, id)
+			go upload.discard() // Waits for a chunk still being written
+		}
+	}
+}
+
+// abortUpload discards an upload after a failed chunk. The caller must hold upload.mu.
+func (s *Server) abortUpload(uploadID string, upload *chunkedUpload) {
+	upload.discarded = true
+	os.Remove(upload.path)
+	s.forgetUpload(uploadID, upload)
+}
+
+// forgetUpload removes an upload from the uploads map, unless it was replaced by a restart
+func (s *Server) forgetUpload(uploadID string, upload *chunkedUpload) {
+	s.uploadsMu.Lock()
+	defer s.uploadsMu.Unlock()
+	if s.uploads[uploadID] == upload {
+		delete(s.uploads, uploadID)
+	}
+}
+
+// discard removes the partial file of an upload no longer in the uploads map
+func (u *chunkedUpload) discard() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.discarded = true
+	os.Remove(u.path)
+}
+
+// appendChunk appends a chunk to the partial file
+func appendChunk(path string, header *multipart.FileHeader) error {
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	in, err := header.Open()
+	if err != nil {
+		out.Close()
+		return err
+	}
+	defer in.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// storeUploadedFile passes a whole file from a multipart request to store
+func storeUploadedFile(header *multipart.FileHeader, store func(filename string, file io.Reader) uploadResult) uploadResult {
+	file, err := header.Open()
+	if err != nil {
+		return uploadResult{Filename: header.Filename, Error: "failed to read upload"}
+	}
+	defer file.Close()
+	return store(header.Filename, file)
+}
+
+// storePhoto adds the photo to the project and generates its thumbnail
+func (s *Server) storePhoto(slug, filename string, r io.Reader) uploadResult {
+	photo, duplicate, err := s.contentMgr.AddPhoto(slug, filename, r)
+	if err != nil {
+		if !errors.Is(err, content.ErrUnsupportedFormat) {
+			log.Error().Err(err).Str("slug", slug).Str("filename", filename).Msg("Failed to store uploaded photo")
+		}
+		return uploadResult{Filename: filename, Error: err.Error()}
+	}
+
+	result := uploadResult{
+		Filename:  photo.Filename,
+		HashID:    photo.HashID,
+		ThumbPath: photo.ThumbPath,
+		Duplicate: duplicate,
+	}
+	if duplicate {
+		log.Info().Str("slug", slug).Str("filename", filename).Str("existing", photo.Filename).Msg("Skipped duplicate upload")
+		return result
+	}
+
+	if err := s.generateThumbnail(slug, photo); err != nil {
+		log.Error().Err(err).Str("slug", slug).Str("filename", photo.Filename).Msg("Failed to generate thumbnail")
+		result.Error = "stored, but the thumbnail could not be generated"
+	}
+
+	log.Info().Str("slug", slug).Str("filename", photo.Filename).Str("hashId", photo.HashID).Msg("Photo uploaded")
+	s.processChanged(slug)
+	return result
+}
+
+// imagesDestination returns where the processed images of a project are stored
+func (s *Server) imagesDestination(slug string) *processing.Destination {
+	// Thumbnails live next to the processed variants: {output}/images/{slug}/.thumbs/
+	return &processing.Destination{OutputDir: filepath.Join(s.outputDir, "images", slug)}
+}
+
+// generateThumbnail generates the builder thumbnail of a photo
+func (s *Server) generateThumbnail(slug string, photo *content.PhotoInfo) error {
+	processor := processing.NewProcessor(processing.ProcessConfig{Quality: 85, GenerateThumbnails: true})
+	_, err := processor.ProcessThumbnail(&processing.FileSource{Path: photo.Path}, s.imagesDestination(slug))
+	return err
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
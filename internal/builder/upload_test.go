@@ -0,0 +1,118 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/assets"
+)
+
+// newTestServer returns a builder server over empty content, photos and output directories
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	s, err := NewServer(assets.TemplatesFS, assets.StaticFS, filepath.Join(dir, "content"), filepath.Join(dir, "photos"), filepath.Join(dir, "dist"))
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	return s
+}
+
+// uploadChunk posts one chunk of a chunked upload and returns the response
+func uploadChunk(s *Server, slug, uploadID string, index, total int, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("upload_id", uploadID)
+	form.WriteField("chunk_index", fmt.Sprint(index))
+	form.WriteField("total_chunks", fmt.Sprint(total))
+	part, _ := form.CreateFormFile("file", "photo.jpg")
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/project/photos/upload?slug="+slug, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	s.handlePhotoUpload(rec, req)
+	return rec
+}
+
+// TestChunkedUpload checks assembly, per-upload locking and the expiry of abandoned uploads
+func TestChunkedUpload(t *testing.T) {
+	s := newTestServer(t)
+	project, err := s.contentMgr.CreateProject("Uploads", "")
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 30, 20)), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	half := len(data) / 2
+
+	if rec := uploadChunk(s, project.Slug, "upload-one", 0, 2, data[:half]); rec.Code != http.StatusOK {
+		t.Fatalf("First chunk failed: %d %s", rec.Code, rec.Body)
+	}
+	if rec := uploadChunk(s, project.Slug, "upload-one", 1, 3, data[half:]); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a chunk with another total to be rejected, got %d", rec.Code)
+	}
+
+	// A chunk being written only holds up its own upload
+	s.uploadsMu.Lock()
+	first := s.uploads["upload-one"]
+	s.uploadsMu.Unlock()
+	first.mu.Lock()
+	other := make(chan int)
+	go func() { other <- uploadChunk(s, project.Slug, "upload-two", 0, 2, data[:half]).Code }()
+	select {
+	case code := <-other:
+		if code != http.StatusOK {
+			t.Errorf("Expected the other upload to succeed, got %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected other uploads not to wait for a chunk being written")
+	}
+	first.mu.Unlock()
+
+	rec := uploadChunk(s, project.Slug, "upload-one", 1, 2, data[half:])
+	var result struct{ Photos []uploadResult }
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || len(result.Photos) != 1 || result.Photos[0].Error != "" {
+		t.Fatalf("Expected the assembled photo to be stored, got %d %s", rec.Code, rec.Body)
+	}
+	if _, err := os.Stat(first.path); !os.IsNotExist(err) {
+		t.Error("Expected the partial file to be removed once stored")
+	}
+
+	// Abandoned uploads are discarded with their partial file
+	s.uploadsMu.Lock()
+	abandoned := s.uploads["upload-two"]
+	abandoned.lastChunk = time.Now().Add(-2 * uploadTTL)
+	s.uploadsMu.Unlock()
+	uploadChunk(s, project.Slug, "upload-three", 0, 2, data[:half])
+	if rec := uploadChunk(s, project.Slug, "upload-two", 1, 2, data[half:]); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected the expired upload to be unknown, got %d", rec.Code)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		abandoned.mu.Lock()
+		discarded := abandoned.discarded
+		abandoned.mu.Unlock()
+		if discarded || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := os.Stat(abandoned.path); !os.IsNotExist(err) {
+		t.Error("Expected the partial file of the expired upload to be removed")
+	}
+}
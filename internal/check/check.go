@@ -0,0 +1,378 @@
+// Package check lints a content directory and, optionally, a built site for
+// problems that would otherwise only show up as 404s once deployed.
+package check
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/processing"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/util"
+)
+
+// Issue severities. Only errors make a check fail.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue codes, in addition to the layout validation codes of package content
+const (
+	CodeHeroPhotoNotFound     = "hero_photo_not_found"
+	CodeUnknownOrderedProject = "unknown_ordered_project"
+	CodeMissingVariant        = "missing_variant"
+	CodeMissingFavicon        = "missing_favicon"
+	CodeBrokenLink            = "broken_link"
+	CodeSkipped               = "skipped"
+	CodeUnreadableFile        = "unreadable_file"
+	CodeReservedSlug          = "reserved_slug"
+)
+
+// Options selects what to check
+type Options struct {
+	ContentDir string
+	PhotosDir  string
+	// ImagesDir holds processed images (<slug>/<hashID>/<hashID>-<width>w.webp).
+	// Empty skips the variant check, e.g. when images are served from a CDN.
+	ImagesDir string
+	// DistDir is a built site whose internal links are checked. Empty skips the link check.
+	DistDir string
+	// Templates are scanned for favicon references; later ones override earlier ones
+	Templates []fs.FS
+}
+
+// Issue is one problem found
+type Issue struct {
+	Severity string               `json:"severity"`
+	Code     string               `json:"code"`
+	Project  string               `json:"project,omitempty"`
+	File     string               `json:"file,omitempty"`
+	Message  string               `json:"message"`
+	Layout   *content.LayoutError `json:"layout,omitempty"`
+}
+
+// Report lists the issues found by Run
+type Report struct {
+	Projects int      `json:"projects"`
+	Errors   int      `json:"errors"`
+	Warnings int      `json:"warnings"`
+	Issues   []*Issue `json:"issues"`
+}
+
+func (r *Report) add(issue *Issue) {
+	if issue.Severity == SeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+	r.Issues = append(r.Issues, issue)
+}
+
+// Run checks layouts, hero photos, the project order in site.yaml, processed
+// variants, favicons and, when a built site is given, its internal links.
+// Unreadable content files that were read from their backups are errors.
+func Run(opts Options) (*Report, error) {
+	contentMgr := content.NewManagerWithPhotosDir(opts.ContentDir, opts.PhotosDir)
+	report := &Report{Issues: []*Issue{}}
+	recovered := len(util.Recoveries())
+
+	projects, err := contentMgr.ListProjects()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	report.Projects = len(projects)
+
+	known := make(map[string]bool, len(projects))
+	heroes := make(map[string]bool)
+	for _, project := range projects {
+		known[project.Slug] = true
+		if project.HeroPhoto != "" {
+			heroes[project.Slug] = true
+		}
+		if err := checkProject(contentMgr, project, opts.ImagesDir, report); err != nil {
+			return nil, err
+		}
+	}
+
+	indexLayout, err := contentMgr.GetIndexLayout()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range content.ValidateIndexLayout(indexLayout, heroes) {
+		report.add(&Issue{Severity: SeverityError, Code: e.Code, File: "index-layout.yaml", Message: e.Message, Layout: e})
+	}
+
+	siteMeta, err := contentMgr.LoadSiteMeta()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load site metadata: %w", err)
+	}
+	for _, order := range siteMeta.Projects {
+		if !known[order.Slug] {
+			report.add(&Issue{
+				Severity: SeverityError, Code: CodeUnknownOrderedProject, File: "site.yaml",
+				Message: fmt.Sprintf("project order lists %s, which does not exist", order.Slug),
+			})
+		}
+	}
+
+	if opts.ImagesDir != "" {
+		if _, err := os.Stat(opts.ImagesDir); err != nil {
+			report.add(&Issue{
+				Severity: SeverityWarning, Code: CodeSkipped,
+				Message: fmt.Sprintf("images directory %s not found; processed variants were not checked", opts.ImagesDir),
+			})
+		}
+	}
+
+	for _, recovery := range util.Recoveries()[recovered:] {
+		report.add(&Issue{
+			Severity: SeverityError, Code: CodeUnreadableFile, File: recovery.Path,
+			Message: fmt.Sprintf("could not be read (%s); checked its backup %s%s instead", recovery.Err, recovery.Path, util.BackupSuffix),
+		})
+	}
+
+	missingFavicons, err := checkFavicons(opts.ContentDir, opts.Templates, report)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DistDir != "" {
+		if err := checkLinks(opts.DistDir, missingFavicons, report); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// checkProject checks a project's slug, layout, hero photo and processed variants
+func checkProject(contentMgr *content.Manager, project *content.ProjectMetadata, imagesDir string, report *Report) error {
+	slug := project.Slug
+	layoutFile := filepath.ToSlash(filepath.Join("projects", slug, "layout.yaml"))
+	if content.ReservedSlug(slug) {
+		report.add(&Issue{
+			Severity: SeverityError, Code: CodeReservedSlug, Project: slug,
+			Message: fmt.Sprintf("slug %s is used by the site's own pages; rename the project", slug),
+		})
+	}
+
+	layout, err := contentMgr.GetLayout(slug)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		layout = &content.LayoutConfig{} // No layout yet
+	} else {
+		errs, err := contentMgr.ValidateProjectLayout(slug)
+		if err != nil {
+			return err
+		}
+		for _, e := range errs {
+			report.add(&Issue{Severity: SeverityError, Code: e.Code, Project: slug, File: layoutFile, Message: e.Message, Layout: e})
+		}
+	}
+
+	// The hero photo can only be looked up when the originals are available
+	if project.HeroPhoto != "" {
+		if _, err := os.Stat(contentMgr.ProjectPhotosDir(slug)); err == nil {
+			if _, err := contentMgr.GetPhoto(slug, project.HeroPhoto); errors.Is(err, content.ErrPhotoNotFound) {
+				report.add(&Issue{
+					Severity: SeverityError, Code: CodeHeroPhotoNotFound, Project: slug,
+					File:    filepath.ToSlash(filepath.Join("projects", slug, "meta.yaml")),
+					Message: fmt.Sprintf("hero photo %s is not a photo of the project", project.HeroPhoto),
+				})
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+
+	if imagesDir == "" {
+		return nil
+	}
+	if _, err := os.Stat(imagesDir); err != nil {
+		return nil // Reported once by Run
+	}
+
+	variantWidths, err := processing.ProjectWidths(contentMgr, slug, processing.DefaultWidths)
+	if err != nil {
+		return err
+	}
+	for _, hashID := range shownPhotos(layout, project.HeroPhoto) {
+		widths := processing.DefaultWidths
+		if w, ok := variantWidths[hashID]; ok {
+			widths = w
+		}
+		var missing []string
+		for _, width := range widths {
+			name := fmt.Sprintf("%s-%dw.webp", hashID, width)
+			if _, err := os.Stat(filepath.Join(imagesDir, slug, hashID, name)); err != nil {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			report.add(&Issue{
+				Severity: SeverityError, Code: CodeMissingVariant, Project: slug,
+				Message: fmt.Sprintf("photo %s is missing processed variants: %s (run images process)", hashID, strings.Join(missing, ", ")),
+			})
+		}
+	}
+	return nil
+}
+
+// shownPhotos returns the hash IDs a project page shows: placed photos and the hero photo
+func shownPhotos(layout *content.LayoutConfig, heroPhoto string) []string {
+	seen := make(map[string]bool)
+	var hashIDs []string
+	add := func(hashID string) {
+		if hashID != "" && !seen[hashID] && layout.Block(hashID) == nil {
+			seen[hashID] = true
+			hashIDs = append(hashIDs, hashID)
+		}
+	}
+	for _, p := range layout.Placements {
+		add(p.Filename)
+	}
+	for _, p := range layout.MobilePlacements {
+		add(p.Filename)
+	}
+	add(heroPhoto)
+	return hashIDs
+}
+
+// faviconPattern matches favicon references in templates
+var faviconPattern = regexp.MustCompile(`/favicon/([A-Za-z0-9._-]+)`)
+
+// checkFavicons reports favicon files referenced by templates but missing from
+// the content favicon directory, which the generator copies them from. It
+// returns the URLs of the missing favicons.
+func checkFavicons(contentDir string, templates []fs.FS, report *Report) (map[string]bool, error) {
+	referenced := make(map[string][]string) // favicon -> templates referencing it
+	for _, fsys := range templates {
+		err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(p, ".html") {
+				return err
+			}
+			data, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return err
+			}
+			for _, match := range faviconPattern.FindAllStringSubmatch(string(data), -1) {
+				referenced[match[1]] = append(referenced[match[1]], path.Base(p))
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to scan templates: %w", err)
+		}
+	}
+
+	names := make([]string, 0, len(referenced))
+	for name := range referenced {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	missing := make(map[string]bool)
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(contentDir, "favicon", name)); err != nil {
+			missing["/favicon/"+name] = true
+			report.add(&Issue{
+				Severity: SeverityWarning, Code: CodeMissingFavicon,
+				File:    filepath.ToSlash(filepath.Join("favicon", name)),
+				Message: fmt.Sprintf("favicon %s is referenced by %s but missing from the content favicon directory", name, strings.Join(unique(referenced[name]), ", ")),
+			})
+		}
+	}
+	return missing, nil
+}
+
+// linkPattern matches link and asset URLs in generated pages
+var linkPattern = regexp.MustCompile(`(?:href|src|srcset)="([^"]*)"`)
+
+// checkLinks reports root-relative links and assets in the built site's pages
+// that do not resolve to a file in distDir. Links in skip are already reported.
+func checkLinks(distDir string, skip map[string]bool, report *Report) error {
+	return filepath.WalkDir(distDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(p) != ".html" {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		page, _ := filepath.Rel(distDir, p)
+		page = filepath.ToSlash(page)
+
+		seen := make(map[string]bool)
+		for _, match := range linkPattern.FindAllStringSubmatch(string(data), -1) {
+			for _, link := range linkURLs(match[0], match[1]) {
+				if seen[link] || skip[link] || !strings.HasPrefix(link, "/") || strings.HasPrefix(link, "//") {
+					continue
+				}
+				seen[link] = true
+				if !resolves(distDir, link) {
+					issue := &Issue{Severity: SeverityError, Code: CodeBrokenLink}
+					if strings.HasPrefix(link, "/favicon/") {
+						issue = &Issue{Severity: SeverityWarning, Code: CodeMissingFavicon}
+					}
+					issue.File = page
+					issue.Message = fmt.Sprintf("%s links to %s, which does not exist", page, link)
+					report.add(issue)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// linkURLs returns the URLs of an attribute; srcset lists several
+func linkURLs(attr, value string) []string {
+	if !strings.HasPrefix(attr, "srcset") {
+		return []string{value}
+	}
+	var urls []string
+	for _, candidate := range strings.Split(value, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			urls = append(urls, fields[0])
+		}
+	}
+	return urls
+}
+
+// resolves reports whether a root-relative URL is served by a static host from
+// distDir, including directory index pages
+func resolves(distDir, link string) bool {
+	if i := strings.IndexAny(link, "?#"); i >= 0 {
+		link = link[:i]
+	}
+	target := filepath.Join(distDir, filepath.FromSlash(path.Clean(link)))
+	info, err := os.Stat(target)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		_, err = os.Stat(filepath.Join(target, "index.html"))
+		return err == nil
+	}
+	return true
+}
+
+// unique returns values without duplicates, in order
+func unique(values []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
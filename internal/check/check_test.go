@@ -0,0 +1,87 @@
+package check
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRun checks that content and built site problems are reported with their codes
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	contentDir := filepath.Join(dir, "content")
+	distDir := filepath.Join(dir, "dist")
+
+	writeFile(t, filepath.Join(contentDir, "projects", "p", "meta.yaml"), "title: P\nslug: p\n")
+	writeFile(t, filepath.Join(contentDir, "projects", "p", "layout.yaml"),
+		"grid_width: 12\nplacements:\n  - filename: abc123abc123\n    position: {top_left_x: 1, top_left_y: 1, bottom_right_x: 6, bottom_right_y: 2}\n")
+	writeFile(t, filepath.Join(contentDir, "site.yaml"), "projects:\n  - slug: p\n    order: 1\n  - slug: gone\n    order: 2\n")
+	writeFile(t, filepath.Join(contentDir, "favicon", "favicon.svg"), "<svg/>")
+	writeFile(t, filepath.Join(distDir, "index.html"), `<a href="/p/">P</a> <a href="/missing/">M</a> <img srcset="/img/a.webp 480w, https://cdn.example.com/b.webp 800w">`)
+	writeFile(t, filepath.Join(distDir, "p", "index.html"), `<link rel="icon" href="/favicon/favicon.svg?v=1">`)
+	writeFile(t, filepath.Join(distDir, "favicon", "favicon.svg"), "<svg/>")
+
+	templates := fstest.MapFS{
+		"base.html": {Data: []byte(`<link href="{{.BaseURL}}/favicon/favicon.svg"><link href="{{.BaseURL}}/favicon/site.webmanifest">`)},
+	}
+	report, err := Run(Options{
+		ContentDir: contentDir,
+		PhotosDir:  filepath.Join(dir, "photos"),
+		ImagesDir:  filepath.Join(dir, "images"),
+		DistDir:    distDir,
+		Templates:  []fs.FS{templates},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	codes := make(map[string]int)
+	for _, issue := range report.Issues {
+		codes[issue.Code]++
+	}
+	want := map[string]int{
+		CodeUnknownOrderedProject: 1, // gone
+		CodeSkipped:               1, // No images directory
+		CodeMissingFavicon:        1, // site.webmanifest
+		CodeBrokenLink:            2, // /missing/ and /img/a.webp
+	}
+	for code, n := range want {
+		if codes[code] != n {
+			t.Errorf("Expected %d %s issue(s), got %d: %+v", n, code, codes[code], report.Issues)
+		}
+	}
+	if report.Errors != 3 || report.Warnings != 2 {
+		t.Errorf("Expected 3 errors and 2 warnings, got %d and %d", report.Errors, report.Warnings)
+	}
+}
+
+// TestRunUnreadableFile checks that a file read from its backup is reported and left as it is
+func TestRunUnreadableFile(t *testing.T) {
+	contentDir := filepath.Join(t.TempDir(), "content")
+	meta := filepath.Join(contentDir, "projects", "p", "meta.yaml")
+	writeFile(t, meta, "title: [unterminated")
+	writeFile(t, meta+".bak", "title: P\nslug: p\n")
+
+	report, err := Run(Options{ContentDir: contentDir})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(report.Issues) != 1 || report.Issues[0].Code != CodeUnreadableFile || report.Issues[0].File != meta {
+		t.Errorf("Expected one unreadable file issue for %s, got %+v", meta, report.Issues)
+	}
+	if data, _ := os.ReadFile(meta); string(data) != "title: [unterminated" {
+		t.Errorf("Expected the file to be left as it was, got %q", data)
+	}
+}
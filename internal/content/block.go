@@ -0,0 +1,102 @@
+package content
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Layout block types
+const (
+	BlockText   = "text"   // Markdown text
+	BlockVideo  = "video"  // Self-hosted video file, or YouTube or Vimeo embed
+	BlockSpacer = "spacer" // Empty cells
+)
+
+// videoFileExtensions are the extensions of video URLs played with a <video> element
+var videoFileExtensions = map[string]bool{".mp4": true, ".webm": true, ".mov": true, ".m4v": true}
+
+// youTubeID and vimeoID match the video IDs in YouTube and Vimeo URLs
+var (
+	youTubeID = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	vimeoID   = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// Block is a layout cell that is not a photo. Placements reference blocks by
+// their key in LayoutConfig.Blocks (e.g. "text-1a2b3c") instead of a photo hash ID.
+type Block struct {
+	Type  string `yaml:"type" json:"type"`
+	Text  string `yaml:"text,omitempty" json:"text,omitempty"`   // Markdown, for text blocks
+	Video string `yaml:"video,omitempty" json:"video,omitempty"` // Video file URL or YouTube/Vimeo page URL, for video blocks
+}
+
+// IsVideoFile reports whether a video block plays a video file rather than an embed
+func (b *Block) IsVideoFile() bool {
+	u, err := url.Parse(b.Video)
+	return err == nil && videoFileExtensions[strings.ToLower(path.Ext(u.Path))]
+}
+
+// EmbedURL returns the player URL of a YouTube or Vimeo video, or "" for other URLs
+func (b *Block) EmbedURL() string {
+	u, err := url.Parse(b.Video)
+	if err != nil {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	last := segments[len(segments)-1]
+
+	switch host {
+	case "youtube.com", "m.youtube.com", "youtube-nocookie.com":
+		id := u.Query().Get("v")
+		if len(segments) == 2 && (segments[0] == "embed" || segments[0] == "shorts" || segments[0] == "live") {
+			id = segments[1]
+		}
+		if youTubeID.MatchString(id) {
+			return "https://www.youtube-nocookie.com/embed/" + id
+		}
+	case "youtu.be":
+		if len(segments) == 1 && youTubeID.MatchString(last) {
+			return "https://www.youtube-nocookie.com/embed/" + last
+		}
+	case "vimeo.com", "player.vimeo.com":
+		if vimeoID.MatchString(last) {
+			return "https://player.vimeo.com/video/" + last
+		}
+	}
+	return ""
+}
+
+// Validate checks the block type and, for video blocks, the video URL
+func (b *Block) Validate() error {
+	switch b.Type {
+	case BlockText, BlockSpacer:
+		return nil
+	case BlockVideo:
+		u, err := url.Parse(b.Video)
+		if b.Video == "" || err != nil {
+			return fmt.Errorf("video block needs a video URL")
+		}
+		local := u.Scheme == "" && u.Host == "" && strings.HasPrefix(u.Path, "/")
+		if !local && u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid video URL %q: use an http(s) URL or a path starting with /", b.Video)
+		}
+		if !b.IsVideoFile() && b.EmbedURL() == "" {
+			return fmt.Errorf("invalid video URL %q: expected a YouTube or Vimeo video, or an .mp4, .webm or .mov file", b.Video)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown block type %q: expected %s, %s or %s", b.Type, BlockText, BlockVideo, BlockSpacer)
+	}
+}
+
+// Block returns the block a placement references, or nil for photos
+func (lc *LayoutConfig) Block(ref string) *Block {
+	block, ok := lc.Blocks[ref]
+	if !ok {
+		return nil
+	}
+	return &block
+}
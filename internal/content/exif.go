@@ -0,0 +1,302 @@
+package content
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EXIF orientation values (see the TIFF/EXIF specification, tag 0x0112)
+const (
+	OrientationNormal     = 1
+	OrientationFlipH      = 2
+	OrientationRotate180  = 3
+	OrientationFlipV      = 4
+	OrientationTranspose  = 5
+	OrientationRotate270  = 6
+	OrientationTransverse = 7
+	OrientationRotate90   = 8
+)
+
+// EXIF tags read from IFD0 and the EXIF sub-IFD
+const (
+	tagMake             = 0x010f
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagExifIFD          = 0x8769
+	tagExposureTime     = 0x829a
+	tagFNumber          = 0x829d
+	tagISO              = 0x8827
+	tagDateTimeOriginal = 0x9003
+	tagFocalLength      = 0x920a
+	tagLensModel        = 0xa434
+)
+
+// ReadOrientation returns the EXIF orientation of a JPEG stream. Streams without
+// an EXIF block or an orientation tag (including PNG and WebP) report OrientationNormal.
+func ReadOrientation(r io.Reader) int {
+	tiff := readTIFF(r)
+	if tiff == nil {
+		return OrientationNormal
+	}
+	entry, ok := tiff.ifd(tiff.firstIFD())[tagOrientation]
+	if !ok {
+		return OrientationNormal
+	}
+	value := int(tiff.order.Uint16(entry[8:10]))
+	if value < OrientationNormal || value > OrientationRotate90 {
+		return OrientationNormal
+	}
+	return value
+}
+
+// ExifData holds the camera settings of a photo, formatted for display. Fields
+// missing from the photo's EXIF block are empty.
+type ExifData struct {
+	Camera       string `json:"camera,omitempty"`       // Make and model, e.g. "FUJIFILM X-T4"
+	Lens         string `json:"lens,omitempty"`         // e.g. "XF23mmF2 R WR"
+	FocalLength  string `json:"focalLength,omitempty"`  // e.g. "23mm"
+	Aperture     string `json:"aperture,omitempty"`     // e.g. "f/2.8"
+	ExposureTime string `json:"exposureTime,omitempty"` // e.g. "1/250s"
+	ISO          string `json:"iso,omitempty"`          // e.g. "ISO 400"
+	TakenAt      string `json:"takenAt,omitempty"`      // e.g. "2024:05:12 18:30:00", as recorded by the camera
+}
+
+// Settings returns the exposure settings on one line, e.g. "23mm · f/2.8 · 1/250s · ISO 400"
+func (e *ExifData) Settings() string {
+	var parts []string
+	for _, part := range []string{e.FocalLength, e.Aperture, e.ExposureTime, e.ISO} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+// ReadExif returns the camera settings recorded in a JPEG stream, or nil when it
+// has no EXIF block or none of the settings
+func ReadExif(r io.Reader) *ExifData {
+	tiff := readTIFF(r)
+	if tiff == nil {
+		return nil
+	}
+
+	ifd0 := tiff.ifd(tiff.firstIFD())
+	data := &ExifData{}
+	maker, model := tiff.text(ifd0[tagMake]), tiff.text(ifd0[tagModel])
+	// Most models already start with the make (e.g. "Canon" and "Canon EOS R5")
+	if maker != "" && !strings.HasPrefix(strings.ToLower(model), strings.ToLower(maker)) {
+		model = strings.TrimSpace(maker + " " + model)
+	}
+	data.Camera = model
+
+	if entry, ok := ifd0[tagExifIFD]; ok {
+		exif := tiff.ifd(int(tiff.order.Uint32(entry[8:12])))
+		data.Lens = tiff.text(exif[tagLensModel])
+		data.TakenAt = tiff.text(exif[tagDateTimeOriginal])
+		if v, ok := tiff.rational(exif[tagFocalLength]); ok && v > 0 {
+			data.FocalLength = formatDecimal(v) + "mm"
+		}
+		if v, ok := tiff.rational(exif[tagFNumber]); ok && v > 0 {
+			data.Aperture = "f/" + formatDecimal(v)
+		}
+		if v, ok := tiff.rational(exif[tagExposureTime]); ok && v > 0 {
+			if v >= 1 {
+				data.ExposureTime = formatDecimal(v) + "s"
+			} else {
+				data.ExposureTime = fmt.Sprintf("1/%.0fs", 1/v)
+			}
+		}
+		if entry, ok := exif[tagISO]; ok && tiff.order.Uint16(entry[2:4]) == tiffShort {
+			data.ISO = fmt.Sprintf("ISO %d", tiff.order.Uint16(entry[8:10]))
+		}
+	}
+
+	if *data == (ExifData{}) {
+		return nil
+	}
+	return data
+}
+
+// formatDecimal formats a value with at most one decimal, e.g. "2.8" or "35"
+func formatDecimal(v float64) string {
+	return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64)
+}
+
+// TIFF field types used by the tags read here
+const (
+	tiffASCII    = 2
+	tiffShort    = 3
+	tiffRational = 5
+)
+
+// tiffData is the TIFF structure of an EXIF block, whose offsets are relative to its start
+type tiffData struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// readTIFF returns the TIFF structure of a JPEG stream's EXIF APP1 block, or nil
+// when the stream is not a JPEG or has no EXIF block
+func readTIFF(r io.Reader) *tiffData {
+	const (
+		markerSOI   = 0xffd8
+		markerAPP1  = 0xffe1
+		markerSOS   = 0xffda
+		exifHeader  = 0x45786966 // "Exif"
+		byteOrderBE = 0x4d4d
+		byteOrderLE = 0x4949
+	)
+
+	br := bufio.NewReader(r)
+
+	var soi uint16
+	if err := binary.Read(br, binary.BigEndian, &soi); err != nil || soi != markerSOI {
+		return nil
+	}
+
+	// Walk the JPEG segments until the EXIF APP1 block or the start of scan
+	for {
+		var marker, size uint16
+		if err := binary.Read(br, binary.BigEndian, &marker); err != nil {
+			return nil
+		}
+		if marker>>8 != 0xff || marker == markerSOS {
+			return nil
+		}
+		if err := binary.Read(br, binary.BigEndian, &size); err != nil || size < 2 {
+			return nil
+		}
+		if marker != markerAPP1 {
+			if _, err := br.Discard(int(size) - 2); err != nil {
+				return nil
+			}
+			continue
+		}
+
+		block := make([]byte, int(size)-2)
+		if _, err := io.ReadFull(br, block); err != nil {
+			return nil
+		}
+		// "Exif\0\0" followed by the TIFF header
+		if len(block) < 14 || binary.BigEndian.Uint32(block[:4]) != exifHeader {
+			continue
+		}
+		tiff := block[6:]
+
+		switch binary.BigEndian.Uint16(tiff[:2]) {
+		case byteOrderBE:
+			return &tiffData{data: tiff, order: binary.BigEndian}
+		case byteOrderLE:
+			return &tiffData{data: tiff, order: binary.LittleEndian}
+		default:
+			return nil
+		}
+	}
+}
+
+// firstIFD returns the offset of IFD0
+func (t *tiffData) firstIFD() int {
+	return int(t.order.Uint32(t.data[4:8]))
+}
+
+// ifd returns the 12-byte entries of the IFD at offset, by tag. Entries past the
+// end of the block are left out.
+func (t *tiffData) ifd(offset int) map[uint16][]byte {
+	entries := make(map[uint16][]byte)
+	if offset < 0 || offset+2 > len(t.data) {
+		return entries
+	}
+	count := int(t.order.Uint16(t.data[offset : offset+2]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(t.data) {
+			break
+		}
+		entries[t.order.Uint16(t.data[entry:entry+2])] = t.data[entry : entry+12]
+	}
+	return entries
+}
+
+// value returns the bytes of an entry's value, stored in the entry itself when
+// it fits in 4 bytes and at an offset in the block otherwise
+func (t *tiffData) value(entry []byte, size int) []byte {
+	if entry == nil {
+		return nil
+	}
+	size *= int(t.order.Uint32(entry[4:8]))
+	if size <= 4 {
+		return entry[8 : 8+size]
+	}
+	offset := int(t.order.Uint32(entry[8:12]))
+	if size > len(t.data) || offset < 0 || offset > len(t.data)-size {
+		return nil
+	}
+	return t.data[offset : offset+size]
+}
+
+// text returns the trimmed value of an ASCII entry
+func (t *tiffData) text(entry []byte) string {
+	if entry == nil || t.order.Uint16(entry[2:4]) != tiffASCII {
+		return ""
+	}
+	value := t.value(entry, 1)
+	return strings.TrimSpace(strings.TrimRight(string(value), "\x00"))
+}
+
+// rational returns the first value of a RATIONAL entry
+func (t *tiffData) rational(entry []byte) (float64, bool) {
+	if entry == nil || t.order.Uint16(entry[2:4]) != tiffRational {
+		return 0, false
+	}
+	value := t.value(entry, 8)
+	if len(value) < 8 {
+		return 0, false
+	}
+	num, den := t.order.Uint32(value[:4]), t.order.Uint32(value[4:8])
+	if den == 0 {
+		return 0, false
+	}
+	return float64(num) / float64(den), true
+}
+
+// SwapsDimensions reports whether an orientation turns the image by 90 degrees,
+// i.e. the displayed width is the stored height and vice versa.
+func SwapsDimensions(orientation int) bool {
+	return orientation >= OrientationTranspose && orientation <= OrientationRotate90
+}
+
+// ImageDimensions returns the width and height of an image as it should be
+// displayed, taking the EXIF orientation into account
+func ImageDimensions(path string) (width, height int, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	orientation := ReadOrientation(file)
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0, err
+	}
+	if cfg.Width == 0 || cfg.Height == 0 {
+		return 0, 0, fmt.Errorf("invalid image dimensions")
+	}
+
+	if SwapsDimensions(orientation) {
+		return cfg.Height, cfg.Width, nil
+	}
+	return cfg.Width, cfg.Height, nil
+}
@@ -0,0 +1,187 @@
+package content
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeJPEGWithOrientation encodes a width x height JPEG and injects an EXIF
+// APP1 block carrying the given orientation right after the SOI marker
+func writeJPEGWithOrientation(t *testing.T, path string, width, height, orientation int) {
+	t.Helper()
+
+	// TIFF header (little endian) with a single IFD0 entry: Orientation, SHORT, count 1
+	var tiff bytes.Buffer
+	tiff.WriteString("II")
+	binary.Write(&tiff, binary.LittleEndian, uint16(42))
+	binary.Write(&tiff, binary.LittleEndian, uint32(8))
+	binary.Write(&tiff, binary.LittleEndian, uint16(1))
+	binary.Write(&tiff, binary.LittleEndian, uint16(0x0112))
+	binary.Write(&tiff, binary.LittleEndian, uint16(3))
+	binary.Write(&tiff, binary.LittleEndian, uint32(1))
+	binary.Write(&tiff, binary.LittleEndian, uint16(orientation))
+	binary.Write(&tiff, binary.LittleEndian, uint16(0))
+	binary.Write(&tiff, binary.LittleEndian, uint32(0))
+
+	writeJPEGWithTIFF(t, path, width, height, tiff.Bytes())
+}
+
+// writeJPEGWithTIFF encodes a width x height JPEG and injects an EXIF APP1 block
+// with the given TIFF structure right after the SOI marker
+func writeJPEGWithTIFF(t *testing.T, path string, width, height int, tiff []byte) {
+	t.Helper()
+
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatalf("Failed to encode JPEG: %v", err)
+	}
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+
+	var out bytes.Buffer
+	out.Write(encoded.Bytes()[:2]) // SOI
+	out.Write([]byte{0xff, 0xe1})
+	binary.Write(&out, binary.BigEndian, uint16(len(payload)+2))
+	out.Write(payload)
+	out.Write(encoded.Bytes()[2:])
+
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write JPEG: %v", err)
+	}
+}
+
+// exifEntry is an IFD entry for exifTIFF; values over 4 bytes are stored after the IFDs
+type exifEntry struct {
+	tag, fieldType uint16
+	count          uint32
+	value          []byte
+}
+
+// exifTIFF builds a big endian TIFF structure with an IFD0 pointing to an EXIF sub-IFD
+func exifTIFF(ifd0, exif []exifEntry) []byte {
+	ifd0 = append(ifd0, exifEntry{0x8769, 4, 1, nil}) // Patched below
+	ifdSize := func(entries []exifEntry) int { return 2 + 12*len(entries) + 4 }
+	exifOffset := 8 + ifdSize(ifd0)
+	dataOffset := exifOffset + ifdSize(exif)
+
+	var tiff, data bytes.Buffer
+	tiff.WriteString("MM")
+	binary.Write(&tiff, binary.BigEndian, uint16(42))
+	binary.Write(&tiff, binary.BigEndian, uint32(8))
+	for _, entries := range [][]exifEntry{ifd0, exif} {
+		binary.Write(&tiff, binary.BigEndian, uint16(len(entries)))
+		for _, e := range entries {
+			if e.tag == 0x8769 {
+				e.value = binary.BigEndian.AppendUint32(nil, uint32(exifOffset))
+			}
+			binary.Write(&tiff, binary.BigEndian, e.tag)
+			binary.Write(&tiff, binary.BigEndian, e.fieldType)
+			binary.Write(&tiff, binary.BigEndian, e.count)
+			if len(e.value) <= 4 {
+				tiff.Write(append(e.value, make([]byte, 4-len(e.value))...))
+				continue
+			}
+			binary.Write(&tiff, binary.BigEndian, uint32(dataOffset+data.Len()))
+			data.Write(e.value)
+		}
+		binary.Write(&tiff, binary.BigEndian, uint32(0))
+	}
+	return append(tiff.Bytes(), data.Bytes()...)
+}
+
+// asciiEntry and rationalEntry encode ASCII and RATIONAL entries
+func asciiEntry(tag uint16, s string) exifEntry {
+	return exifEntry{tag, 2, uint32(len(s) + 1), append([]byte(s), 0)}
+}
+
+func rationalEntry(tag uint16, num, den uint32) exifEntry {
+	return exifEntry{tag, 5, 1, binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, num), den)}
+}
+
+// TestReadExif checks that camera settings are read from IFD0 and the EXIF sub-IFD
+func TestReadExif(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	writeJPEGWithTIFF(t, path, 8, 8, exifTIFF(
+		[]exifEntry{asciiEntry(0x010f, "Canon"), asciiEntry(0x0110, "Canon EOS R5")},
+		[]exifEntry{
+			rationalEntry(0x829a, 1, 250),
+			rationalEntry(0x829d, 28, 10),
+			{0x8827, 3, 1, []byte{0x01, 0x90}}, // ISO 400
+			asciiEntry(0x9003, "2024:05:12 18:30:00"),
+			rationalEntry(0x920a, 35, 1),
+			asciiEntry(0xa434, "RF35mm F1.8 MACRO IS STM"),
+		},
+	))
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	want := ExifData{
+		Camera: "Canon EOS R5", Lens: "RF35mm F1.8 MACRO IS STM", FocalLength: "35mm", Aperture: "f/2.8",
+		ExposureTime: "1/250s", ISO: "ISO 400", TakenAt: "2024:05:12 18:30:00",
+	}
+	got := ReadExif(file)
+	if got == nil || *got != want {
+		t.Fatalf("ReadExif = %+v, want %+v", got, want)
+	}
+	if settings := got.Settings(); settings != "35mm · f/2.8 · 1/250s · ISO 400" {
+		t.Errorf("Settings() = %q", settings)
+	}
+
+	// The orientation block carries no camera settings
+	writeJPEGWithOrientation(t, path, 8, 8, OrientationRotate90)
+	data, _ := os.ReadFile(path)
+	if got := ReadExif(bytes.NewReader(data)); got != nil {
+		t.Errorf("Expected no EXIF data, got %+v", got)
+	}
+}
+
+// TestImageDimensionsOrientation checks that rotated photos report their displayed size
+func TestImageDimensionsOrientation(t *testing.T) {
+	dir := t.TempDir()
+
+	testCases := []struct {
+		orientation    int
+		expectedWidth  int
+		expectedHeight int
+	}{
+		{OrientationNormal, 60, 40},
+		{OrientationRotate180, 60, 40},
+		{OrientationRotate270, 40, 60},
+		{OrientationRotate90, 40, 60},
+		{OrientationTranspose, 40, 60},
+	}
+
+	for _, tc := range testCases {
+		path := filepath.Join(dir, "photo.jpg")
+		writeJPEGWithOrientation(t, path, 60, 40, tc.orientation)
+
+		width, height, err := ImageDimensions(path)
+		if err != nil {
+			t.Fatalf("orientation %d: failed to read dimensions: %v", tc.orientation, err)
+		}
+		if width != tc.expectedWidth || height != tc.expectedHeight {
+			t.Errorf("orientation %d: got %dx%d, want %dx%d", tc.orientation, width, height, tc.expectedWidth, tc.expectedHeight)
+		}
+	}
+}
+
+// TestReadOrientationWithoutExif checks that plain JPEGs default to normal orientation
+func TestReadOrientationWithoutExif(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatalf("Failed to encode JPEG: %v", err)
+	}
+
+	if got := ReadOrientation(&encoded); got != OrientationNormal {
+		t.Errorf("got orientation %d, want %d", got, OrientationNormal)
+	}
+}
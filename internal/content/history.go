@@ -0,0 +1,224 @@
+package content
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/util"
+)
+
+// LayoutHistoryLimit is the number of layout revisions kept per project
+const LayoutHistoryLimit = 50
+
+// revisionTimeFormat names revision files so that they sort chronologically
+const revisionTimeFormat = "20060102-150405.000000"
+
+// revisionIDPattern matches revision IDs (the timestamp part of the file name)
+var revisionIDPattern = regexp.MustCompile(`^\d{8}-\d{6}\.\d{6}$`)
+
+// ErrRevisionNotFound is returned when a layout revision does not exist
+var ErrRevisionNotFound = errors.New("layout revision not found")
+
+// LayoutRevision describes a saved version of a project layout
+type LayoutRevision struct {
+	ID               string    `json:"id"`
+	CreatedAt        time.Time `json:"createdAt"`
+	Placements       int       `json:"placements"`
+	MobilePlacements int       `json:"mobilePlacements"`
+}
+
+// LayoutChange describes how one photo's placement differs between two layouts
+type LayoutChange struct {
+	HashID string        `json:"hashId"`
+	View   string        `json:"view"`   // "desktop" or "mobile"
+	Change string        `json:"change"` // "added", "removed" or "moved"
+	From   *GridPosition `json:"from,omitempty"`
+	To     *GridPosition `json:"to,omitempty"`
+}
+
+// ProjectHistoryDir returns the directory holding a project's layout revisions
+func (m *Manager) ProjectHistoryDir(slug string) string {
+	return filepath.Join(m.ProjectDir(slug), ".history")
+}
+
+// saveLayoutRevision stores data as a new revision unless it matches the latest
+// one, then prunes the history to LayoutHistoryLimit revisions
+func (m *Manager) saveLayoutRevision(slug string, data []byte, at time.Time) error {
+	ids, err := m.layoutRevisionIDs(slug)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		latest, err := os.ReadFile(m.revisionPath(slug, ids[0]))
+		if err == nil && bytes.Equal(latest, data) {
+			return nil
+		}
+	}
+
+	dir := m.ProjectHistoryDir(slug)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	id := at.UTC().Format(revisionTimeFormat)
+	if len(ids) > 0 && id <= ids[0] {
+		// Keep IDs increasing even if the clock did not move (or went back)
+		latest, _ := time.Parse(revisionTimeFormat, ids[0])
+		id = latest.Add(time.Microsecond).Format(revisionTimeFormat)
+	}
+	if err := util.WriteFileAtomic(m.revisionPath(slug, id), data, 0644); err != nil {
+		return fmt.Errorf("failed to save layout revision: %w", err)
+	}
+
+	ids = append([]string{id}, ids...)
+	for len(ids) > LayoutHistoryLimit {
+		os.Remove(m.revisionPath(slug, ids[len(ids)-1]))
+		ids = ids[:len(ids)-1]
+	}
+	return nil
+}
+
+// ListLayoutRevisions returns a project's layout revisions, newest first
+func (m *Manager) ListLayoutRevisions(slug string) ([]*LayoutRevision, error) {
+	ids, err := m.layoutRevisionIDs(slug)
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]*LayoutRevision, 0, len(ids))
+	for _, id := range ids {
+		createdAt, _ := time.Parse(revisionTimeFormat, id) // Validated by layoutRevisionIDs
+		revision := &LayoutRevision{ID: id, CreatedAt: createdAt}
+		if layout, err := m.GetLayoutRevision(slug, id); err == nil {
+			revision.Placements = len(layout.Placements)
+			revision.MobilePlacements = len(layout.MobilePlacements)
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+// layoutRevisionIDs returns the IDs of a project's layout revisions, newest first
+func (m *Manager) layoutRevisionIDs(slug string) ([]string, error) {
+	entries, err := os.ReadDir(m.ProjectHistoryDir(slug))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read layout history: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "layout-") || !strings.HasSuffix(name, ".yaml") {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(name, "layout-"), ".yaml")
+		if revisionIDPattern.MatchString(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids, nil
+}
+
+// GetLayoutRevision loads a layout revision
+func (m *Manager) GetLayoutRevision(slug, id string) (*LayoutConfig, error) {
+	if !revisionIDPattern.MatchString(id) {
+		return nil, ErrRevisionNotFound
+	}
+	var layout LayoutConfig
+	if err := util.LoadYAML(m.revisionPath(slug, id), &layout); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, fmt.Errorf("failed to load layout revision: %w", err)
+	}
+	return &layout, nil
+}
+
+// RestoreLayoutRevision makes a revision the current layout and returns it with
+// its new version. The restore is itself recorded as a new revision, so it can
+// be undone.
+func (m *Manager) RestoreLayoutRevision(slug, id string) (*LayoutConfig, string, error) {
+	layout, err := m.GetLayoutRevision(slug, id)
+	if err != nil {
+		return nil, "", err
+	}
+	version, err := m.UpdateLayoutIfVersion(slug, layout, "")
+	if err != nil {
+		return nil, "", err
+	}
+	return layout, version, nil
+}
+
+// revisionPath returns the file of a layout revision
+func (m *Manager) revisionPath(slug, id string) string {
+	return filepath.Join(m.ProjectHistoryDir(slug), "layout-"+id+".yaml")
+}
+
+// snapshotExistingLayout records the current layout file as the first revision
+// of a project without history, so layouts saved before history existed can be
+// restored
+func (m *Manager) snapshotExistingLayout(slug string) error {
+	ids, err := m.layoutRevisionIDs(slug)
+	if err != nil || len(ids) > 0 {
+		return err
+	}
+	path := m.ProjectLayoutPath(slug)
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return m.saveLayoutRevision(slug, data, info.ModTime())
+}
+
+// DiffLayouts lists the photos added, removed or moved between two layouts, for
+// the desktop and mobile grids
+func DiffLayouts(from, to *LayoutConfig) []LayoutChange {
+	changes := diffPlacements("desktop", from.Placements, to.Placements)
+	return append(changes, diffPlacements("mobile", from.MobilePlacements, to.MobilePlacements)...)
+}
+
+// diffPlacements compares placements by hash ID, in the order of the target layout
+func diffPlacements(view string, from, to []PhotoPlacement) []LayoutChange {
+	before := make(map[string]GridPosition, len(from))
+	for _, p := range from {
+		before[p.Filename] = p.Position
+	}
+
+	changes := []LayoutChange{}
+	seen := make(map[string]bool, len(to))
+	for _, p := range to {
+		seen[p.Filename] = true
+		pos := p.Position
+		old, ok := before[p.Filename]
+		switch {
+		case !ok:
+			changes = append(changes, LayoutChange{HashID: p.Filename, View: view, Change: "added", To: &pos})
+		case old != pos:
+			changes = append(changes, LayoutChange{HashID: p.Filename, View: view, Change: "moved", From: &old, To: &pos})
+		}
+	}
+	for _, p := range from {
+		if !seen[p.Filename] {
+			pos := p.Position
+			changes = append(changes, LayoutChange{HashID: p.Filename, View: view, Change: "removed", From: &pos})
+			seen[p.Filename] = true
+		}
+	}
+	return changes
+}
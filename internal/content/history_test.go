@@ -0,0 +1,87 @@
+package content
+
+import (
+	"os"
+	"testing"
+)
+
+func layoutWith(positions ...int) *LayoutConfig {
+	layout := &LayoutConfig{GridWidth: 12}
+	for i, x := range positions {
+		layout.Placements = append(layout.Placements, PhotoPlacement{
+			Filename: string(rune('a' + i)),
+			Position: GridPosition{TopLeftX: x, TopLeftY: 1, BottomRightX: x, BottomRightY: 1},
+		})
+	}
+	return layout
+}
+
+// TestLayoutHistory checks that saves are recorded once each and can be restored
+func TestLayoutHistory(t *testing.T) {
+	m := NewManager(t.TempDir())
+	project, err := m.CreateProject("History", "")
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+
+	// The layout created with the project becomes the first revision on the first save
+	for _, layout := range []*LayoutConfig{layoutWith(1), layoutWith(1), layoutWith(2, 5)} {
+		if err := m.UpdateLayout(project.Slug, layout); err != nil {
+			t.Fatalf("UpdateLayout failed: %v", err)
+		}
+	}
+
+	revisions, err := m.ListLayoutRevisions(project.Slug)
+	if err != nil {
+		t.Fatalf("ListLayoutRevisions failed: %v", err)
+	}
+	if len(revisions) != 3 {
+		t.Fatalf("Expected 3 revisions (initial, first and last save), got %d", len(revisions))
+	}
+	if revisions[0].Placements != 2 || revisions[1].Placements != 1 || revisions[2].Placements != 0 {
+		t.Errorf("Unexpected revision order: %+v %+v %+v", revisions[0], revisions[1], revisions[2])
+	}
+
+	changes := DiffLayouts(layoutWith(1), layoutWith(2, 5))
+	if len(changes) != 2 || changes[0].Change != "moved" || changes[1].Change != "added" {
+		t.Errorf("Unexpected diff: %+v", changes)
+	}
+
+	restored, _, err := m.RestoreLayoutRevision(project.Slug, revisions[1].ID)
+	if err != nil {
+		t.Fatalf("RestoreLayoutRevision failed: %v", err)
+	}
+	current, _ := m.GetLayout(project.Slug)
+	if len(restored.Placements) != 1 || len(current.Placements) != 1 {
+		t.Errorf("Restore did not update the layout: %+v", current)
+	}
+	if revisions, _ := m.ListLayoutRevisions(project.Slug); len(revisions) != 4 {
+		t.Errorf("Expected the restore to add a revision, got %d revisions", len(revisions))
+	}
+
+	if _, err := m.GetLayoutRevision(project.Slug, "../layout"); err != ErrRevisionNotFound {
+		t.Errorf("Expected ErrRevisionNotFound for an invalid ID, got %v", err)
+	}
+}
+
+// TestLayoutHistoryLimit checks that old revisions are pruned
+func TestLayoutHistoryLimit(t *testing.T) {
+	m := NewManager(t.TempDir())
+	if err := os.MkdirAll(m.ProjectDir("p"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < LayoutHistoryLimit+5; i++ {
+		if err := m.UpdateLayout("p", layoutWith(i%12+1, i/12+1)); err != nil {
+			t.Fatalf("UpdateLayout failed: %v", err)
+		}
+	}
+
+	revisions, err := m.ListLayoutRevisions("p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != LayoutHistoryLimit {
+		t.Errorf("Expected %d revisions, got %d", LayoutHistoryLimit, len(revisions))
+	}
+}
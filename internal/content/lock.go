@@ -0,0 +1,111 @@
+package content
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ErrVersionConflict is returned when a file was changed since the version the
+// caller based its update on
+var ErrVersionConflict = errors.New("content was changed by someone else")
+
+// fileLocks serializes access to content files. Locks are per path and shared
+// by every Manager in the process, so the builder and the generator it runs do
+// not read a file while it is being rewritten, and read-modify-write cycles do
+// not overwrite each other's changes.
+type fileLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+var contentLocks = &fileLocks{locks: make(map[string]*sync.Mutex)}
+
+// lockKey returns the key path is locked under
+func lockKey(path string) string {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		key = filepath.Clean(path)
+	}
+	return key
+}
+
+// lock locks path and returns the function that unlocks it
+func (l *fileLocks) lock(path string) func() {
+	key := lockKey(path)
+
+	l.mu.Lock()
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		l.locks[key] = lock
+	}
+	l.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// lockFile locks a content file for the duration of a read or read-modify-write cycle
+func lockFile(path string) func() {
+	return contentLocks.lock(path)
+}
+
+// lockFiles locks several content files and returns the function that unlocks
+// them. They are locked in a fixed order, so that callers locking overlapping
+// files cannot deadlock.
+func lockFiles(paths ...string) func() {
+	keys := make([]string, 0, len(paths))
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if key := lockKey(path); !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	unlocks := make([]func(), len(keys))
+	for i, key := range keys {
+		unlocks[i] = contentLocks.lock(key)
+	}
+	return func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+}
+
+// fileVersion returns the version of a file's contents, used as an ETag. A
+// missing file has the empty version.
+func fileVersion(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// checkVersion returns ErrVersionConflict when ifVersion is set and differs
+// from the current version of path. The caller must hold the file's lock.
+func checkVersion(path, ifVersion string) error {
+	if ifVersion == "" {
+		return nil
+	}
+	current, err := fileVersion(path)
+	if err != nil {
+		return err
+	}
+	if current != ifVersion {
+		return ErrVersionConflict
+	}
+	return nil
+}
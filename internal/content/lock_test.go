@@ -0,0 +1,74 @@
+package content
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// TestUpdateLayoutIfVersion checks that updates based on a stale version are rejected
+func TestUpdateLayoutIfVersion(t *testing.T) {
+	m := NewManager(t.TempDir())
+	project, err := m.CreateProject("Versions", "")
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+
+	_, loaded, err := m.GetLayoutVersion(project.Slug)
+	if err != nil {
+		t.Fatalf("GetLayoutVersion failed: %v", err)
+	}
+
+	// First editor saves over the version both loaded
+	saved, err := m.UpdateLayoutIfVersion(project.Slug, layoutWith(1), loaded)
+	if err != nil {
+		t.Fatalf("UpdateLayoutIfVersion failed: %v", err)
+	}
+	if saved == loaded {
+		t.Errorf("Expected a new version after saving, got %s again", saved)
+	}
+
+	// Second editor still has the old version
+	if _, err := m.UpdateLayoutIfVersion(project.Slug, layoutWith(2), loaded); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict, got %v", err)
+	}
+	layout, current, err := m.GetLayoutVersion(project.Slug)
+	if err != nil {
+		t.Fatalf("GetLayoutVersion failed: %v", err)
+	}
+	if current != saved || layout.Placements[0].Position.TopLeftX != 1 {
+		t.Errorf("Expected the first editor's layout at version %s, got %+v at %s", saved, layout.Placements, current)
+	}
+}
+
+// TestUpdateSiteMetaConcurrent checks that concurrent read-modify-write cycles do not lose updates
+func TestUpdateSiteMetaConcurrent(t *testing.T) {
+	m := NewManager(t.TempDir())
+	if err := m.SaveSiteMeta(&SiteMetadata{}); err != nil {
+		t.Fatalf("SaveSiteMeta failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := m.UpdateSiteMeta(func(meta *SiteMetadata) error {
+				meta.Projects = append(meta.Projects, ProjectOrder{Slug: string(rune('a' + i)), Order: i})
+				return nil
+			})
+			if err != nil {
+				t.Errorf("UpdateSiteMeta failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	meta, err := m.LoadSiteMeta()
+	if err != nil {
+		t.Fatalf("LoadSiteMeta failed: %v", err)
+	}
+	if len(meta.Projects) != 20 {
+		t.Errorf("Expected 20 project orders, got %d", len(meta.Projects))
+	}
+}
@@ -12,10 +12,11 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/disintegration/imaging"
 	"golang.org/x/image/draw"
 )
 
-// PhotoInfo holds information about a photo
+// PhotoInfo holds information about a photo or video clip
 type PhotoInfo struct {
 	Filename    string  `json:"filename"` // Original filename
 	HashID      string  `json:"hashId"`   // 12-char hash ID used in layout.yaml and processed images
@@ -25,9 +26,12 @@ type PhotoInfo struct {
 	RatioWidth  int     `json:"ratioWidth"`  // integer width of aspect ratio (e.g., 3 for 3:2)
 	RatioHeight int     `json:"ratioHeight"` // integer height of aspect ratio (e.g., 2 for 3:2)
 	ThumbPath   string  `json:"thumbPath"`   // path to thumbnail for builder UI
+
+	// Video is set for video clips, whose poster frame stands in for the photo
+	Video bool `json:"video,omitempty"`
 }
 
-// ListPhotos returns all photos for a project
+// ListPhotos returns all photos and video clips for a project
 func (m *Manager) ListPhotos(slug string) ([]*PhotoInfo, error) {
 	photosDir := m.ProjectPhotosDir(slug)
 
@@ -55,41 +59,13 @@ func (m *Manager) ListPhotos(slug string) ([]*PhotoInfo, error) {
 		}
 
 		ext := strings.ToLower(filepath.Ext(entry.Name()))
-		if ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".webp" {
+		if ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".webp" && !IsVideo(ext) {
 			continue
 		}
 
-		photoPath := filepath.Join(photosDir, entry.Name())
-
-		// Compute hash ID from photo content
-		hashID, err := computePhotoHash(photoPath)
+		photo, err := newPhotoInfo(slug, filepath.Join(photosDir, entry.Name()), info.Size())
 		if err != nil {
-			continue // Skip if we can't compute hash
-		}
-
-		// Compute aspect ratio
-		aspectRatio, err := getImageAspectRatio(photoPath)
-		if err != nil {
-			continue // Skip invalid images
-		}
-
-		// Convert to integer ratio
-		ratioW, ratioH := getIntegerRatio(aspectRatio)
-
-		// Build thumbnail URL - thumbnails are in dist/images/{project}/.thumbs/thumb-{hashID}.webp
-		// The builder server serves /images/ from dist/images/
-		thumbFilename := fmt.Sprintf("thumb-%s.webp", hashID)
-		thumbURL := fmt.Sprintf("/images/%s/.thumbs/%s", slug, thumbFilename)
-
-		photo := &PhotoInfo{
-			Filename:    entry.Name(),
-			HashID:      hashID,
-			Path:        photoPath,
-			Size:        info.Size(),
-			AspectRatio: aspectRatio,
-			RatioWidth:  ratioW,
-			RatioHeight: ratioH,
-			ThumbPath:   thumbURL,
+			continue // Skip unreadable or invalid images
 		}
 		photos = append(photos, photo)
 	}
@@ -97,24 +73,65 @@ func (m *Manager) ListPhotos(slug string) ([]*PhotoInfo, error) {
 	return photos, nil
 }
 
-// getImageAspectRatio returns the aspect ratio (width/height) of an image
-func getImageAspectRatio(path string) (float64, error) {
-	file, err := os.Open(path)
+// newPhotoInfo builds the PhotoInfo of a photo file in a project's photos directory
+func newPhotoInfo(slug, photoPath string, size int64) (*PhotoInfo, error) {
+	// Compute hash ID from photo content
+	hashID, err := computePhotoHash(photoPath)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	defer file.Close()
 
-	img, _, err := image.DecodeConfig(file)
+	// Compute aspect ratio
+	video := IsVideo(photoPath)
+	var aspectRatio float64
+	if video {
+		aspectRatio, err = getVideoAspectRatio(photoPath)
+	} else {
+		aspectRatio, err = getImageAspectRatio(photoPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to integer ratio
+	ratioW, ratioH := getIntegerRatio(aspectRatio)
+
+	// Build thumbnail URL - thumbnails are in dist/images/{project}/.thumbs/thumb-{hashID}.webp
+	// The builder server serves /images/ from dist/images/
+	thumbFilename := fmt.Sprintf("thumb-%s.webp", hashID)
+	thumbURL := fmt.Sprintf("/images/%s/.thumbs/%s", slug, thumbFilename)
+
+	return &PhotoInfo{
+		Filename:    filepath.Base(photoPath),
+		HashID:      hashID,
+		Path:        photoPath,
+		Size:        size,
+		AspectRatio: aspectRatio,
+		RatioWidth:  ratioW,
+		RatioHeight: ratioH,
+		ThumbPath:   thumbURL,
+		Video:       video,
+	}, nil
+}
+
+// getImageAspectRatio returns the aspect ratio (width/height) of an image as displayed
+func getImageAspectRatio(path string) (float64, error) {
+	width, height, err := ImageDimensions(path)
 	if err != nil {
 		return 0, err
 	}
 
-	if img.Height == 0 {
-		return 0, fmt.Errorf("invalid image dimensions")
+	return float64(width) / float64(height), nil
+}
+
+// getVideoAspectRatio returns the aspect ratio (width/height) of a video as displayed
+func getVideoAspectRatio(path string) (float64, error) {
+	width, height, err := VideoDimensions(path)
+	if err != nil {
+		return 0, err
 	}
 
-	return float64(img.Width) / float64(img.Height), nil
+	return float64(width) / float64(height), nil
 }
 
 // getIntegerRatio converts a decimal aspect ratio to the nearest common integer ratio
@@ -179,14 +196,8 @@ func computePhotoHash(photoPath string) (string, error) {
 
 // generateThumbnail creates a compressed thumbnail for the builder UI
 func generateThumbnail(sourcePath, thumbPath string, maxWidth int) error {
-	// Open source image
-	file, err := os.Open(sourcePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	img, _, err := image.Decode(file)
+	// Open source image, applying the EXIF orientation so portrait shots stay upright
+	img, err := imaging.Open(sourcePath, imaging.AutoOrientation(true))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,265 @@
+package content
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/util"
+)
+
+var (
+	// ErrPhotoNotFound is returned when no photo of a project has the given hash ID
+	ErrPhotoNotFound = errors.New("photo not found")
+	// ErrDuplicatePhoto is returned when the photo content already exists in the project
+	ErrDuplicatePhoto = errors.New("photo already exists in the project")
+)
+
+// GetPhoto returns the photo of a project with the given hash ID
+func (m *Manager) GetPhoto(slug, hashID string) (*PhotoInfo, error) {
+	photos, err := m.ListPhotos(slug)
+	if err != nil {
+		return nil, err
+	}
+	for _, photo := range photos {
+		if photo.HashID == hashID {
+			return photo, nil
+		}
+	}
+	return nil, ErrPhotoNotFound
+}
+
+// DeletePhoto removes a photo's placements, settings and hero reference, then
+// its file. References go first, so a failure never leaves them pointing at a
+// missing photo.
+func (m *Manager) DeletePhoto(slug, hashID string) error {
+	photo, err := m.GetPhoto(slug, hashID)
+	if err != nil {
+		return err
+	}
+
+	if err := m.editLayout(slug, func(layout *LayoutConfig) bool {
+		return layout.RemovePhoto(hashID)
+	}); err != nil {
+		return err
+	}
+	if err := m.editHeroPhoto(slug, hashID, ""); err != nil {
+		return err
+	}
+
+	if err := os.Remove(photo.Path); err != nil {
+		return fmt.Errorf("failed to remove photo: %w", err)
+	}
+	return nil
+}
+
+// ReplacePhoto replaces a photo with new content (e.g. a re-edited version). The
+// new photo takes over the old one's placements, settings and hero reference, and
+// its file name when the extension allows it.
+func (m *Manager) ReplacePhoto(slug, hashID, filename string, r io.Reader) (*PhotoInfo, error) {
+	old, err := m.GetPhoto(slug, hashID)
+	if err != nil {
+		return nil, err
+	}
+
+	photo, duplicate, err := m.AddPhoto(slug, filename, r)
+	if err != nil {
+		return nil, err
+	}
+	if duplicate {
+		if photo.HashID == hashID {
+			return old, nil // Same content, nothing to replace
+		}
+		return nil, ErrDuplicatePhoto
+	}
+
+	// Point the references at the new photo before removing the old one
+	if err := m.editLayout(slug, func(layout *LayoutConfig) bool {
+		return layout.RenamePhoto(hashID, photo.HashID)
+	}); err != nil {
+		return nil, err
+	}
+	if err := m.editHeroPhoto(slug, hashID, photo.HashID); err != nil {
+		return nil, err
+	}
+
+	if err := os.Remove(old.Path); err != nil {
+		return nil, fmt.Errorf("failed to remove replaced photo: %w", err)
+	}
+
+	// Keep the old file name if the new photo has the same extension
+	if strings.EqualFold(filepath.Ext(old.Path), filepath.Ext(photo.Path)) {
+		if err := os.Rename(photo.Path, old.Path); err == nil {
+			photo.Path = old.Path
+			photo.Filename = old.Filename
+		}
+	}
+	return photo, nil
+}
+
+// MovePhoto moves a photo to another project. It is placed in the target layout
+// at the same desktop position, and at its mobile position when the target has
+// a mobile layout, wherever those cells are free, or below the existing
+// placements otherwise. The layouts and the hero photo are updated before the
+// file is moved; if a step fails, the steps before it are undone.
+func (m *Manager) MovePhoto(slug, hashID, targetSlug string) (*PhotoInfo, error) {
+	if slug == targetSlug {
+		return nil, fmt.Errorf("photo is already in project %s", slug)
+	}
+	if _, err := m.GetProject(targetSlug); err != nil {
+		return nil, err
+	}
+
+	photo, err := m.GetPhoto(slug, hashID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.GetPhoto(targetSlug, hashID); err == nil {
+		return nil, ErrDuplicatePhoto
+	}
+
+	targetDir := m.ProjectPhotosDir(targetSlug)
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create photos directory: %w", err)
+	}
+	targetPath, err := claimPhotoPath(targetDir, photo.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var undo []func() error
+	fail := func(err error) (*PhotoInfo, error) {
+		os.Remove(targetPath)
+		for i := len(undo) - 1; i >= 0; i-- {
+			if undoErr := undo[i](); undoErr != nil {
+				return nil, fmt.Errorf("%w (undoing the move failed too: %v)", err, undoErr)
+			}
+		}
+		return nil, err
+	}
+
+	// Carry the positions and settings over to the target layout
+	var position, mobilePosition *GridPosition
+	var settings *PhotoSettings
+	if layout, err := m.GetLayout(slug); err == nil {
+		position = placementOf(layout.Placements, hashID)
+		mobilePosition = placementOf(layout.MobilePlacements, hashID)
+		if s, ok := layout.Photos[hashID]; ok {
+			settings = &s
+		}
+	}
+	if mobilePosition == nil {
+		mobilePosition = position
+	}
+
+	if position != nil || settings != nil {
+		if err := m.editLayout(targetSlug, func(layout *LayoutConfig) bool {
+			if position != nil {
+				layout.PlacePhoto(hashID, *position)
+			}
+			if mobilePosition != nil && layout.HasMobileLayout() {
+				layout.PlaceMobilePhoto(hashID, *mobilePosition)
+			}
+			if settings != nil {
+				if layout.Photos == nil {
+					layout.Photos = make(map[string]PhotoSettings)
+				}
+				layout.Photos[hashID] = *settings
+			}
+			return true
+		}); err != nil {
+			return fail(err)
+		}
+		undo = append(undo, func() error {
+			return m.editLayout(targetSlug, func(layout *LayoutConfig) bool {
+				return layout.RemovePhoto(hashID)
+			})
+		})
+	}
+
+	var before LayoutConfig
+	if err := m.editLayout(slug, func(layout *LayoutConfig) bool {
+		before.Placements = append([]PhotoPlacement(nil), layout.Placements...)
+		before.MobilePlacements = append([]PhotoPlacement(nil), layout.MobilePlacements...)
+		return layout.RemovePhoto(hashID)
+	}); err != nil {
+		return fail(err)
+	}
+	undo = append(undo, func() error {
+		return m.editLayout(slug, func(layout *LayoutConfig) bool {
+			layout.Placements, layout.MobilePlacements = before.Placements, before.MobilePlacements
+			if settings != nil {
+				if layout.Photos == nil {
+					layout.Photos = make(map[string]PhotoSettings)
+				}
+				layout.Photos[hashID] = *settings
+			}
+			return true
+		})
+	})
+
+	if meta, err := m.GetProject(slug); err == nil && meta.HeroPhoto == hashID {
+		if err := m.editHeroPhoto(slug, hashID, ""); err != nil {
+			return fail(err)
+		}
+		undo = append(undo, func() error { return m.editHeroPhoto(slug, "", hashID) })
+	}
+
+	if err := os.Rename(photo.Path, targetPath); err != nil {
+		return fail(fmt.Errorf("failed to move photo: %w", err))
+	}
+	return newPhotoInfo(targetSlug, targetPath, photo.Size)
+}
+
+// placementOf returns the position of the first placement of hashID, or nil
+func placementOf(placements []PhotoPlacement, hashID string) *GridPosition {
+	for _, p := range placements {
+		if p.Filename == hashID {
+			pos := p.Position
+			return &pos
+		}
+	}
+	return nil
+}
+
+// editLayout applies edit to a project's layout and saves it if edit reports a
+// change. Projects without a layout file are left alone.
+func (m *Manager) editLayout(slug string, edit func(layout *LayoutConfig) bool) error {
+	defer lockFile(m.ProjectLayoutPath(slug))()
+	layout, err := m.loadLayout(slug)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if !edit(layout) {
+		return nil
+	}
+	if err := m.writeLayout(slug, layout); err != nil {
+		return fmt.Errorf("failed to save layout: %w", err)
+	}
+	return nil
+}
+
+// editHeroPhoto points the project's hero photo from oldID to newID (empty to clear it)
+func (m *Manager) editHeroPhoto(slug, oldID, newID string) error {
+	defer lockFile(m.ProjectMetaPath(slug))()
+	meta, err := m.loadProject(slug)
+	if err != nil {
+		return err
+	}
+	if meta.HeroPhoto != oldID {
+		return nil
+	}
+	meta.HeroPhoto = newID
+	meta.UpdatedAt = time.Now()
+	if err := util.SaveYAML(m.ProjectMetaPath(slug), meta); err != nil {
+		return fmt.Errorf("failed to save metadata: %w", err)
+	}
+	return nil
+}
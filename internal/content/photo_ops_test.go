@@ -0,0 +1,238 @@
+package content
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/util"
+)
+
+// encodeJPEG returns a small JPEG filled with c, so different colors hash differently
+func encodeJPEG(t *testing.T, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 30, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 30; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("Failed to encode JPEG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// setupPhotoProject creates a project with one photo placed in its layout and
+// used as the hero photo
+func setupPhotoProject(t *testing.T, m *Manager, title string) (*ProjectMetadata, *PhotoInfo) {
+	t.Helper()
+	project, err := m.CreateProject(title, "")
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	photo, _, err := m.AddPhoto(project.Slug, "photo.jpg", bytes.NewReader(encodeJPEG(t, color.Black)))
+	if err != nil {
+		t.Fatalf("AddPhoto failed: %v", err)
+	}
+
+	layout := &LayoutConfig{
+		GridWidth: 12,
+		Placements: []PhotoPlacement{
+			{Filename: photo.HashID, Position: GridPosition{TopLeftX: 3, TopLeftY: 1, BottomRightX: 8, BottomRightY: 4}},
+		},
+		Photos: map[string]PhotoSettings{photo.HashID: {FocalPoint: &FocalPoint{X: 0.2, Y: 0.8}}},
+	}
+	if err := m.UpdateLayout(project.Slug, layout); err != nil {
+		t.Fatalf("UpdateLayout failed: %v", err)
+	}
+	project.HeroPhoto = photo.HashID
+	if err := util.SaveYAML(m.ProjectMetaPath(project.Slug), project); err != nil {
+		t.Fatalf("Failed to save metadata: %v", err)
+	}
+	return project, photo
+}
+
+// TestDeletePhoto checks that a deleted photo leaves the layout and hero, and
+// that a failed reference update keeps the file
+func TestDeletePhoto(t *testing.T) {
+	m := NewManager(t.TempDir())
+	project, photo := setupPhotoProject(t, m, "Delete")
+
+	// An unreadable layout stops the delete before the file is removed
+	layoutPath := m.ProjectLayoutPath(project.Slug)
+	if err := os.Rename(layoutPath, layoutPath+".moved"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(layoutPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DeletePhoto(project.Slug, photo.HashID); err == nil {
+		t.Fatal("Expected DeletePhoto to fail with an unreadable layout")
+	}
+	if _, err := os.Stat(photo.Path); err != nil {
+		t.Fatalf("Expected the photo to be kept when its references could not be updated: %v", err)
+	}
+	os.Remove(layoutPath)
+	if err := os.Rename(layoutPath+".moved", layoutPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.DeletePhoto(project.Slug, photo.HashID); err != nil {
+		t.Fatalf("DeletePhoto failed: %v", err)
+	}
+	if _, err := os.Stat(photo.Path); !os.IsNotExist(err) {
+		t.Error("Expected the photo file to be removed")
+	}
+	layout, _ := m.GetLayout(project.Slug)
+	if len(layout.Placements) != 0 || layout.FocalPointFor(photo.HashID) != nil {
+		t.Errorf("Expected the placement and settings to be removed, got %+v", layout)
+	}
+	if meta, _ := m.GetProject(project.Slug); meta.HeroPhoto != "" {
+		t.Errorf("Expected the hero photo to be cleared, got %s", meta.HeroPhoto)
+	}
+}
+
+// TestReplacePhotoKeepsPlacement checks that the new hash takes over the layout and hero
+func TestReplacePhotoKeepsPlacement(t *testing.T) {
+	m := NewManager(t.TempDir())
+	project, old := setupPhotoProject(t, m, "Replace")
+
+	photo, err := m.ReplacePhoto(project.Slug, old.HashID, "edited.jpg", bytes.NewReader(encodeJPEG(t, color.White)))
+	if err != nil {
+		t.Fatalf("ReplacePhoto failed: %v", err)
+	}
+	if photo.HashID == old.HashID {
+		t.Fatal("Expected a new hash ID")
+	}
+	if photo.Filename != old.Filename {
+		t.Errorf("Filename = %s, want %s", photo.Filename, old.Filename)
+	}
+
+	layout, _ := m.GetLayout(project.Slug)
+	if len(layout.Placements) != 1 || layout.Placements[0].Filename != photo.HashID {
+		t.Errorf("Placements = %+v, want the new hash ID", layout.Placements)
+	}
+	if layout.Placements[0].Position.TopLeftX != 3 {
+		t.Errorf("Position changed: %+v", layout.Placements[0].Position)
+	}
+	if layout.FocalPointFor(photo.HashID) == nil {
+		t.Error("Focal point was not carried over")
+	}
+	if meta, _ := m.GetProject(project.Slug); meta.HeroPhoto != photo.HashID {
+		t.Errorf("HeroPhoto = %s, want %s", meta.HeroPhoto, photo.HashID)
+	}
+	if photos, _ := m.ListPhotos(project.Slug); len(photos) != 1 {
+		t.Errorf("Expected 1 photo after replace, got %d", len(photos))
+	}
+}
+
+// TestMovePhoto checks that a moved photo leaves the source and keeps its
+// desktop and mobile positions in the target
+func TestMovePhoto(t *testing.T) {
+	m := NewManager(t.TempDir())
+	source, photo := setupPhotoProject(t, m, "Source")
+	target, err := m.CreateProject("Target", "")
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+
+	mobileAt := GridPosition{TopLeftX: 2, TopLeftY: 3, BottomRightX: 5, BottomRightY: 6}
+	layout, _ := m.GetLayout(source.Slug)
+	layout.MobileGridWidth = 6
+	layout.MobilePlacements = []PhotoPlacement{{Filename: photo.HashID, Position: mobileAt}}
+	if err := m.UpdateLayout(source.Slug, layout); err != nil {
+		t.Fatalf("UpdateLayout failed: %v", err)
+	}
+	// The target has a hand-made mobile layout whose first rows are taken
+	taken := GridPosition{TopLeftX: 1, TopLeftY: 1, BottomRightX: 6, BottomRightY: 2}
+	if err := m.UpdateLayout(target.Slug, &LayoutConfig{
+		GridWidth:        12,
+		MobileGridWidth:  6,
+		Placements:       []PhotoPlacement{{Filename: "text-1", Position: GridPosition{TopLeftX: 9, TopLeftY: 1, BottomRightX: 12, BottomRightY: 2}}},
+		MobilePlacements: []PhotoPlacement{{Filename: "text-1", Position: taken}},
+		Blocks:           map[string]Block{"text-1": {Type: BlockText, Text: "Hello"}},
+	}); err != nil {
+		t.Fatalf("UpdateLayout failed: %v", err)
+	}
+
+	if _, err := m.MovePhoto(source.Slug, photo.HashID, target.Slug); err != nil {
+		t.Fatalf("MovePhoto failed: %v", err)
+	}
+
+	if photos, _ := m.ListPhotos(source.Slug); len(photos) != 0 {
+		t.Errorf("Expected no photos left in source, got %d", len(photos))
+	}
+	if layout, _ := m.GetLayout(source.Slug); len(layout.Placements) != 0 || len(layout.MobilePlacements) != 0 {
+		t.Errorf("Source placements = %+v / %+v, want none", layout.Placements, layout.MobilePlacements)
+	}
+	if meta, _ := m.GetProject(source.Slug); meta.HeroPhoto != "" {
+		t.Errorf("Source HeroPhoto = %s, want empty", meta.HeroPhoto)
+	}
+
+	if _, err := m.GetPhoto(target.Slug, photo.HashID); err != nil {
+		t.Errorf("Photo not found in target: %v", err)
+	}
+	layout, _ = m.GetLayout(target.Slug)
+	if len(layout.Placements) != 2 || layout.Placements[1].Position.TopLeftX != 3 {
+		t.Errorf("Target placements = %+v, want the original position", layout.Placements)
+	}
+	if len(layout.MobilePlacements) != 2 || layout.MobilePlacements[1].Position != mobileAt {
+		t.Errorf("Target mobile placements = %+v, want the original mobile position", layout.MobilePlacements)
+	}
+	if layout.FocalPointFor(photo.HashID) == nil {
+		t.Error("Focal point was not carried over")
+	}
+	if err := ValidateLayout(layout, map[string]bool{photo.HashID: true}).Err(); err != nil {
+		t.Errorf("Expected a valid target layout, got %v", err)
+	}
+}
+
+// TestMovePhotoRollback checks that a failed move leaves the photo, its
+// placement and the target layout as they were
+func TestMovePhotoRollback(t *testing.T) {
+	m := NewManager(t.TempDir())
+	source, photo := setupPhotoProject(t, m, "Source")
+	target, err := m.CreateProject("Target", "")
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	if err := m.UpdateLayout(target.Slug, &LayoutConfig{GridWidth: 12}); err != nil {
+		t.Fatalf("UpdateLayout failed: %v", err)
+	}
+
+	// The source metadata cannot be saved (its backup path is a directory), so
+	// clearing the hero photo fails after both layouts were updated
+	backup := m.ProjectMetaPath(source.Slug) + util.BackupSuffix
+	os.Remove(backup)
+	if err := os.MkdirAll(filepath.Join(backup, "keep"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.MovePhoto(source.Slug, photo.HashID, target.Slug); err == nil {
+		t.Fatal("Expected MovePhoto to fail")
+	}
+	if err := os.RemoveAll(backup); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(photo.Path); err != nil {
+		t.Errorf("Expected the photo to stay in the source: %v", err)
+	}
+	if photos, _ := m.ListPhotos(target.Slug); len(photos) != 0 {
+		t.Errorf("Expected no photos in the target, got %d", len(photos))
+	}
+	if layout, _ := m.GetLayout(source.Slug); len(layout.Placements) != 1 || layout.FocalPointFor(photo.HashID) == nil {
+		t.Errorf("Expected the source placement and settings to be restored, got %+v", layout)
+	}
+	if layout, _ := m.GetLayout(target.Slug); len(layout.Placements) != 0 || len(layout.Photos) != 0 {
+		t.Errorf("Expected the target layout to be unchanged, got %+v", layout)
+	}
+	if meta, _ := m.GetProject(source.Slug); meta.HeroPhoto != photo.HashID {
+		t.Errorf("Source HeroPhoto = %s, want %s", meta.HeroPhoto, photo.HashID)
+	}
+}
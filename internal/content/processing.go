@@ -0,0 +1,16 @@
+package content
+
+// ProcessingSettings configures how image variants are encoded. They are set in
+// site.yaml, so that images process and the builder render the same variants.
+type ProcessingSettings struct {
+	Quality      int              `yaml:"quality,omitempty"`       // WebP quality for variants and thumbnails (1-100, default 85)
+	QualityCurve string           `yaml:"quality_curve,omitempty"` // Per-width variant quality, e.g. "480=90,1920=80"
+	Sharpen      *SharpenSettings `yaml:"sharpen,omitempty"`       // Unsharp mask applied after resizing
+}
+
+// SharpenSettings configures the unsharp mask applied to variants
+type SharpenSettings struct {
+	Amount    float64 `yaml:"amount"`              // Strength (0 disables, e.g. 0.5)
+	Radius    float64 `yaml:"radius,omitempty"`    // Blur radius (sigma) in pixels (default 0.5)
+	Threshold float64 `yaml:"threshold,omitempty"` // Minimum pixel difference (0-255) before sharpening applies
+}
@@ -5,21 +5,142 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/util"
+	"gopkg.in/yaml.v3"
 )
 
 // ProjectMetadata holds project information
 type ProjectMetadata struct {
-	Title       string    `yaml:"title"`
-	Slug        string    `yaml:"slug"`
-	Description string    `yaml:"description"`
-	HeroPhoto   string    `yaml:"hero_photo,omitempty" json:"heroPhoto,omitempty"`
-	Hidden      bool      `yaml:"hidden"`
-	CreatedAt   time.Time `yaml:"created_at"`
-	UpdatedAt   time.Time `yaml:"updated_at"`
+	Title        string             `yaml:"title"`
+	Slug         string             `yaml:"slug"`
+	Description  string             `yaml:"description"`
+	Body         string             `yaml:"body,omitempty"`          // Markdown text shown with the grid
+	BodyPosition string             `yaml:"body_position,omitempty"` // BodyAbove (default) or BodyBelow the grid
+	Date         string             `yaml:"date,omitempty"`          // Year ("2024"), month ("2024-05") or day ("2024-05-12")
+	Category     string             `yaml:"category,omitempty"`      // Kind of work, e.g. "Commercial", "Editorial" or "Personal"
+	Tags         []string           `yaml:"tags,omitempty"`          // Free-form labels, e.g. "portrait" or "film"
+	Credits      *Credits           `yaml:"credits,omitempty"`
+	Theme        *ProjectTheme      `yaml:"theme,omitempty"` // Overrides the site's look on the project page
+	HeroPhoto    string             `yaml:"hero_photo,omitempty" json:"heroPhoto,omitempty"`
+	Hidden       bool               `yaml:"hidden"`
+	Watermark    *WatermarkSettings `yaml:"watermark,omitempty"` // Overrides the site watermark for this project
+	CreatedAt    time.Time          `yaml:"created_at"`
+	UpdatedAt    time.Time          `yaml:"updated_at"`
+}
+
+// Body positions relative to the project's grid
+const (
+	BodyAbove = "above"
+	BodyBelow = "below"
+)
+
+// BodyBelowGrid reports whether the body is shown below the grid
+func (p *ProjectMetadata) BodyBelowGrid() bool {
+	return p.BodyPosition == BodyBelow
+}
+
+// DisplayDate returns the project date for display, e.g. "2024", "May 2024" or
+// "12 May 2024". Dates that do not parse are returned as they are.
+func (p *ProjectMetadata) DisplayDate() string {
+	display, _ := parseProjectDate(p.Date)
+	return display
+}
+
+// projectDateFormats are the accepted project date layouts and how each is displayed
+var projectDateFormats = []struct{ layout, display string }{
+	{"2006", "2006"}, {"2006-01", "January 2006"}, {"2006-01-02", "2 January 2006"},
+}
+
+// parseProjectDate returns a project date formatted for display, and whether it is valid
+func parseProjectDate(date string) (string, bool) {
+	for _, f := range projectDateFormats {
+		if t, err := time.Parse(f.layout, date); err == nil {
+			return t.Format(f.display), true
+		}
+	}
+	return date, false
+}
+
+// Credits lists the people and places behind a project, e.g. for commissioned work
+type Credits struct {
+	Client    string `yaml:"client,omitempty"`
+	Agency    string `yaml:"agency,omitempty"`
+	Assistant string `yaml:"assistant,omitempty"`
+	Location  string `yaml:"location,omitempty"`
+}
+
+// Credit is a role and the name credited for it
+type Credit struct {
+	Role string
+	Name string
+}
+
+// Entries returns the credits that are set, in display order
+func (c *Credits) Entries() []Credit {
+	if c == nil {
+		return nil
+	}
+	var entries []Credit
+	for _, e := range []Credit{
+		{"Client", c.Client}, {"Agency", c.Agency}, {"Assistant", c.Assistant}, {"Location", c.Location},
+	} {
+		if e.Name != "" {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// ProjectTheme overrides parts of the site's look on a project page
+type ProjectTheme struct {
+	Background string `yaml:"background,omitempty"` // Page background, as a hex color (e.g. "#111111")
+	GridGap    *int   `yaml:"grid_gap,omitempty"`   // Space between grid cells, in pixels
+}
+
+// maxGridGap is the largest grid gap a project theme can set, in pixels
+const maxGridGap = 200
+
+// Validate checks that the theme values are safe to use in CSS
+func (t *ProjectTheme) Validate() error {
+	if t == nil {
+		return nil
+	}
+	if t.Background != "" && !ValidHexColor(t.Background) {
+		return fmt.Errorf("invalid background %q: expected a hex color like #1a1a1a", t.Background)
+	}
+	if t.GridGap != nil && (*t.GridGap < 0 || *t.GridGap > maxGridGap) {
+		return fmt.Errorf("invalid grid gap %d: expected 0 to %d pixels", *t.GridGap, maxGridGap)
+	}
+	return nil
+}
+
+// ValidHexColor reports whether s is a CSS hex color (#rgb, #rgba, #rrggbb or #rrggbbaa)
+func ValidHexColor(s string) bool {
+	if !strings.HasPrefix(s, "#") {
+		return false
+	}
+	switch len(s) - 1 {
+	case 3, 4, 6, 8:
+	default:
+		return false
+	}
+	_, err := strconv.ParseUint(s[1:], 16, 64)
+	return err == nil
+}
+
+// Validate checks the project's date, body position and theme
+func (p *ProjectMetadata) Validate() error {
+	if _, ok := parseProjectDate(p.Date); p.Date != "" && !ok {
+		return fmt.Errorf("invalid date %q: expected YYYY, YYYY-MM or YYYY-MM-DD", p.Date)
+	}
+	if p.BodyPosition != "" && p.BodyPosition != BodyAbove && p.BodyPosition != BodyBelow {
+		return fmt.Errorf("invalid body position %q: expected %s or %s", p.BodyPosition, BodyAbove, BodyBelow)
+	}
+	return p.Theme.Validate()
 }
 
 // GridPosition represents a photo's position in the grid
@@ -30,18 +151,39 @@ type GridPosition struct {
 	BottomRightY int `yaml:"bottom_right_y" json:"bottomRightY"`
 }
 
-// PhotoPlacement holds a photo's placement in the grid
+// PhotoPlacement holds a photo's or a block's placement in the grid
 type PhotoPlacement struct {
-	Filename string       `yaml:"filename" json:"filename"`
+	Filename string       `yaml:"filename" json:"filename"` // Photo hash ID, or block ID (see LayoutConfig.Blocks)
 	Position GridPosition `yaml:"position" json:"position"`
 }
 
+// FocalPoint marks the point of interest of a photo, as fractions (0-1) of its
+// width and height measured from the top-left corner
+type FocalPoint struct {
+	X float64 `yaml:"x" json:"x"`
+	Y float64 `yaml:"y" json:"y"`
+}
+
+// CSSPosition returns the focal point as a CSS object-position value
+func (fp FocalPoint) CSSPosition() string {
+	return fmt.Sprintf("%.1f%% %.1f%%", fp.X*100, fp.Y*100)
+}
+
+// PhotoSettings holds per-photo presentation settings
+type PhotoSettings struct {
+	FocalPoint *FocalPoint `yaml:"focal_point,omitempty" json:"focalPoint,omitempty"`
+	Caption    string      `yaml:"caption,omitempty" json:"caption,omitempty"` // Shown in the lightbox and on the photo's page
+}
+
 // LayoutConfig holds layout configuration for grid-based composition
 type LayoutConfig struct {
-	GridWidth        int              `yaml:"grid_width" json:"gridWidth"`                                   // Width of the grid (default: 12)
-	Placements       []PhotoPlacement `yaml:"placements" json:"placements"`                                  // Photo positions in the grid
-	MobileGridWidth  int              `yaml:"mobile_grid_width,omitempty" json:"mobileGridWidth,omitempty"`  // Width of mobile grid (optional)
-	MobilePlacements []PhotoPlacement `yaml:"mobile_placements,omitempty" json:"mobilePlacements,omitempty"` // Mobile photo positions (optional)
+	GridWidth        int                      `yaml:"grid_width" json:"gridWidth"`                                   // Width of the grid (default: 12)
+	Placements       []PhotoPlacement         `yaml:"placements" json:"placements"`                                  // Photo positions in the grid
+	MobileGridWidth  int                      `yaml:"mobile_grid_width,omitempty" json:"mobileGridWidth,omitempty"`  // Width of mobile grid (optional)
+	MobilePlacements []PhotoPlacement         `yaml:"mobile_placements,omitempty" json:"mobilePlacements,omitempty"` // Mobile photo positions (optional)
+	MobileCrop       string                   `yaml:"mobile_crop,omitempty" json:"mobileCrop,omitempty"`             // Aspect ratio (e.g. "4:5") of cropped variants served on mobile (optional)
+	Photos           map[string]PhotoSettings `yaml:"photos,omitempty" json:"photos,omitempty"`                      // Per-photo settings keyed by hash ID (optional)
+	Blocks           map[string]Block         `yaml:"blocks,omitempty" json:"blocks,omitempty"`                      // Text, video and spacer blocks keyed by the ID placements use (optional)
 }
 
 // HasMobileLayout returns true if a separate mobile layout is configured
@@ -49,6 +191,181 @@ func (lc *LayoutConfig) HasMobileLayout() bool {
 	return lc.MobileGridWidth > 0 && len(lc.MobilePlacements) > 0
 }
 
+// MissingFromMobile returns the hash IDs of photos placed on the desktop grid but
+// not on the mobile grid; blocks are not counted. It is empty when no mobile
+// layout is configured.
+func (lc *LayoutConfig) MissingFromMobile() []string {
+	if !lc.HasMobileLayout() {
+		return nil
+	}
+	onMobile := make(map[string]bool, len(lc.MobilePlacements))
+	for _, p := range lc.MobilePlacements {
+		onMobile[p.Filename] = true
+	}
+	var missing []string
+	for _, p := range lc.Placements {
+		if _, isBlock := lc.Blocks[p.Filename]; isBlock {
+			continue
+		}
+		if !onMobile[p.Filename] {
+			missing = append(missing, p.Filename)
+			onMobile[p.Filename] = true // Report each photo once
+		}
+	}
+	return missing
+}
+
+// RemovePhoto removes a photo's desktop and mobile placements and its settings.
+// It returns true if the layout changed.
+func (lc *LayoutConfig) RemovePhoto(hashID string) bool {
+	changed := false
+	filter := func(placements []PhotoPlacement) []PhotoPlacement {
+		kept := placements[:0]
+		for _, p := range placements {
+			if p.Filename == hashID {
+				changed = true
+				continue
+			}
+			kept = append(kept, p)
+		}
+		return kept
+	}
+	lc.Placements = filter(lc.Placements)
+	lc.MobilePlacements = filter(lc.MobilePlacements)
+	if _, ok := lc.Photos[hashID]; ok {
+		delete(lc.Photos, hashID)
+		changed = true
+	}
+	return changed
+}
+
+// RenamePhoto points placements and settings of oldID to newID (e.g. after the
+// photo was replaced). It returns true if the layout changed.
+func (lc *LayoutConfig) RenamePhoto(oldID, newID string) bool {
+	changed := false
+	for _, placements := range [][]PhotoPlacement{lc.Placements, lc.MobilePlacements} {
+		for i := range placements {
+			if placements[i].Filename == oldID {
+				placements[i].Filename = newID
+				changed = true
+			}
+		}
+	}
+	if settings, ok := lc.Photos[oldID]; ok {
+		delete(lc.Photos, oldID)
+		lc.Photos[newID] = settings
+		changed = true
+	}
+	return changed
+}
+
+// PlacePhoto adds a desktop placement for a photo at pos if it fits the grid and
+// the cells are free, otherwise with the same size below the existing placements
+func (lc *LayoutConfig) PlacePhoto(hashID string, pos GridPosition) {
+	lc.Placements = placeInGrid(lc.Placements, lc.GridWidth, hashID, pos)
+}
+
+// PlaceMobilePhoto is PlacePhoto for the mobile grid
+func (lc *LayoutConfig) PlaceMobilePhoto(hashID string, pos GridPosition) {
+	width := lc.MobileGridWidth
+	if width == 0 {
+		width = DefaultMobileGridWidth
+	}
+	lc.MobilePlacements = placeInGrid(lc.MobilePlacements, width, hashID, pos)
+}
+
+// placeInGrid appends a placement for hashID at pos if it fits a grid of
+// gridWidth columns without overlapping the other placements, otherwise with
+// the same size (narrowed to the grid) below them
+func placeInGrid(placements []PhotoPlacement, gridWidth int, hashID string, pos GridPosition) []PhotoPlacement {
+	width := pos.BottomRightX - pos.TopLeftX + 1
+	height := pos.BottomRightY - pos.TopLeftY + 1
+	if width > gridWidth {
+		width = gridWidth
+	}
+
+	free := pos.BottomRightX <= gridWidth
+	lastRow := 0
+	for _, p := range placements {
+		other := p.Position
+		if other.BottomRightY > lastRow {
+			lastRow = other.BottomRightY
+		}
+		if other.TopLeftX <= pos.BottomRightX && pos.TopLeftX <= other.BottomRightX &&
+			other.TopLeftY <= pos.BottomRightY && pos.TopLeftY <= other.BottomRightY {
+			free = false
+		}
+	}
+
+	if !free {
+		pos = GridPosition{
+			TopLeftX:     1,
+			TopLeftY:     lastRow + 1,
+			BottomRightX: width,
+			BottomRightY: lastRow + height,
+		}
+	}
+	return append(placements, PhotoPlacement{Filename: hashID, Position: pos})
+}
+
+// FocalPointFor returns the focal point of a photo, or nil if none is set
+func (lc *LayoutConfig) FocalPointFor(hashID string) *FocalPoint {
+	if settings, ok := lc.Photos[hashID]; ok {
+		return settings.FocalPoint
+	}
+	return nil
+}
+
+// CaptionFor returns the caption of a photo, or "" if none is set
+func (lc *LayoutConfig) CaptionFor(hashID string) string {
+	return lc.Photos[hashID].Caption
+}
+
+// MobileCropName returns the variant name segment for mobile crops (e.g. "crop4x5"),
+// or an empty string if no valid mobile crop is configured
+func (lc *LayoutConfig) MobileCropName() string {
+	w, h, err := ParseRatio(lc.MobileCrop)
+	if err != nil {
+		return ""
+	}
+	return CropName(w, h)
+}
+
+// CropRatio is the aspect ratio of a crop, e.g. 4:5
+type CropRatio struct {
+	Width  int
+	Height int
+}
+
+// MobileCropRatio returns the mobile crop's aspect ratio, or nil if no valid
+// mobile crop is configured
+func (lc *LayoutConfig) MobileCropRatio() *CropRatio {
+	w, h, err := ParseRatio(lc.MobileCrop)
+	if err != nil {
+		return nil
+	}
+	return &CropRatio{Width: w, Height: h}
+}
+
+// ParseRatio parses an aspect ratio in the form "W:H" (e.g. "4:5")
+func ParseRatio(s string) (width, height int, err error) {
+	wStr, hStr, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid ratio %q: expected W:H", s)
+	}
+	width, errW := strconv.Atoi(strings.TrimSpace(wStr))
+	height, errH := strconv.Atoi(strings.TrimSpace(hStr))
+	if errW != nil || errH != nil || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("invalid ratio %q: expected positive integers W:H", s)
+	}
+	return width, height, nil
+}
+
+// CropName returns the variant name segment for a crop ratio, e.g. "crop4x5"
+func CropName(width, height int) string {
+	return fmt.Sprintf("crop%dx%d", width, height)
+}
+
 // Manager handles content operations
 type Manager struct {
 	contentDir string
@@ -93,14 +410,40 @@ type ProjectOrder struct {
 	Order int    `yaml:"order"`
 }
 
+// Redirect points a project's former slug to its current one, so that links to
+// the old address keep working after a rename
+type Redirect struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
 type SiteMetadata struct {
-	Copyright     string         `yaml:"copyright"`
-	WebsiteName   string         `yaml:"website_name"`
-	LogoPrimary   string         `yaml:"logo_primary"`
-	LogoSecondary string         `yaml:"logo_secondary"`
-	About         *About         `yaml:"about,omitempty"`
-	Contact       *Contact       `yaml:"contact,omitempty"`
-	Projects      []ProjectOrder `yaml:"projects,omitempty"`
+	Copyright     string             `yaml:"copyright"`
+	WebsiteName   string             `yaml:"website_name"`
+	LogoPrimary   string             `yaml:"logo_primary"`
+	LogoSecondary string             `yaml:"logo_secondary"`
+	About         *About             `yaml:"about,omitempty"`
+	Contact       *Contact           `yaml:"contact,omitempty"`
+	Projects      []ProjectOrder     `yaml:"projects,omitempty"`
+	Watermark     *WatermarkSettings `yaml:"watermark,omitempty"` // Applied to generated image variants
+	Lightbox      *LightboxSettings  `yaml:"lightbox,omitempty"`  // Full-screen viewer of project photos
+
+	// Processing sets how image variants are encoded, for images process and the builder alike
+	Processing *ProcessingSettings `yaml:"processing,omitempty"`
+
+	// LayoutAwareWidths limits each photo's variants to the widths its largest
+	// grid cell needs (e.g. photos in small cells get no 1920w variant)
+	LayoutAwareWidths bool `yaml:"layout_aware_widths,omitempty"`
+
+	// Redirects from renamed projects' former slugs, maintained by RenameProject
+	Redirects []Redirect `yaml:"redirects,omitempty"`
+}
+
+// LightboxSettings configures the full-screen photo viewer of project pages and
+// the per-photo pages it links to
+type LightboxSettings struct {
+	Disabled bool `yaml:"disabled,omitempty"` // No viewer and no per-photo pages
+	Exif     bool `yaml:"exif,omitempty"`     // Show the camera settings read from each photo's EXIF data
 }
 
 // SiteMetaPath returns the path to the site-level metadata YAML file
@@ -111,6 +454,12 @@ func (m *Manager) SiteMetaPath() string {
 // LoadSiteMeta loads the site metadata if present. If the file does not exist
 // an empty SiteMetadata is returned with no error.
 func (m *Manager) LoadSiteMeta() (*SiteMetadata, error) {
+	defer lockFile(m.SiteMetaPath())()
+	return m.loadSiteMeta()
+}
+
+// loadSiteMeta loads the site metadata; the caller must hold the file's lock
+func (m *Manager) loadSiteMeta() (*SiteMetadata, error) {
 	var meta SiteMetadata
 	if err := util.LoadYAML(m.SiteMetaPath(), &meta); err != nil {
 		if os.IsNotExist(err) {
@@ -129,6 +478,21 @@ func (m *Manager) LoadSiteMeta() (*SiteMetadata, error) {
 
 // SaveSiteMeta saves the site metadata
 func (m *Manager) SaveSiteMeta(meta *SiteMetadata) error {
+	defer lockFile(m.SiteMetaPath())()
+	return util.SaveYAML(m.SiteMetaPath(), meta)
+}
+
+// UpdateSiteMeta loads the site metadata, applies update and saves the result,
+// without other changes to site.yaml in between
+func (m *Manager) UpdateSiteMeta(update func(meta *SiteMetadata) error) error {
+	defer lockFile(m.SiteMetaPath())()
+	meta, err := m.loadSiteMeta()
+	if err != nil {
+		return err
+	}
+	if err := update(meta); err != nil {
+		return err
+	}
 	return util.SaveYAML(m.SiteMetaPath(), meta)
 }
 
@@ -162,35 +526,49 @@ func (m *Manager) ProjectPhotosDir(slug string) string {
 	return filepath.Join(m.PhotosDir(), slug)
 }
 
-// Slugify converts a title to a URL-friendly slug
-func Slugify(title string) string {
-	slug := strings.ToLower(title)
-	slug = strings.ReplaceAll(slug, " ", "-")
-	// Remove special characters
-	var result strings.Builder
-	for _, r := range slug {
-		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
-			result.WriteRune(r)
-		}
-	}
-	return result.String()
+// CreateProject creates a new project with a slug derived from the title. If
+// the slug is taken or reserved (see ReservedSlug), a numeric suffix is added
+// ("-2", "-3", ...).
+func (m *Manager) CreateProject(title, description string) (*ProjectMetadata, error) {
+	return m.CreateProjectWithSlug(title, description, "")
 }
 
-// CreateProject creates a new project
-func (m *Manager) CreateProject(title, description string) (*ProjectMetadata, error) {
-	slug := Slugify(title)
-	if slug == "" {
+// CreateProjectWithSlug creates a new project with the given slug, or with one
+// derived from the title when slug is empty (see CreateProject). A given slug
+// must be valid and free, otherwise an error is returned.
+func (m *Manager) CreateProjectWithSlug(title, description, slug string) (*ProjectMetadata, error) {
+	if slug != "" {
+		if err := checkSlug(slug); err != nil {
+			return nil, err
+		}
+	}
+	auto := slug == ""
+	base := slug
+	if auto {
+		base = Slugify(title)
+	}
+	if base == "" {
 		return nil, fmt.Errorf("invalid title: cannot create slug")
 	}
 
-	projectDir := m.ProjectDir(slug)
-	if _, err := os.Stat(projectDir); err == nil {
-		return nil, fmt.Errorf("project with slug '%s' already exists", slug)
+	// Create directories. Creating the project directory claims the slug, so
+	// that projects created at the same time do not get the same one.
+	if err := os.MkdirAll(m.ProjectsDir(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create projects directory: %w", err)
 	}
-
-	// Create directories
-	if err := os.MkdirAll(projectDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create project directory: %w", err)
+	var mkdirErr error
+	slug = uniqueSlug(base, func(candidate string) bool {
+		if ReservedSlug(candidate) {
+			return true // Only reached for derived slugs, given ones were checked
+		}
+		mkdirErr = os.Mkdir(m.ProjectDir(candidate), 0755)
+		return auto && os.IsExist(mkdirErr)
+	})
+	if os.IsExist(mkdirErr) {
+		return nil, fmt.Errorf("%w: %s", ErrProjectExists, slug)
+	}
+	if mkdirErr != nil {
+		return nil, fmt.Errorf("failed to create project directory: %w", mkdirErr)
 	}
 	if err := os.MkdirAll(m.ProjectPhotosDir(slug), 0755); err != nil {
 		return nil, fmt.Errorf("failed to create photos directory: %w", err)
@@ -224,6 +602,12 @@ func (m *Manager) CreateProject(title, description string) (*ProjectMetadata, er
 
 // GetProject retrieves a project's metadata
 func (m *Manager) GetProject(slug string) (*ProjectMetadata, error) {
+	defer lockFile(m.ProjectMetaPath(slug))()
+	return m.loadProject(slug)
+}
+
+// loadProject loads a project's metadata; the caller must hold the file's lock
+func (m *Manager) loadProject(slug string) (*ProjectMetadata, error) {
 	var meta ProjectMetadata
 	if err := util.LoadYAML(m.ProjectMetaPath(slug), &meta); err != nil {
 		return nil, fmt.Errorf("failed to load project: %w", err)
@@ -233,14 +617,29 @@ func (m *Manager) GetProject(slug string) (*ProjectMetadata, error) {
 
 // UpdateProject updates a project's metadata
 func (m *Manager) UpdateProject(slug string, title, description string, hidden bool) error {
-	meta, err := m.GetProject(slug)
+	return m.UpdateProjectMeta(slug, func(meta *ProjectMetadata) error {
+		meta.Title = title
+		meta.Description = description
+		meta.Hidden = hidden
+		return nil
+	})
+}
+
+// UpdateProjectMeta loads a project's metadata, applies update and saves the
+// result if it is valid, without other changes to meta.yaml in between
+func (m *Manager) UpdateProjectMeta(slug string, update func(meta *ProjectMetadata) error) error {
+	defer lockFile(m.ProjectMetaPath(slug))()
+	meta, err := m.loadProject(slug)
 	if err != nil {
 		return err
 	}
-
-	meta.Title = title
-	meta.Description = description
-	meta.Hidden = hidden
+	if err := update(meta); err != nil {
+		return err
+	}
+	if err := meta.Validate(); err != nil {
+		return err
+	}
+	meta.Slug = slug // Changed by RenameProject only
 	meta.UpdatedAt = time.Now()
 
 	return util.SaveYAML(m.ProjectMetaPath(slug), meta)
@@ -324,6 +723,27 @@ func (m *Manager) ListProjects() ([]*ProjectMetadata, error) {
 
 // GetLayout retrieves a project's layout configuration
 func (m *Manager) GetLayout(slug string) (*LayoutConfig, error) {
+	defer lockFile(m.ProjectLayoutPath(slug))()
+	return m.loadLayout(slug)
+}
+
+// GetLayoutVersion retrieves a project's layout with its version, to be passed
+// back to UpdateLayoutIfVersion
+func (m *Manager) GetLayoutVersion(slug string) (*LayoutConfig, string, error) {
+	defer lockFile(m.ProjectLayoutPath(slug))()
+	version, err := fileVersion(m.ProjectLayoutPath(slug))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load layout: %w", err)
+	}
+	layout, err := m.loadLayout(slug)
+	if err != nil {
+		return nil, "", err
+	}
+	return layout, version, nil
+}
+
+// loadLayout loads a project's layout; the caller must hold the file's lock
+func (m *Manager) loadLayout(slug string) (*LayoutConfig, error) {
 	var layout LayoutConfig
 	if err := util.LoadYAML(m.ProjectLayoutPath(slug), &layout); err != nil {
 		return nil, fmt.Errorf("failed to load layout: %w", err)
@@ -357,6 +777,27 @@ func (lc *IndexLayoutConfig) HasMobileLayout() bool {
 
 // GetIndexLayout retrieves the index page layout configuration
 func (m *Manager) GetIndexLayout() (*IndexLayoutConfig, error) {
+	defer lockFile(m.IndexLayoutPath())()
+	return m.loadIndexLayout()
+}
+
+// GetIndexLayoutVersion retrieves the index page layout with its version, to
+// be passed back to SaveIndexLayoutIfVersion
+func (m *Manager) GetIndexLayoutVersion() (*IndexLayoutConfig, string, error) {
+	defer lockFile(m.IndexLayoutPath())()
+	version, err := fileVersion(m.IndexLayoutPath())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load index layout: %w", err)
+	}
+	layout, err := m.loadIndexLayout()
+	if err != nil {
+		return nil, "", err
+	}
+	return layout, version, nil
+}
+
+// loadIndexLayout loads the index page layout; the caller must hold the file's lock
+func (m *Manager) loadIndexLayout() (*IndexLayoutConfig, error) {
 	var layout IndexLayoutConfig
 	if err := util.LoadYAML(m.IndexLayoutPath(), &layout); err != nil {
 		if os.IsNotExist(err) {
@@ -370,10 +811,62 @@ func (m *Manager) GetIndexLayout() (*IndexLayoutConfig, error) {
 
 // SaveIndexLayout saves the index page layout configuration
 func (m *Manager) SaveIndexLayout(layout *IndexLayoutConfig) error {
-	return util.SaveYAML(m.IndexLayoutPath(), layout)
+	_, err := m.SaveIndexLayoutIfVersion(layout, "")
+	return err
+}
+
+// SaveIndexLayoutIfVersion saves the index page layout unless it changed since
+// ifVersion (empty to skip the check), returning ErrVersionConflict in that
+// case, and returns the new version
+func (m *Manager) SaveIndexLayoutIfVersion(layout *IndexLayoutConfig, ifVersion string) (string, error) {
+	path := m.IndexLayoutPath()
+	defer lockFile(path)()
+	if err := checkVersion(path, ifVersion); err != nil {
+		return "", err
+	}
+	if err := util.SaveYAML(path, layout); err != nil {
+		return "", err
+	}
+	return fileVersion(path)
 }
 
-// UpdateLayout updates a project's layout configuration
+// UpdateLayout updates a project's layout configuration and records it in the
+// layout history
 func (m *Manager) UpdateLayout(slug string, layout *LayoutConfig) error {
-	return util.SaveYAML(m.ProjectLayoutPath(slug), layout)
+	_, err := m.UpdateLayoutIfVersion(slug, layout, "")
+	return err
+}
+
+// UpdateLayoutIfVersion updates a project's layout unless it changed since
+// ifVersion (empty to skip the check), returning ErrVersionConflict in that
+// case, and returns the new version
+func (m *Manager) UpdateLayoutIfVersion(slug string, layout *LayoutConfig, ifVersion string) (string, error) {
+	path := m.ProjectLayoutPath(slug)
+	defer lockFile(path)()
+	if err := checkVersion(path, ifVersion); err != nil {
+		return "", err
+	}
+	if err := m.writeLayout(slug, layout); err != nil {
+		return "", err
+	}
+	return fileVersion(path)
+}
+
+// writeLayout saves a project's layout and records it in the layout history;
+// the caller must hold the file's lock
+func (m *Manager) writeLayout(slug string, layout *LayoutConfig) error {
+	data, err := yaml.Marshal(layout)
+	if err != nil {
+		return fmt.Errorf("failed to encode layout: %w", err)
+	}
+	if err := m.snapshotExistingLayout(slug); err != nil {
+		return fmt.Errorf("failed to record layout history: %w", err)
+	}
+	if err := util.WriteFileAtomic(m.ProjectLayoutPath(slug), data, 0644); err != nil {
+		return err
+	}
+	if err := m.saveLayoutRevision(slug, data, time.Now()); err != nil {
+		return fmt.Errorf("failed to record layout history: %w", err)
+	}
+	return nil
 }
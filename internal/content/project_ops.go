@@ -0,0 +1,272 @@
+package content
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/util"
+)
+
+// ErrProjectExists is returned when a project with the requested slug already exists
+var ErrProjectExists = errors.New("project already exists")
+
+// checkNewSlug returns an error if slug is invalid, reserved or taken by a
+// project or a photos directory
+func (m *Manager) checkNewSlug(slug string) error {
+	if err := checkSlug(slug); err != nil {
+		return err
+	}
+	if m.slugTaken(slug) {
+		return fmt.Errorf("%w: %s", ErrProjectExists, slug)
+	}
+	return nil
+}
+
+// slugTaken reports whether a project or a photos directory has the slug
+func (m *Manager) slugTaken(slug string) bool {
+	for _, dir := range []string{m.ProjectDir(slug), m.ProjectPhotosDir(slug)} {
+		if _, err := os.Stat(dir); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// RenameProject changes a project's slug. Its content and photos directories are
+// moved, the references in site.yaml and the index layout updated, and the old
+// slug recorded in site.yaml as a redirect to the new one. If a step fails,
+// the steps before it are undone.
+func (m *Manager) RenameProject(slug, newSlug string) (*ProjectMetadata, error) {
+	if _, err := m.GetProject(slug); err != nil {
+		return nil, err
+	}
+	if newSlug == slug {
+		return nil, fmt.Errorf("project already has slug %s", slug)
+	}
+
+	// Hold the project's files under both slugs for the whole rename, so that
+	// writes in progress finish first and nothing is written in between
+	defer lockFiles(m.ProjectLayoutPath(slug), m.ProjectMetaPath(slug), m.ProjectLayoutPath(newSlug), m.ProjectMetaPath(newSlug))()
+	if err := m.checkNewSlug(newSlug); err != nil {
+		return nil, err
+	}
+
+	var undo []func() error
+	fail := func(err error) (*ProjectMetadata, error) {
+		for i := len(undo) - 1; i >= 0; i-- {
+			if undoErr := undo[i](); undoErr != nil {
+				return nil, fmt.Errorf("%w (undoing the rename failed too: %v)", err, undoErr)
+			}
+		}
+		return nil, err
+	}
+
+	if err := m.moveProjectDirs(slug, newSlug); err != nil {
+		return nil, err
+	}
+	undo = append(undo, func() error { return m.moveProjectDirs(newSlug, slug) })
+
+	meta, err := m.setProjectSlug(newSlug, newSlug)
+	if err != nil {
+		return fail(err)
+	}
+	undo = append(undo, func() error {
+		_, err := m.setProjectSlug(newSlug, slug)
+		return err
+	})
+
+	var before SiteMetadata
+	if err := m.UpdateSiteMeta(func(site *SiteMetadata) error {
+		before.Projects = append([]ProjectOrder(nil), site.Projects...)
+		before.Redirects = append([]Redirect(nil), site.Redirects...)
+		site.renameProject(slug, newSlug)
+		return nil
+	}); err != nil {
+		return fail(fmt.Errorf("failed to update site metadata: %w", err))
+	}
+	undo = append(undo, func() error {
+		return m.UpdateSiteMeta(func(site *SiteMetadata) error {
+			site.Projects, site.Redirects = before.Projects, before.Redirects
+			return nil
+		})
+	})
+
+	if err := m.renameIndexReferences(slug, newSlug); err != nil {
+		return fail(fmt.Errorf("failed to update index layout: %w", err))
+	}
+	return meta, nil
+}
+
+// moveProjectDirs moves a project's content and photos directories to a new slug
+func (m *Manager) moveProjectDirs(slug, newSlug string) error {
+	if err := os.Rename(m.ProjectDir(slug), m.ProjectDir(newSlug)); err != nil {
+		return fmt.Errorf("failed to move project: %w", err)
+	}
+	if _, err := os.Stat(m.ProjectPhotosDir(slug)); os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.Rename(m.ProjectPhotosDir(slug), m.ProjectPhotosDir(newSlug)); err != nil {
+		// Put the project back so that it keeps its photos
+		os.Rename(m.ProjectDir(newSlug), m.ProjectDir(slug))
+		return fmt.Errorf("failed to move photos: %w", err)
+	}
+	return nil
+}
+
+// setProjectSlug sets the slug stored in the metadata of the project in the
+// directory of dirSlug; the caller must hold the file's lock
+func (m *Manager) setProjectSlug(dirSlug, slug string) (*ProjectMetadata, error) {
+	meta, err := m.loadProject(dirSlug)
+	if err != nil {
+		return nil, err
+	}
+	meta.Slug = slug
+	meta.UpdatedAt = time.Now()
+	if err := util.SaveYAML(m.ProjectMetaPath(dirSlug), meta); err != nil {
+		return nil, fmt.Errorf("failed to save metadata: %w", err)
+	}
+	return meta, nil
+}
+
+// renameProject points the project order and redirects from slug to newSlug and
+// adds a redirect from slug
+func (site *SiteMetadata) renameProject(slug, newSlug string) {
+	for i := range site.Projects {
+		if site.Projects[i].Slug == slug {
+			site.Projects[i].Slug = newSlug
+		}
+	}
+	redirects := site.Redirects[:0]
+	for _, r := range site.Redirects {
+		if r.From == newSlug {
+			continue // The slug is in use again
+		}
+		if r.To == slug {
+			r.To = newSlug // Older slugs lead straight to the new one
+		}
+		redirects = append(redirects, r)
+	}
+	site.Redirects = append(redirects, Redirect{From: slug, To: newSlug})
+}
+
+// renameIndexReferences points the index layout's hero placements of a project
+// to its new slug
+func (m *Manager) renameIndexReferences(slug, newSlug string) error {
+	defer lockFile(m.IndexLayoutPath())()
+	layout, err := m.loadIndexLayout()
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, placements := range [][]IndexHeroPlacement{layout.Placements, layout.MobilePlacements} {
+		for i := range placements {
+			if placements[i].ProjectSlug == slug {
+				placements[i].ProjectSlug = newSlug
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return util.SaveYAML(m.IndexLayoutPath(), layout)
+}
+
+// DuplicateProject copies a project, with its photos and layout but without the
+// layout history, to a new project. An empty title defaults to the original's
+// with " (copy)" appended, and an empty newSlug to the slug of the title, with
+// a numeric suffix if it is taken (see CreateProject). The
+// copy is hidden, so that it is not published before it was edited.
+func (m *Manager) DuplicateProject(slug, newSlug, title string) (*ProjectMetadata, error) {
+	source, err := m.GetProject(slug)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := m.GetLayout(slug)
+	if err != nil {
+		return nil, err
+	}
+	if title == "" {
+		title = source.Title + " (copy)"
+	}
+	// Creating the directories claims the slug, so that a project created at
+	// the same time cannot take it between the check and the copy
+	var claimErr error
+	if newSlug == "" {
+		base := Slugify(title)
+		if base == "" {
+			return nil, fmt.Errorf("invalid title: cannot create slug")
+		}
+		newSlug = uniqueSlug(base, func(candidate string) bool {
+			if ReservedSlug(candidate) {
+				return true
+			}
+			claimErr = m.claimProjectDirs(candidate)
+			return errors.Is(claimErr, ErrProjectExists)
+		})
+	} else if claimErr = checkSlug(newSlug); claimErr == nil {
+		claimErr = m.claimProjectDirs(newSlug)
+	}
+	if claimErr != nil {
+		return nil, claimErr
+	}
+
+	meta, err := m.writeDuplicate(source, layout, newSlug, title)
+	if err != nil {
+		// Do not leave a half-copied project behind; the directories are ours
+		m.DeleteProject(newSlug)
+		return nil, err
+	}
+	return meta, nil
+}
+
+// claimProjectDirs creates the empty project and photos directories of a new
+// project. It fails with ErrProjectExists if either already exists.
+func (m *Manager) claimProjectDirs(slug string) error {
+	for _, dir := range []string{m.ProjectsDir(), m.PhotosDir()} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+	if err := os.Mkdir(m.ProjectDir(slug), 0755); err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("%w: %s", ErrProjectExists, slug)
+		}
+		return fmt.Errorf("failed to create project directory: %w", err)
+	}
+	if err := os.Mkdir(m.ProjectPhotosDir(slug), 0755); err != nil {
+		os.Remove(m.ProjectDir(slug))
+		if os.IsExist(err) {
+			return fmt.Errorf("%w: %s", ErrProjectExists, slug)
+		}
+		return fmt.Errorf("failed to create photos directory: %w", err)
+	}
+	return nil
+}
+
+// writeDuplicate copies the photos and writes the files of a duplicated project
+// into its claimed directories
+func (m *Manager) writeDuplicate(source *ProjectMetadata, layout *LayoutConfig, newSlug, title string) (*ProjectMetadata, error) {
+	if _, err := os.Stat(m.ProjectPhotosDir(source.Slug)); err == nil {
+		if err := util.CopyDir(m.ProjectPhotosDir(source.Slug), m.ProjectPhotosDir(newSlug)); err != nil {
+			return nil, fmt.Errorf("failed to copy photos: %w", err)
+		}
+	}
+
+	now := time.Now()
+	meta := *source
+	meta.Title = title
+	meta.Slug = newSlug
+	meta.Hidden = true
+	meta.CreatedAt = now
+	meta.UpdatedAt = now
+	if err := util.SaveYAML(m.ProjectMetaPath(newSlug), &meta); err != nil {
+		return nil, fmt.Errorf("failed to save metadata: %w", err)
+	}
+	if err := util.SaveYAML(m.ProjectLayoutPath(newSlug), layout); err != nil {
+		return nil, fmt.Errorf("failed to save layout: %w", err)
+	}
+	return &meta, nil
+}
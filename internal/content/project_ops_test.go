@@ -0,0 +1,184 @@
+package content
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// TestRenameProject checks that a rename moves the project and updates every reference to it
+func TestRenameProject(t *testing.T) {
+	m := NewManager(t.TempDir())
+	project, photo := setupPhotoProject(t, m, "Old Name")
+	if _, err := m.CreateProject("Other", ""); err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	if err := m.SaveSiteMeta(&SiteMetadata{
+		Projects:  []ProjectOrder{{Slug: "other", Order: 1}, {Slug: project.Slug, Order: 2}},
+		Redirects: []Redirect{{From: "older-name", To: project.Slug}, {From: "new-name", To: "other"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SaveIndexLayout(&IndexLayoutConfig{GridWidth: 12, Placements: []IndexHeroPlacement{
+		{ProjectSlug: project.Slug, Position: GridPosition{TopLeftX: 1, TopLeftY: 1, BottomRightX: 6, BottomRightY: 3}},
+		{ProjectSlug: "other", Position: GridPosition{TopLeftX: 7, TopLeftY: 1, BottomRightX: 12, BottomRightY: 3}},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	renamed, err := m.RenameProject(project.Slug, "new-name")
+	if err != nil {
+		t.Fatalf("RenameProject failed: %v", err)
+	}
+	if renamed.Slug != "new-name" || renamed.Title != "Old Name" {
+		t.Errorf("Expected the new slug and the same title, got %+v", renamed)
+	}
+	if _, err := m.GetProject(project.Slug); err == nil {
+		t.Error("Expected the old slug to be gone")
+	}
+	if _, err := m.GetPhoto("new-name", photo.HashID); err != nil {
+		t.Errorf("Expected the photo to move along: %v", err)
+	}
+	if layout, err := m.GetLayout("new-name"); err != nil || len(layout.Placements) != 1 {
+		t.Errorf("Expected the layout to move along, got %+v (%v)", layout, err)
+	}
+
+	site, err := m.LoadSiteMeta()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if site.Projects[1].Slug != "new-name" {
+		t.Errorf("Expected the project order to follow the rename, got %+v", site.Projects)
+	}
+	want := []Redirect{{From: "older-name", To: "new-name"}, {From: "old-name", To: "new-name"}}
+	if len(site.Redirects) != len(want) || site.Redirects[0] != want[0] || site.Redirects[1] != want[1] {
+		t.Errorf("Expected redirects %+v, got %+v", want, site.Redirects)
+	}
+
+	index, err := m.GetIndexLayout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Placements[0].ProjectSlug != "new-name" || index.Placements[1].ProjectSlug != "other" {
+		t.Errorf("Expected the index hero to follow the rename, got %+v", index.Placements)
+	}
+
+	if _, err := m.RenameProject("new-name", "other"); !errors.Is(err, ErrProjectExists) {
+		t.Errorf("Expected ErrProjectExists, got %v", err)
+	}
+	if _, err := m.RenameProject("new-name", "Not A Slug"); err == nil {
+		t.Error("Expected an error for an invalid slug")
+	}
+}
+
+// TestRenameProjectRollback checks that a rename failing halfway leaves the
+// project and site.yaml as they were
+func TestRenameProjectRollback(t *testing.T) {
+	m := NewManager(t.TempDir())
+	project, photo := setupPhotoProject(t, m, "Old Name")
+	site := &SiteMetadata{Projects: []ProjectOrder{{Slug: project.Slug, Order: 1}}}
+	if err := m.SaveSiteMeta(site); err != nil {
+		t.Fatal(err)
+	}
+	// An unreadable index layout makes the last step fail
+	if err := os.MkdirAll(m.IndexLayoutPath(), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.RenameProject(project.Slug, "new-name"); err == nil {
+		t.Fatal("Expected the rename to fail")
+	}
+	if restored, err := m.GetProject(project.Slug); err != nil || restored.Slug != project.Slug {
+		t.Errorf("Expected the project back at %s, got %+v (%v)", project.Slug, restored, err)
+	}
+	if _, err := m.GetPhoto(project.Slug, photo.HashID); err != nil {
+		t.Errorf("Expected the photo back: %v", err)
+	}
+	if m.slugTaken("new-name") {
+		t.Error("Expected nothing left at the new slug")
+	}
+	if loaded, err := m.LoadSiteMeta(); err != nil || !reflect.DeepEqual(loaded.Projects, site.Projects) || len(loaded.Redirects) != 0 {
+		t.Errorf("Expected site.yaml unchanged, got %+v (%v)", loaded, err)
+	}
+}
+
+// TestDuplicateProject checks that a duplicate gets its own copy of the photos and layout
+func TestDuplicateProject(t *testing.T) {
+	m := NewManager(t.TempDir())
+	project, photo := setupPhotoProject(t, m, "Original")
+
+	copied, err := m.DuplicateProject(project.Slug, "", "")
+	if err != nil {
+		t.Fatalf("DuplicateProject failed: %v", err)
+	}
+	if copied.Slug != "original-copy" || copied.Title != "Original (copy)" || !copied.Hidden {
+		t.Errorf("Expected a hidden copy, got %+v", copied)
+	}
+	if copied.HeroPhoto != photo.HashID {
+		t.Errorf("Expected the hero photo to be kept, got %q", copied.HeroPhoto)
+	}
+	if _, err := m.GetPhoto(copied.Slug, photo.HashID); err != nil {
+		t.Errorf("Expected the photo to be copied: %v", err)
+	}
+	if _, err := m.GetPhoto(project.Slug, photo.HashID); err != nil {
+		t.Errorf("Expected the original photo to stay: %v", err)
+	}
+	layout, err := m.GetLayout(copied.Slug)
+	if err != nil || len(layout.Placements) != 1 || layout.FocalPointFor(photo.HashID) == nil {
+		t.Errorf("Expected the layout to be copied, got %+v (%v)", layout, err)
+	}
+	if revisions, _ := m.ListLayoutRevisions(copied.Slug); len(revisions) != 0 {
+		t.Errorf("Expected no layout history for the copy, got %d revisions", len(revisions))
+	}
+
+	if second, err := m.DuplicateProject(project.Slug, "", ""); err != nil || second.Slug != "original-copy-2" {
+		t.Errorf("Expected a suffix for a second copy, got %+v (%v)", second, err)
+	}
+	if _, err := m.DuplicateProject(project.Slug, "original-copy", ""); !errors.Is(err, ErrProjectExists) {
+		t.Errorf("Expected ErrProjectExists for a taken custom slug, got %v", err)
+	}
+}
+
+// TestDuplicateProjectConcurrent checks that copies requested at the same time
+// under one slug do not overwrite each other: one wins, the others fail
+func TestDuplicateProjectConcurrent(t *testing.T) {
+	m := NewManager(t.TempDir())
+	project, _ := setupPhotoProject(t, m, "Original")
+
+	const n = 8
+	var wg sync.WaitGroup
+	copies := make([]*ProjectMetadata, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			copies[i], errs[i] = m.DuplicateProject(project.Slug, "same", fmt.Sprintf("Copy %d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	var winner *ProjectMetadata
+	for i, err := range errs {
+		switch {
+		case err == nil && winner == nil:
+			winner = copies[i]
+		case err == nil:
+			t.Errorf("Expected one copy to get the slug, %s and %s both did", winner.Title, copies[i].Title)
+		case !errors.Is(err, ErrProjectExists):
+			t.Errorf("Expected ErrProjectExists, got %v", err)
+		}
+	}
+	if winner == nil {
+		t.Fatal("Expected one copy to succeed")
+	}
+	if meta, err := m.GetProject("same"); err != nil || meta.Title != winner.Title {
+		t.Errorf("Expected the winning copy %q to be kept, got %+v (%v)", winner.Title, meta, err)
+	}
+	if photos, _ := m.ListPhotos("same"); len(photos) != 1 {
+		t.Errorf("Expected the copied photo to be kept, got %d photos", len(photos))
+	}
+}
@@ -0,0 +1,39 @@
+package content
+
+import "testing"
+
+// TestUpdateProjectMetaValidation checks that invalid dates and themes are rejected
+func TestUpdateProjectMetaValidation(t *testing.T) {
+	m := NewManager(t.TempDir())
+	project, err := m.CreateProject("Validated", "")
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+
+	gap := 500
+	for name, update := range map[string]func(*ProjectMetadata){
+		"date":       func(p *ProjectMetadata) { p.Date = "May 2024" },
+		"position":   func(p *ProjectMetadata) { p.BodyPosition = "left" },
+		"background": func(p *ProjectMetadata) { p.Theme = &ProjectTheme{Background: "red; } body { display: none"} },
+		"gap":        func(p *ProjectMetadata) { p.Theme = &ProjectTheme{GridGap: &gap} },
+	} {
+		if err := m.UpdateProjectMeta(project.Slug, func(p *ProjectMetadata) error { update(p); return nil }); err == nil {
+			t.Errorf("Expected an error for an invalid %s", name)
+		}
+	}
+
+	if err := m.UpdateProjectMeta(project.Slug, func(p *ProjectMetadata) error {
+		p.Date = "2024-05-12"
+		p.Theme = &ProjectTheme{Background: "#fafafa"}
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateProjectMeta failed: %v", err)
+	}
+	saved, err := m.GetProject(project.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.DisplayDate() != "12 May 2024" || saved.Theme.Background != "#fafafa" {
+		t.Errorf("Expected the date and theme to be saved, got %+v", saved)
+	}
+}
@@ -0,0 +1,100 @@
+package content
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// slugHashLength is the length of the hash used as slug for titles without
+// any letters or digits that transliterate to ASCII (e.g. Japanese titles)
+const slugHashLength = 8
+
+// Output directories of the tag and category listing pages
+const (
+	TagsPath     = "tags"
+	CategoryPath = "category"
+)
+
+// reservedSlugs are the output directories the generator writes next to the
+// project pages, which a project with the same slug would overwrite
+var reservedSlugs = map[string]bool{"about": true, "static": true, "images": true, "favicon": true, TagsPath: true, CategoryPath: true}
+
+// ReservedSlug reports whether slug names an output directory of the site
+// itself, and so cannot be a project's slug
+func ReservedSlug(slug string) bool {
+	return reservedSlugs[slug]
+}
+
+// transliterations maps letters that do not decompose into an ASCII letter and
+// accents to their usual ASCII spelling
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d",
+	'ł': "l", 'þ': "th", 'ı': "i", 'ħ': "h", 'ŧ': "t", 'ŋ': "ng",
+}
+
+// Slugify converts a title to a URL-friendly slug of lowercase ASCII letters,
+// digits and single hyphens. Accented letters lose their accents ("Città" gives
+// "citta"), apostrophes are dropped and other characters separate words. Titles
+// with nothing left (e.g. in a non-Latin script) get a short hash of the title.
+func Slugify(title string) string {
+	var result strings.Builder
+	hyphen := false
+	for _, r := range norm.NFKD.String(strings.ToLower(title)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue // Accent split off by the decomposition
+		}
+		text, ok := transliterations[r]
+		switch {
+		case ok:
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+			text = string(r)
+		case r == '\'' || r == '’':
+			continue
+		default:
+			hyphen = result.Len() > 0
+			continue
+		}
+		if hyphen {
+			result.WriteByte('-')
+			hyphen = false
+		}
+		result.WriteString(text)
+	}
+	if result.Len() == 0 && strings.TrimSpace(title) != "" {
+		sum := sha256.Sum256([]byte(strings.TrimSpace(title)))
+		return hex.EncodeToString(sum[:])[:slugHashLength]
+	}
+	return result.String()
+}
+
+// ValidSlug reports whether slug can be used as a project slug as it is
+func ValidSlug(slug string) bool {
+	return slug != "" && Slugify(slug) == slug && !ReservedSlug(slug)
+}
+
+// checkSlug returns an error explaining why slug cannot be used as a project slug
+func checkSlug(slug string) error {
+	if ReservedSlug(slug) {
+		return fmt.Errorf("invalid slug %q: it is used by the site's own pages", slug)
+	}
+	if !ValidSlug(slug) {
+		return fmt.Errorf("invalid slug %q: use lowercase letters, digits and hyphens", slug)
+	}
+	return nil
+}
+
+// uniqueSlug returns slug, or slug with the first numeric suffix ("-2", "-3",
+// ...) for which taken returns false
+func uniqueSlug(slug string, taken func(slug string) bool) string {
+	candidate := slug
+	for n := 2; taken(candidate); n++ {
+		candidate = slug + "-" + strconv.Itoa(n)
+	}
+	return candidate
+}
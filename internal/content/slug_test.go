@@ -0,0 +1,80 @@
+package content
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestSlugify checks transliteration, hyphen collapsing and the hash fallback
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Città di Notte":       "citta-di-notte",
+		"Straße & Größe":       "strasse-grosse",
+		"  Rock -- n' Roll!  ": "rock-n-roll",
+		"Łódź, Ærø":            "lodz-aero",
+		"Original (copy)":      "original-copy",
+		"L’été 2024":           "lete-2024",
+		"already-a-slug":       "already-a-slug",
+		"":                     "",
+	}
+	for title, want := range tests {
+		if got := Slugify(title); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", title, got, want)
+		}
+	}
+
+	// Titles without any ASCII letters or digits get a short stable hash
+	tokyo, night := Slugify("東京の夜"), Slugify("Ночь")
+	if len(tokyo) != slugHashLength || tokyo == night || !ValidSlug(tokyo) {
+		t.Errorf("Expected distinct valid hash slugs, got %q and %q", tokyo, night)
+	}
+}
+
+// TestCreateProjectSlugs checks collision suffixes and custom slugs
+func TestCreateProjectSlugs(t *testing.T) {
+	m := NewManager(t.TempDir())
+	for _, want := range []string{"citta-di-notte", "citta-di-notte-2", "citta-di-notte-3"} {
+		project, err := m.CreateProject("Città di Notte", "")
+		if err != nil {
+			t.Fatalf("CreateProject failed: %v", err)
+		}
+		if project.Slug != want {
+			t.Errorf("Expected slug %s, got %s", want, project.Slug)
+		}
+	}
+
+	project, err := m.CreateProjectWithSlug("東京の夜", "", "tokyo-nights")
+	if err != nil || project.Slug != "tokyo-nights" {
+		t.Fatalf("Expected the custom slug, got %+v (%v)", project, err)
+	}
+	if _, err := m.CreateProjectWithSlug("Again", "", "tokyo-nights"); !errors.Is(err, ErrProjectExists) {
+		t.Errorf("Expected ErrProjectExists for a taken custom slug, got %v", err)
+	}
+	if _, err := m.CreateProjectWithSlug("Bad", "", "Not/A Slug"); err == nil {
+		t.Error("Expected an error for an invalid custom slug")
+	}
+}
+
+// TestReservedSlugs checks that slugs of the site's own pages are refused or suffixed
+func TestReservedSlugs(t *testing.T) {
+	m := NewManager(t.TempDir())
+	for _, slug := range []string{"images", "static", "about", "favicon", TagsPath, CategoryPath} {
+		if ValidSlug(slug) {
+			t.Errorf("Expected %s to be reserved", slug)
+		}
+		if _, err := m.CreateProjectWithSlug("Custom", "", slug); err == nil {
+			t.Errorf("Expected an error for the custom slug %s", slug)
+		}
+	}
+
+	project, err := m.CreateProject("Images", "")
+	if err != nil || project.Slug != "images-2" {
+		t.Errorf("Expected the derived slug to be suffixed, got %+v (%v)", project, err)
+	}
+	if _, err := m.RenameProject(project.Slug, "tags"); err == nil {
+		t.Error("Expected an error for renaming to a reserved slug")
+	}
+	if copied, err := m.DuplicateProject(project.Slug, "", "About"); err != nil || copied.Slug != "about-2" {
+		t.Errorf("Expected the duplicate's slug to be suffixed, got %+v (%v)", copied, err)
+	}
+}
@@ -0,0 +1,51 @@
+package content
+
+import (
+	"sort"
+	"strings"
+)
+
+// NormalizeTags splits comma-separated tags, trims them and drops empty ones and
+// those whose slug repeats an earlier tag (e.g. "Film" after "film")
+func NormalizeTags(tags []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, value := range tags {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "" || seen[Slugify(tag)] {
+				continue
+			}
+			seen[Slugify(tag)] = true
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// ListTerms returns the categories and tags used by any project, sorted, e.g. to
+// suggest them while editing a project
+func (m *Manager) ListTerms() (categories, tags []string, err error) {
+	projects, err := m.ListProjects()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var allCategories, allTags []string
+	for _, p := range projects {
+		if p.Category != "" {
+			allCategories = append(allCategories, p.Category)
+		}
+		allTags = append(allTags, p.Tags...)
+	}
+	return sortedTerms(allCategories), sortedTerms(allTags), nil
+}
+
+// sortedTerms returns terms without duplicates, sorted case-insensitively
+func sortedTerms(terms []string) []string {
+	terms = NormalizeTags(terms)
+	sort.Slice(terms, func(i, j int) bool {
+		return strings.ToLower(terms[i]) < strings.ToLower(terms[j])
+	})
+	return terms
+}
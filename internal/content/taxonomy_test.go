@@ -0,0 +1,48 @@
+package content
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestNormalizeTags checks that tags are split, trimmed and deduplicated by slug
+func TestNormalizeTags(t *testing.T) {
+	got := NormalizeTags([]string{"Film, portrait ", "", "film", "Città,città", " , "})
+	want := []string{"Film", "portrait", "Città"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeTags = %q, want %q", got, want)
+	}
+}
+
+// TestListTerms checks that categories and tags are collected from all projects
+func TestListTerms(t *testing.T) {
+	m := NewManager(t.TempDir())
+	for title, meta := range map[string]ProjectMetadata{
+		"Acme":   {Category: "Commercial", Tags: []string{"studio", "Portrait"}},
+		"Vogue":  {Category: "Editorial", Tags: []string{"portrait"}},
+		"Travel": {Category: "Personal"},
+		"Misc":   {},
+	} {
+		project, err := m.CreateProject(title, "")
+		if err != nil {
+			t.Fatalf("CreateProject failed: %v", err)
+		}
+		if err := m.UpdateProjectMeta(project.Slug, func(p *ProjectMetadata) error {
+			p.Category, p.Tags = meta.Category, meta.Tags
+			return nil
+		}); err != nil {
+			t.Fatalf("UpdateProjectMeta failed: %v", err)
+		}
+	}
+
+	categories, tags, err := m.ListTerms()
+	if err != nil {
+		t.Fatalf("ListTerms failed: %v", err)
+	}
+	if want := []string{"Commercial", "Editorial", "Personal"}; !reflect.DeepEqual(categories, want) {
+		t.Errorf("categories = %q, want %q", categories, want)
+	}
+	if len(tags) != 2 || tags[1] != "studio" {
+		t.Errorf("Expected the portrait and studio tags, got %q", tags)
+	}
+}
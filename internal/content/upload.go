@@ -0,0 +1,152 @@
+package content
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsupportedFormat is returned when an uploaded file is not a supported image
+var ErrUnsupportedFormat = errors.New("unsupported image format (JPEG and PNG are supported)")
+
+// uploadFormats maps the accepted file extensions to their decoded image format
+var uploadFormats = map[string]string{
+	".jpg":  "jpeg",
+	".jpeg": "jpeg",
+	".png":  "png",
+}
+
+// AddPhoto stores an uploaded photo in the project's photos directory. The content
+// is validated as a JPEG or PNG image. If a photo with the same content already
+// exists, the upload is discarded and the existing photo is returned with
+// duplicate set to true. Name clashes with different content get a numeric suffix.
+func (m *Manager) AddPhoto(slug, filename string, r io.Reader) (photo *PhotoInfo, duplicate bool, err error) {
+	name := sanitizePhotoFilename(filename)
+	ext := strings.ToLower(filepath.Ext(name))
+	if _, ok := uploadFormats[ext]; !ok {
+		return nil, false, ErrUnsupportedFormat
+	}
+
+	photosDir := m.ProjectPhotosDir(slug)
+	if err := os.MkdirAll(photosDir, 0755); err != nil {
+		return nil, false, fmt.Errorf("failed to create photos directory: %w", err)
+	}
+
+	// Write to a hidden temp file (ignored by ListPhotos) while hashing
+	tmp, err := os.CreateTemp(photosDir, ".upload-*")
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // No-op once renamed
+
+	hash := sha256.New()
+	size, err := io.Copy(tmp, io.TeeReader(r, hash))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to write upload: %w", err)
+	}
+	hashID := hex.EncodeToString(hash.Sum(nil))[:12]
+
+	if err := validateUpload(tmpPath, ext); err != nil {
+		return nil, false, err
+	}
+
+	// Dedupe by content hash
+	existing, err := m.ListPhotos(slug)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, p := range existing {
+		if p.HashID == hashID {
+			return p, true, nil
+		}
+	}
+
+	if err := os.Chmod(tmpPath, 0644); err != nil { // CreateTemp uses 0600
+		return nil, false, fmt.Errorf("failed to set photo permissions: %w", err)
+	}
+	destPath, err := claimPhotoPath(photosDir, name)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		os.Remove(destPath)
+		return nil, false, fmt.Errorf("failed to store photo: %w", err)
+	}
+
+	photo, err = newPhotoInfo(slug, destPath, size)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read stored photo: %w", err)
+	}
+	return photo, false, nil
+}
+
+// validateUpload checks that the file decodes as the format its extension claims
+func validateUpload(path, ext string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	cfg, format, err := image.DecodeConfig(file)
+	if err != nil || format != uploadFormats[ext] {
+		return ErrUnsupportedFormat
+	}
+	if cfg.Width == 0 || cfg.Height == 0 {
+		return fmt.Errorf("invalid image dimensions")
+	}
+	return nil
+}
+
+// sanitizePhotoFilename strips directories and characters that are unsafe in filenames
+func sanitizePhotoFilename(filename string) string {
+	name := filepath.Base(strings.ReplaceAll(filename, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	ext := filepath.Ext(name)
+	base := strings.TrimLeft(strings.TrimSuffix(name, ext), ".") // No hidden files
+	if base == "" {
+		base = "photo"
+	}
+	return base + ext
+}
+
+// claimPhotoPath reserves a path for name in dir by creating an empty file there,
+// adding "-2", "-3", ... before the extension on clashes. The file is created
+// exclusively, so concurrent requests never get the same path; the caller renames
+// the photo over it.
+func claimPhotoPath(dir, name string) (string, error) {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; i < 1000; i++ {
+		candidate := name
+		if i > 1 {
+			candidate = fmt.Sprintf("%s-%d%s", base, i, ext)
+		}
+		path := filepath.Join(dir, candidate)
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			file.Close()
+			return path, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", fmt.Errorf("failed to create photo file: %w", err)
+		}
+	}
+	return "", fmt.Errorf("too many photos named %s", name)
+}
@@ -0,0 +1,133 @@
+package content
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestSanitizePhotoFilename checks that uploaded names cannot escape the photos directory
+func TestSanitizePhotoFilename(t *testing.T) {
+	tests := map[string]string{
+		"photo.jpg":              "photo.jpg",
+		"../../etc/passwd.jpg":   "passwd.jpg",
+		`C:\Users\me\Beach.JPG`:  "Beach.JPG",
+		"a:b*c?.png":             "a_b_c_.png",
+		".hidden.jpg":            "hidden.jpg",
+		".jpg":                   "photo.jpg",
+		"tab\there.jpg":          "tab_here.jpg",
+		"Città di Notte 01.jpeg": "Città di Notte 01.jpeg",
+	}
+	for in, want := range tests {
+		if got := sanitizePhotoFilename(in); got != want {
+			t.Errorf("sanitizePhotoFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// TestClaimPhotoPath checks name suffixes and that concurrent claims never share a path
+func TestClaimPhotoPath(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "photo.jpg"), []byte("taken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	paths := make(chan string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path, err := claimPhotoPath(dir, "photo.jpg")
+			if err != nil {
+				t.Errorf("claimPhotoPath failed: %v", err)
+				return
+			}
+			paths <- path
+		}()
+	}
+	wg.Wait()
+	close(paths)
+
+	seen := make(map[string]bool)
+	for path := range paths {
+		if seen[path] {
+			t.Errorf("Path %s claimed twice", path)
+		}
+		seen[path] = true
+	}
+	if seen[filepath.Join(dir, "photo.jpg")] || !seen[filepath.Join(dir, "photo-2.jpg")] {
+		t.Errorf("Expected suffixed names next to the existing photo, got %v", seen)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "photo.jpg")); string(data) != "taken" {
+		t.Error("Expected the existing photo to be left untouched")
+	}
+}
+
+// TestAddPhoto checks storing, name clashes, duplicates and rejected formats
+func TestAddPhoto(t *testing.T) {
+	m := NewManager(t.TempDir())
+	project, err := m.CreateProject("Uploads", "")
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+
+	black := encodeJPEG(t, color.Black)
+	first, duplicate, err := m.AddPhoto(project.Slug, "../beach.jpg", bytes.NewReader(black))
+	if err != nil || duplicate {
+		t.Fatalf("AddPhoto failed: %v (duplicate %v)", err, duplicate)
+	}
+	if first.Filename != "beach.jpg" || filepath.Dir(first.Path) != m.ProjectPhotosDir(project.Slug) {
+		t.Errorf("Expected beach.jpg in the photos directory, got %s", first.Path)
+	}
+
+	// Same name, different content
+	second, _, err := m.AddPhoto(project.Slug, "beach.jpg", bytes.NewReader(encodeJPEG(t, color.White)))
+	if err != nil {
+		t.Fatalf("AddPhoto failed: %v", err)
+	}
+	if second.Filename != "beach-2.jpg" {
+		t.Errorf("Expected a suffixed name for a clash, got %s", second.Filename)
+	}
+
+	// Same content, any name
+	again, duplicate, err := m.AddPhoto(project.Slug, "copy.jpg", bytes.NewReader(black))
+	if err != nil {
+		t.Fatalf("AddPhoto failed: %v", err)
+	}
+	if !duplicate || again.HashID != first.HashID {
+		t.Errorf("Expected the existing photo for a duplicate, got %+v", again)
+	}
+	if _, err := os.Stat(filepath.Join(m.ProjectPhotosDir(project.Slug), "copy.jpg")); !os.IsNotExist(err) {
+		t.Error("Expected the duplicate not to be stored")
+	}
+
+	for name, data := range map[string][]byte{
+		"notes.txt":    []byte("hello"),
+		"fake.jpg":     []byte("not an image"),
+		"mislabel.png": black,
+	} {
+		if _, _, err := m.AddPhoto(project.Slug, name, bytes.NewReader(data)); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("Expected ErrUnsupportedFormat for %s, got %v", name, err)
+		}
+	}
+
+	photos, err := m.ListPhotos(project.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(m.ProjectPhotosDir(project.Slug))
+	for _, entry := range entries {
+		if !entry.IsDir() && entry.Name() != "beach.jpg" && entry.Name() != "beach-2.jpg" {
+			t.Errorf("Unexpected file left in the photos directory: %s", entry.Name())
+		}
+	}
+	if len(photos) != 2 {
+		t.Errorf("Expected 2 photos, got %d", len(photos))
+	}
+}
@@ -0,0 +1,289 @@
+package content
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// MaxGridWidth is the largest number of columns a layout grid may have
+const MaxGridWidth = 24
+
+// DefaultMobileGridWidth is used when a layout does not set a mobile grid width
+const DefaultMobileGridWidth = 6
+
+// Layout validation error codes
+const (
+	CodeInvalidGridWidth  = "invalid_grid_width"
+	CodeInvertedPosition  = "inverted_position"
+	CodeOutOfBounds       = "out_of_bounds"
+	CodeOverlap           = "overlap"
+	CodeUnknownPhoto      = "unknown_photo"
+	CodeUnknownProject    = "unknown_project"
+	CodeInvalidFocalPoint = "invalid_focal_point"
+	CodeInvalidMobileCrop = "invalid_mobile_crop"
+	CodeInvalidBlock      = "invalid_block"
+)
+
+// GridCell is a 1-based grid cell
+type GridCell struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// LayoutError describes one problem found in a layout
+type LayoutError struct {
+	Code      string    `json:"code"`
+	View      string    `json:"view,omitempty"`  // "desktop" or "mobile"
+	Placement int       `json:"placement"`       // Index in the view's placements, -1 for layout-wide problems
+	Ref       string    `json:"ref,omitempty"`   // Hash ID of the photo or block ID, or slug of the project on the index page
+	Cell      *GridCell `json:"cell,omitempty"`  // First overlapping cell
+	Other     *int      `json:"other,omitempty"` // Index of the placement overlapped
+	Message   string    `json:"message"`
+}
+
+func (e *LayoutError) Error() string {
+	return e.Message
+}
+
+// LayoutErrors lists every problem found in a layout
+type LayoutErrors []*LayoutError
+
+func (errs LayoutErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Err returns errs as an error, or nil when there are none
+func (errs LayoutErrors) Err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// gridItem is a placement on a grid, whatever it places
+type gridItem struct {
+	ref      string
+	position GridPosition
+}
+
+// ValidateLayout checks a project layout: grid widths, placements within the
+// grid and not overlapping on desktop and mobile, blocks, focal points and the
+// mobile crop. When photoIDs is not nil, placements must also reference one of
+// its hash IDs or a block.
+func ValidateLayout(layout *LayoutConfig, photoIDs map[string]bool) LayoutErrors {
+	items := func(placements []PhotoPlacement) []gridItem {
+		result := make([]gridItem, len(placements))
+		for i, p := range placements {
+			result[i] = gridItem{ref: p.Filename, position: p.Position}
+		}
+		return result
+	}
+
+	errs := validateGrids(layout.GridWidth, items(layout.Placements), layout.MobileGridWidth, items(layout.MobilePlacements))
+
+	if photoIDs != nil {
+		for _, view := range []struct {
+			name       string
+			placements []PhotoPlacement
+		}{{"desktop", layout.Placements}, {"mobile", layout.MobilePlacements}} {
+			for i, p := range view.placements {
+				if _, ok := layout.Blocks[p.Filename]; !ok && !photoIDs[p.Filename] {
+					errs = append(errs, &LayoutError{
+						Code: CodeUnknownPhoto, View: view.name, Placement: i, Ref: p.Filename,
+						Message: fmt.Sprintf("%s %d references unknown photo %s", placementKind(view.name), i, p.Filename),
+					})
+				}
+			}
+		}
+	}
+
+	// Sorted, so that errors come in the same order on every run
+	for _, id := range sortedKeys(layout.Blocks) {
+		block := layout.Blocks[id]
+		if err := block.Validate(); err != nil {
+			errs = append(errs, &LayoutError{
+				Code: CodeInvalidBlock, Placement: -1, Ref: id,
+				Message: fmt.Sprintf("block %s: %v", id, err),
+			})
+		}
+	}
+	for _, hashID := range sortedKeys(layout.Photos) {
+		if fp := layout.Photos[hashID].FocalPoint; fp != nil && (fp.X < 0 || fp.X > 1 || fp.Y < 0 || fp.Y > 1) {
+			errs = append(errs, &LayoutError{
+				Code: CodeInvalidFocalPoint, Placement: -1, Ref: hashID,
+				Message: fmt.Sprintf("focal point for %s must be between 0 and 1", hashID),
+			})
+		}
+	}
+	if layout.MobileCrop != "" {
+		if _, _, err := ParseRatio(layout.MobileCrop); err != nil {
+			errs = append(errs, &LayoutError{
+				Code: CodeInvalidMobileCrop, Placement: -1,
+				Message: fmt.Sprintf("invalid mobile crop: %v", err),
+			})
+		}
+	}
+	return errs
+}
+
+// ValidateIndexLayout checks the home page hero grid like ValidateLayout. When
+// heroes is not nil, placements must reference one of its project slugs.
+func ValidateIndexLayout(layout *IndexLayoutConfig, heroes map[string]bool) LayoutErrors {
+	items := func(placements []IndexHeroPlacement) []gridItem {
+		result := make([]gridItem, len(placements))
+		for i, p := range placements {
+			result[i] = gridItem{ref: p.ProjectSlug, position: p.Position}
+		}
+		return result
+	}
+
+	errs := validateGrids(layout.GridWidth, items(layout.Placements), layout.MobileGridWidth, items(layout.MobilePlacements))
+
+	if heroes != nil {
+		for _, view := range []struct {
+			name       string
+			placements []IndexHeroPlacement
+		}{{"desktop", layout.Placements}, {"mobile", layout.MobilePlacements}} {
+			for i, p := range view.placements {
+				if !heroes[p.ProjectSlug] {
+					errs = append(errs, &LayoutError{
+						Code: CodeUnknownProject, View: view.name, Placement: i, Ref: p.ProjectSlug,
+						Message: fmt.Sprintf("%s %d references project %s, which does not exist or has no hero photo", placementKind(view.name), i, p.ProjectSlug),
+					})
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// ValidateProjectLayout validates a project's saved layout. Photo references
+// are checked when the project's photos directory exists.
+func (m *Manager) ValidateProjectLayout(slug string) (LayoutErrors, error) {
+	layout, err := m.GetLayout(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	var photoIDs map[string]bool
+	if _, err := os.Stat(m.ProjectPhotosDir(slug)); err == nil {
+		photos, err := m.ListPhotos(slug)
+		if err != nil {
+			return nil, err
+		}
+		photoIDs = PhotoIDs(photos)
+	}
+	return ValidateLayout(layout, photoIDs), nil
+}
+
+// PhotoIDs returns the set of hash IDs of photos
+func PhotoIDs(photos []*PhotoInfo) map[string]bool {
+	ids := make(map[string]bool, len(photos))
+	for _, photo := range photos {
+		ids[photo.HashID] = true
+	}
+	return ids
+}
+
+// validateGrids checks the desktop and mobile grids of a layout
+func validateGrids(gridWidth int, placements []gridItem, mobileGridWidth int, mobilePlacements []gridItem) LayoutErrors {
+	var errs LayoutErrors
+	if gridWidth < 1 || gridWidth > MaxGridWidth {
+		errs = append(errs, &LayoutError{
+			Code: CodeInvalidGridWidth, View: "desktop", Placement: -1,
+			Message: fmt.Sprintf("grid width must be between 1 and %d", MaxGridWidth),
+		})
+	} else {
+		errs = append(errs, validateGrid("desktop", gridWidth, placements)...)
+	}
+
+	if mobileGridWidth == 0 {
+		mobileGridWidth = DefaultMobileGridWidth
+	}
+	if mobileGridWidth < 1 || mobileGridWidth > MaxGridWidth {
+		errs = append(errs, &LayoutError{
+			Code: CodeInvalidGridWidth, View: "mobile", Placement: -1,
+			Message: fmt.Sprintf("mobile grid width must be between 1 and %d", MaxGridWidth),
+		})
+	} else {
+		errs = append(errs, validateGrid("mobile", mobileGridWidth, mobilePlacements)...)
+	}
+	return errs
+}
+
+// validateGrid checks that placements are within the grid width (grids grow
+// downwards, so there is no height limit) and that no two placements overlap.
+// Positions are 1-based and inclusive. Overlaps are found by comparing
+// rectangles, so the work does not grow with placement sizes.
+func validateGrid(view string, width int, placements []gridItem) LayoutErrors {
+	var errs LayoutErrors
+	kind := placementKind(view)
+	var placed []int // Indices of the placements checked for overlaps so far
+
+	for i, p := range placements {
+		pos := p.position
+		fail := func(code, message string) {
+			errs = append(errs, &LayoutError{Code: code, View: view, Placement: i, Ref: p.ref, Message: message})
+		}
+
+		if pos.TopLeftX < 1 || pos.TopLeftY < 1 {
+			fail(CodeOutOfBounds, fmt.Sprintf("%s %d (%s) has top-left coordinates less than 1", kind, i, p.ref))
+			continue
+		}
+		if pos.BottomRightX < pos.TopLeftX || pos.BottomRightY < pos.TopLeftY {
+			fail(CodeInvertedPosition, fmt.Sprintf("%s %d (%s) has bottom-right before top-left", kind, i, p.ref))
+			continue
+		}
+		if pos.BottomRightX > width {
+			fail(CodeOutOfBounds, fmt.Sprintf("%s %d (%s) extends beyond %s width (%d): bottom_right_x=%d", kind, i, p.ref, gridName(view), width, pos.BottomRightX))
+			continue
+		}
+
+		// Report each overlapping placement once, at the first shared cell
+		for _, other := range placed {
+			o := placements[other].position
+			cell := GridCell{X: max(pos.TopLeftX, o.TopLeftX), Y: max(pos.TopLeftY, o.TopLeftY)}
+			if cell.X > min(pos.BottomRightX, o.BottomRightX) || cell.Y > min(pos.BottomRightY, o.BottomRightY) {
+				continue
+			}
+			errs = append(errs, &LayoutError{
+				Code: CodeOverlap, View: view, Placement: i, Ref: p.ref, Cell: &cell, Other: &other,
+				Message: fmt.Sprintf("%s %d (%s) overlaps with %s %d (%s) at cell %d,%d", kind, i, p.ref, kind, other, placements[other].ref, cell.X, cell.Y),
+			})
+		}
+		placed = append(placed, i)
+	}
+	return errs
+}
+
+// placementKind names placements of a view in error messages
+func placementKind(view string) string {
+	if view == "mobile" {
+		return "mobile placement"
+	}
+	return "placement"
+}
+
+// gridName names the grid of a view in error messages
+func gridName(view string) string {
+	if view == "mobile" {
+		return "mobile grid"
+	}
+	return "grid"
+}
+
+// sortedKeys returns the keys of a map keyed by block or photo ID, sorted
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
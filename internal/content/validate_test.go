@@ -0,0 +1,130 @@
+package content
+
+import "testing"
+
+// TestValidateLayout checks that every problem is reported with its placement and code
+func TestValidateLayout(t *testing.T) {
+	at := func(x1, y1, x2, y2 int) GridPosition {
+		return GridPosition{TopLeftX: x1, TopLeftY: y1, BottomRightX: x2, BottomRightY: y2}
+	}
+	layout := &LayoutConfig{
+		GridWidth: 12,
+		Placements: []PhotoPlacement{
+			{Filename: "a", Position: at(1, 1, 6, 2)},
+			{Filename: "b", Position: at(5, 2, 8, 3)},   // Overlaps a at 5,2
+			{Filename: "c", Position: at(10, 1, 13, 1)}, // Beyond the grid
+			{Filename: "x", Position: at(9, 4, 9, 4)},   // Unknown photo
+		},
+		MobilePlacements: []PhotoPlacement{
+			{Filename: "a", Position: at(1, 1, 7, 1)}, // Beyond the default mobile width
+		},
+	}
+
+	errs := ValidateLayout(layout, map[string]bool{"a": true, "b": true, "c": true})
+	want := []struct {
+		code, view string
+		placement  int
+	}{
+		{CodeOverlap, "desktop", 1},
+		{CodeOutOfBounds, "desktop", 2},
+		{CodeOutOfBounds, "mobile", 0},
+		{CodeUnknownPhoto, "desktop", 3},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(want), len(errs), errs)
+	}
+	for i, w := range want {
+		if errs[i].Code != w.code || errs[i].View != w.view || errs[i].Placement != w.placement {
+			t.Errorf("Error %d: expected %s in %s placement %d, got %+v", i, w.code, w.view, w.placement, errs[i])
+		}
+	}
+	if cell := errs[0].Cell; cell == nil || *cell != (GridCell{X: 5, Y: 2}) {
+		t.Errorf("Expected overlap at cell 5,2, got %v", cell)
+	}
+
+	if err := ValidateLayout(&LayoutConfig{GridWidth: 12, Placements: layout.Placements[:1]}, nil).Err(); err != nil {
+		t.Errorf("Expected a valid layout, got %v", err)
+	}
+
+	// Huge placements are compared as rectangles, not cell by cell
+	huge := &LayoutConfig{GridWidth: 12, Placements: []PhotoPlacement{
+		{Filename: "a", Position: at(1, 1, 12, 1e9)},
+		{Filename: "b", Position: at(3, 5e8, 4, 1e9)},
+	}}
+	errs = ValidateLayout(huge, map[string]bool{"a": true, "b": true})
+	if len(errs) != 1 || errs[0].Code != CodeOverlap || *errs[0].Cell != (GridCell{X: 3, Y: 5e8}) {
+		t.Errorf("Expected one overlap at 3,500000000, got %v", errs)
+	}
+}
+
+// TestValidateLayoutBlocks checks that placements may reference blocks and that blocks are checked
+func TestValidateLayoutBlocks(t *testing.T) {
+	layout := &LayoutConfig{
+		GridWidth: 12,
+		Placements: []PhotoPlacement{
+			{Filename: "text-1", Position: GridPosition{TopLeftX: 1, TopLeftY: 1, BottomRightX: 4, BottomRightY: 2}},
+			{Filename: "video-1", Position: GridPosition{TopLeftX: 5, TopLeftY: 1, BottomRightX: 12, BottomRightY: 2}},
+		},
+		Blocks: map[string]Block{
+			"text-1":   {Type: BlockText, Text: "Hello"},
+			"video-1":  {Type: BlockVideo, Video: "https://youtu.be/dQw4w9WgXcQ"},
+			"spacer-1": {Type: BlockSpacer}, // Not placed
+		},
+	}
+	if err := ValidateLayout(layout, map[string]bool{}).Err(); err != nil {
+		t.Errorf("Expected a valid layout, got %v", err)
+	}
+
+	layout.Blocks["video-1"] = Block{Type: BlockVideo, Video: "javascript:alert(1)"}
+	layout.Blocks["spacer-1"] = Block{Type: "poster"}
+	for i := 0; i < 10; i++ {
+		errs := ValidateLayout(layout, map[string]bool{})
+		if len(errs) != 2 || errs[0].Code != CodeInvalidBlock || errs[1].Code != CodeInvalidBlock {
+			t.Fatalf("Expected two invalid blocks, got %v", errs)
+		}
+		if errs[0].Ref != "spacer-1" || errs[1].Ref != "video-1" {
+			t.Fatalf("Expected block errors sorted by ID, got %s then %s", errs[0].Ref, errs[1].Ref)
+		}
+	}
+}
+
+// TestMissingFromMobile checks that only photos left out of the mobile grid are reported
+func TestMissingFromMobile(t *testing.T) {
+	at := GridPosition{TopLeftX: 1, TopLeftY: 1, BottomRightX: 1, BottomRightY: 1}
+	layout := &LayoutConfig{
+		GridWidth:       12,
+		MobileGridWidth: 6,
+		Placements: []PhotoPlacement{
+			{Filename: "a", Position: at}, {Filename: "b", Position: at}, {Filename: "text-1", Position: at},
+		},
+		MobilePlacements: []PhotoPlacement{{Filename: "a", Position: at}},
+		Blocks:           map[string]Block{"text-1": {Type: BlockText, Text: "Hello"}},
+	}
+	if missing := layout.MissingFromMobile(); len(missing) != 1 || missing[0] != "b" {
+		t.Errorf("Expected only b to be missing, got %v", missing)
+	}
+}
+
+// TestBlockVideo checks how video URLs are played
+func TestBlockVideo(t *testing.T) {
+	tests := []struct {
+		url, embed string
+		file       bool
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=10", "https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ", false},
+		{"https://youtu.be/dQw4w9WgXcQ", "https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ", false},
+		{"https://vimeo.com/76979871", "https://player.vimeo.com/video/76979871", false},
+		{"/videos/clip.MP4", "", true},
+		{"https://cdn.example.com/clip.webm?v=2", "", true},
+		{"https://example.com/watch?v=dQw4w9WgXcQ", "", false},
+	}
+	for _, tt := range tests {
+		block := Block{Type: BlockVideo, Video: tt.url}
+		if got := block.EmbedURL(); got != tt.embed {
+			t.Errorf("EmbedURL(%q) = %q, want %q", tt.url, got, tt.embed)
+		}
+		if got := block.IsVideoFile(); got != tt.file {
+			t.Errorf("IsVideoFile(%q) = %v, want %v", tt.url, got, tt.file)
+		}
+	}
+}
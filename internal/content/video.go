@@ -0,0 +1,143 @@
+package content
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// videoExtensions are the extensions of video clips listed with the photos of a project
+var videoExtensions = map[string]bool{".mp4": true, ".mov": true}
+
+// IsVideo reports whether a file in a photos directory is a video clip
+func IsVideo(filename string) bool {
+	return videoExtensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+// mp4Box is a box (atom) of an MP4 or QuickTime file: its type and the byte
+// range of its content
+type mp4Box struct {
+	boxType    string
+	start, end int64
+}
+
+// VideoDimensions returns the width and height of an MP4 or QuickTime video as
+// it should be displayed, read from the track header of its first video track
+// and taking the track's rotation into account
+func VideoDimensions(path string) (width, height int, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	top, err := readBoxes(file, 0, info.Size())
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, moov := range filterBoxes(top, "moov") {
+		traks, err := readBoxes(file, moov.start, moov.end)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, trak := range filterBoxes(traks, "trak") {
+			children, err := readBoxes(file, trak.start, trak.end)
+			if err != nil {
+				return 0, 0, err
+			}
+			for _, tkhd := range filterBoxes(children, "tkhd") {
+				width, height, err := trackDimensions(file, tkhd)
+				if err != nil {
+					return 0, 0, err
+				}
+				if width > 0 && height > 0 { // Audio tracks have no dimensions
+					return width, height, nil
+				}
+			}
+		}
+	}
+	return 0, 0, fmt.Errorf("no video track found")
+}
+
+// readBoxes lists the boxes between start and end
+func readBoxes(r io.ReaderAt, start, end int64) ([]mp4Box, error) {
+	var boxes []mp4Box
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return nil, fmt.Errorf("failed to read box header: %w", err)
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		headerSize := int64(8)
+		switch size {
+		case 0: // Extends to the end of the file
+			size = end - offset
+		case 1: // 64-bit size follows the type
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return nil, fmt.Errorf("failed to read box size: %w", err)
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if size < headerSize || offset+size > end {
+			return nil, fmt.Errorf("invalid box size at offset %d", offset)
+		}
+		boxes = append(boxes, mp4Box{boxType: string(header[4:8]), start: offset + headerSize, end: offset + size})
+		offset += size
+	}
+	return boxes, nil
+}
+
+// filterBoxes returns the boxes of a type
+func filterBoxes(boxes []mp4Box, boxType string) []mp4Box {
+	var result []mp4Box
+	for _, box := range boxes {
+		if box.boxType == boxType {
+			result = append(result, box)
+		}
+	}
+	return result
+}
+
+// trackDimensions reads the width and height of a track header (tkhd) box.
+// Tracks rotated by 90 or 270 degrees have them swapped.
+func trackDimensions(r io.ReaderAt, tkhd mp4Box) (width, height int, err error) {
+	data := make([]byte, tkhd.end-tkhd.start)
+	if len(data) < 4 {
+		return 0, 0, fmt.Errorf("track header too short")
+	}
+	if _, err := r.ReadAt(data, tkhd.start); err != nil {
+		return 0, 0, fmt.Errorf("failed to read track header: %w", err)
+	}
+
+	// Version 1 headers have 64-bit creation and modification times and duration
+	offset := 4 + 20
+	if data[0] == 1 {
+		offset = 4 + 32
+	}
+	offset += 8 + 2 + 2 + 2 + 2 // Reserved, layer, alternate group, volume, reserved
+	matrix := offset
+	offset += 36
+	if len(data) < offset+8 {
+		return 0, 0, fmt.Errorf("track header too short")
+	}
+
+	// Dimensions are 16.16 fixed-point numbers
+	width = int(binary.BigEndian.Uint32(data[offset:]) >> 16)
+	height = int(binary.BigEndian.Uint32(data[offset+4:]) >> 16)
+
+	// The transformation matrix starts with a and b: a quarter turn has a = 0
+	a := int32(binary.BigEndian.Uint32(data[matrix:]))
+	b := int32(binary.BigEndian.Uint32(data[matrix+4:]))
+	if a == 0 && b != 0 {
+		width, height = height, width
+	}
+	return width, height, nil
+}
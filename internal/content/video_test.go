@@ -0,0 +1,109 @@
+package content
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mp4Atom encodes a box with its size and type
+func mp4Atom(boxType string, content ...[]byte) []byte {
+	var body bytes.Buffer
+	for _, c := range content {
+		body.Write(c)
+	}
+	var box bytes.Buffer
+	binary.Write(&box, binary.BigEndian, uint32(8+body.Len()))
+	box.WriteString(boxType)
+	box.Write(body.Bytes())
+	return box.Bytes()
+}
+
+// tkhdAtom encodes a version 0 track header with the given dimensions, turned a
+// quarter turn when rotated
+func tkhdAtom(width, height int, rotated bool) []byte {
+	var data bytes.Buffer
+	data.Write(make([]byte, 4+20+8+2+2+2+2)) // Version, flags, times, IDs, duration, reserved...
+	a, b, c, d := int32(1<<16), int32(0), int32(0), int32(1<<16)
+	if rotated {
+		a, b, c, d = 0, 1<<16, -1<<16, 0
+	}
+	for _, v := range []int32{a, b, 0, c, d, 0, 0, 0, 1 << 30} {
+		binary.Write(&data, binary.BigEndian, v)
+	}
+	binary.Write(&data, binary.BigEndian, uint32(width<<16))
+	binary.Write(&data, binary.BigEndian, uint32(height<<16))
+	return mp4Atom("tkhd", data.Bytes())
+}
+
+// writeMP4 writes a file with the boxes VideoDimensions reads: an audio track
+// followed by a video track, and the movie data before the movie header
+func writeMP4(t *testing.T, path string, width, height int, rotated bool) {
+	t.Helper()
+	data := bytes.Join([][]byte{
+		mp4Atom("ftyp", []byte("isom\x00\x00\x02\x00")),
+		mp4Atom("mdat", make([]byte, 64)),
+		mp4Atom("moov",
+			mp4Atom("mvhd", make([]byte, 100)),
+			mp4Atom("trak", tkhdAtom(0, 0, false)),
+			mp4Atom("trak", tkhdAtom(width, height, rotated), mp4Atom("mdia")),
+		),
+	}, nil)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestVideoDimensions checks that dimensions come from the video track, turned with it
+func TestVideoDimensions(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name          string
+		rotated       bool
+		width, height int
+	}{
+		{"landscape.mp4", false, 1920, 1080},
+		{"portrait.mov", true, 1080, 1920},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		writeMP4(t, path, 1920, 1080, tt.rotated)
+		width, height, err := VideoDimensions(path)
+		if err != nil {
+			t.Fatalf("VideoDimensions(%s) failed: %v", tt.name, err)
+		}
+		if width != tt.width || height != tt.height {
+			t.Errorf("VideoDimensions(%s) = %dx%d, want %dx%d", tt.name, width, height, tt.width, tt.height)
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.mp4")
+	os.WriteFile(bad, []byte("not a video at all"), 0644)
+	if _, _, err := VideoDimensions(bad); err == nil {
+		t.Error("Expected an error for a file that is not a video")
+	}
+}
+
+// TestListPhotosVideos checks that video clips are listed with the photos
+func TestListPhotosVideos(t *testing.T) {
+	m := NewManager(t.TempDir())
+	project, _ := setupPhotoProject(t, m, "Clips")
+	writeMP4(t, filepath.Join(m.ProjectPhotosDir(project.Slug), "clip.MOV"), 1080, 1920, false)
+
+	photos, err := m.ListPhotos(project.Slug)
+	if err != nil {
+		t.Fatalf("ListPhotos failed: %v", err)
+	}
+	if len(photos) != 2 {
+		t.Fatalf("Expected a photo and a clip, got %d entries", len(photos))
+	}
+	clip := photos[0]
+	if clip.Filename != "clip.MOV" || !clip.Video || clip.RatioWidth != 9 || clip.RatioHeight != 16 {
+		t.Errorf("Expected a 9:16 clip, got %+v", clip)
+	}
+	if photos[1].Video {
+		t.Error("Expected the photo not to be a video")
+	}
+}
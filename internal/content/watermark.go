@@ -0,0 +1,84 @@
+package content
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Watermark positions
+const (
+	WatermarkTopLeft     = "top-left"
+	WatermarkTopRight    = "top-right"
+	WatermarkBottomLeft  = "bottom-left"
+	WatermarkBottomRight = "bottom-right"
+	WatermarkCenter      = "center"
+)
+
+// WatermarkSettings configures the overlay applied to generated image variants.
+// It can be set site-wide in site.yaml and overridden per project in meta.yaml.
+type WatermarkSettings struct {
+	Image    string  `yaml:"image,omitempty" json:"image,omitempty"`        // Overlay image, relative to the content dir (takes precedence over Text)
+	Text     string  `yaml:"text,omitempty" json:"text,omitempty"`          // Text overlay, used when no image is set
+	Color    string  `yaml:"color,omitempty" json:"color,omitempty"`        // Text color as #rrggbb (default: #ffffff)
+	Position string  `yaml:"position,omitempty" json:"position,omitempty"`  // top-left, top-right, bottom-left, bottom-right (default) or center
+	Opacity  float64 `yaml:"opacity,omitempty" json:"opacity,omitempty"`    // 0-1 (default: 0.5)
+	Scale    float64 `yaml:"scale,omitempty" json:"scale,omitempty"`        // Overlay width relative to the variant width (default: 0.2)
+	MinWidth int     `yaml:"min_width,omitempty" json:"minWidth,omitempty"` // Variants narrower than this are left untouched
+	Disabled bool    `yaml:"disabled,omitempty" json:"disabled,omitempty"`  // Set in a project override to turn off the site watermark
+}
+
+// IsActive returns true if the settings describe an overlay to apply
+func (w *WatermarkSettings) IsActive() bool {
+	return w != nil && !w.Disabled && (w.Image != "" || w.Text != "")
+}
+
+// Validate checks the watermark settings for out-of-range values
+func (w *WatermarkSettings) Validate() error {
+	if w == nil {
+		return nil
+	}
+	switch w.Position {
+	case "", WatermarkTopLeft, WatermarkTopRight, WatermarkBottomLeft, WatermarkBottomRight, WatermarkCenter:
+	default:
+		return fmt.Errorf("invalid watermark position %q", w.Position)
+	}
+	if w.Opacity < 0 || w.Opacity > 1 {
+		return fmt.Errorf("watermark opacity must be between 0 and 1")
+	}
+	if w.Scale < 0 || w.Scale > 1 {
+		return fmt.Errorf("watermark scale must be between 0 and 1")
+	}
+	if w.MinWidth < 0 {
+		return fmt.Errorf("watermark min_width must be >= 0")
+	}
+	return nil
+}
+
+// EffectiveWatermark returns the watermark settings that apply to a project:
+// the project override if present, otherwise the site-wide settings. Returns nil
+// when no watermark should be applied. Relative image paths are resolved against
+// the content directory.
+func (m *Manager) EffectiveWatermark(slug string) (*WatermarkSettings, error) {
+	siteMeta, err := m.LoadSiteMeta()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load site metadata: %w", err)
+	}
+
+	settings := siteMeta.Watermark
+	if project, err := m.GetProject(slug); err == nil && project.Watermark != nil {
+		settings = project.Watermark
+	}
+
+	if !settings.IsActive() {
+		return nil, nil
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	resolved := *settings
+	if resolved.Image != "" && !filepath.IsAbs(resolved.Image) {
+		resolved.Image = filepath.Join(m.contentDir, resolved.Image)
+	}
+	return &resolved, nil
+}
@@ -0,0 +1,78 @@
+package content
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestWatermarkSettingsValidate checks the accepted ranges of each setting
+func TestWatermarkSettingsValidate(t *testing.T) {
+	valid := []*WatermarkSettings{
+		nil,
+		{Text: "© Studio"},
+		{Image: "logo.png", Position: WatermarkCenter, Opacity: 1, Scale: 0.5, MinWidth: 800},
+	}
+	for _, w := range valid {
+		if err := w.Validate(); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", w, err)
+		}
+	}
+
+	invalid := map[string]*WatermarkSettings{
+		"position": {Text: "x", Position: "middle"},
+		"opacity":  {Text: "x", Opacity: 1.5},
+		"scale":    {Text: "x", Scale: -0.1},
+		"width":    {Text: "x", MinWidth: -1},
+	}
+	for name, w := range invalid {
+		if err := w.Validate(); err == nil {
+			t.Errorf("Expected an error for an invalid %s", name)
+		}
+	}
+}
+
+// TestEffectiveWatermark checks the site default, project overrides and image path resolution
+func TestEffectiveWatermark(t *testing.T) {
+	contentDir := t.TempDir()
+	m := NewManager(contentDir)
+	project, err := m.CreateProject("Marked", "")
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+
+	if w, err := m.EffectiveWatermark(project.Slug); err != nil || w != nil {
+		t.Errorf("Expected no watermark by default, got %+v, %v", w, err)
+	}
+
+	if err := m.UpdateSiteMeta(func(meta *SiteMetadata) error {
+		meta.Watermark = &WatermarkSettings{Image: "logo.png"}
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateSiteMeta failed: %v", err)
+	}
+	w, err := m.EffectiveWatermark(project.Slug)
+	if err != nil {
+		t.Fatalf("EffectiveWatermark failed: %v", err)
+	}
+	if w == nil || w.Image != filepath.Join(contentDir, "logo.png") {
+		t.Errorf("Expected the site watermark with its image resolved in the content dir, got %+v", w)
+	}
+
+	override := func(settings *WatermarkSettings) {
+		t.Helper()
+		if err := m.UpdateProjectMeta(project.Slug, func(p *ProjectMetadata) error {
+			p.Watermark = settings
+			return nil
+		}); err != nil {
+			t.Fatalf("UpdateProjectMeta failed: %v", err)
+		}
+	}
+	override(&WatermarkSettings{Text: "Client proof"})
+	if w, err := m.EffectiveWatermark(project.Slug); err != nil || w == nil || w.Text != "Client proof" || w.Image != "" {
+		t.Errorf("Expected the project override, got %+v, %v", w, err)
+	}
+	override(&WatermarkSettings{Disabled: true})
+	if w, err := m.EffectiveWatermark(project.Slug); err != nil || w != nil {
+		t.Errorf("Expected a disabled override to turn off the site watermark, got %+v, %v", w, err)
+	}
+}
@@ -1,9 +1,11 @@
 package generator
 
 import (
+	"context"
 	"fmt"
 	"html/template"
 	"io/fs"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
@@ -12,6 +14,8 @@ import (
 
 	"github.com/rs/zerolog/log"
 	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/layouts"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/processing"
 )
 
 const (
@@ -32,6 +36,16 @@ type Generator struct {
 	imageURLPrefix string
 	customCSSPath  string
 	customJSPath   string
+	progress       func(Progress) // Optional, see SetProgress
+	categories     []*term        // Categories of the published projects, for the navigation
+}
+
+// Progress describes how far a Generate run is: Done of Total pages and asset
+// steps, with Step naming the one being worked on
+type Progress struct {
+	Step  string `json:"step"`
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
 }
 
 // NewGenerator creates a new site generator
@@ -50,8 +64,26 @@ func (g *Generator) SetTemplatesDir(templatesDir string) {
 	g.templatesDir = templatesDir
 }
 
+// SetProgress sets a function called as generation moves through its steps
+func (g *Generator) SetProgress(progress func(Progress)) {
+	g.progress = progress
+}
+
+// reportProgress passes progress to the progress function, if any
+func (g *Generator) reportProgress(step string, done, total int) {
+	if g.progress != nil {
+		g.progress(Progress{Step: step, Done: done, Total: total})
+	}
+}
+
 // Generate generates the complete static site with the given base URL prefix and optional image URL prefix
 func (g *Generator) Generate(baseURL string, imageURLPrefix string) error {
+	return g.GenerateContext(context.Background(), baseURL, imageURLPrefix)
+}
+
+// GenerateContext is Generate, stopping between pages once ctx is done. A
+// cancelled run leaves a partially updated output directory.
+func (g *Generator) GenerateContext(ctx context.Context, baseURL string, imageURLPrefix string) error {
 	g.baseURL = baseURL
 	g.imageURLPrefix = imageURLPrefix
 
@@ -74,6 +106,8 @@ func (g *Generator) Generate(baseURL string, imageURLPrefix string) error {
 			}
 			return s
 		},
+		"markdown": renderMarkdown,
+		"slugify":  content.Slugify,
 		"sanitizeClass": func(s string) string {
 			// Replace dots and special chars with hyphens for valid CSS class names
 			result := ""
@@ -145,6 +179,19 @@ func (g *Generator) Generate(baseURL string, imageURLPrefix string) error {
 			return fmt.Sprintf("(max-width: 768px) calc((100vw - %dpx) * %d / %d + %dpx), %dpx",
 				16+totalGapsPx, colSpan, mobileGridWidth, imageGapsPx, fallbackPx)
 		},
+		"variantSrcset": func(base string, widths []int, max int) string {
+			// Photos without layout-aware widths have every default variant
+			if len(widths) == 0 {
+				widths = processing.DefaultWidths
+			}
+			return processing.Srcset(base, widths, max)
+		},
+		"variantSrc": func(base string, widths []int, preferred int) string {
+			if len(widths) == 0 {
+				widths = processing.DefaultWidths
+			}
+			return fmt.Sprintf("%s-%dw.webp", base, processing.FallbackWidth(widths, preferred))
+		},
 		"calculateIndexSizes": func(placement content.IndexHeroPlacement) string {
 			// Same logic as calculateSizes but for index hero placements
 			colSpan := placement.Position.BottomRightX - placement.Position.TopLeftX + 1
@@ -223,23 +270,47 @@ func (g *Generator) Generate(baseURL string, imageURLPrefix string) error {
 		return fmt.Errorf("failed to list projects: %w", err)
 	}
 
-	// Filter out hidden projects
+	// Filter out hidden projects. Published ones must not share a directory
+	// with the site's own pages (older versions accepted such slugs).
 	var projects []*content.ProjectMetadata
 	for _, p := range allProjects {
-		if !p.Hidden {
-			projects = append(projects, p)
+		if p.Hidden {
+			continue
+		}
+		if content.ReservedSlug(p.Slug) {
+			return fmt.Errorf("project %s: the slug is used by the site's own pages; rename the project", p.Slug)
 		}
+		projects = append(projects, p)
 	}
 
 	log.Info().Int("total", len(allProjects)).Int("active", len(projects)).Msg("Generating site for projects")
+	g.categories = projectCategories(projects)
+
+	// Index, about, project and listing pages, redirects, then static assets and favicons
+	total := len(projects) + 6
+	done := 0
+	step := func(name string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		g.reportProgress(name, done, total)
+		done++
+		return nil
+	}
 
 	// Generate index page
+	if err := step("index page"); err != nil {
+		return err
+	}
 	log.Debug().Msg("Generating index page")
 	if err := g.generateIndex(projects, buildTimestamp); err != nil {
 		return fmt.Errorf("failed to generate index: %w", err)
 	}
 
 	// Generate about page
+	if err := step("about page"); err != nil {
+		return err
+	}
 	log.Debug().Msg("Generating about page")
 	if err := g.generateAbout(buildTimestamp); err != nil {
 		return fmt.Errorf("failed to generate about: %w", err)
@@ -247,48 +318,64 @@ func (g *Generator) Generate(baseURL string, imageURLPrefix string) error {
 
 	// Generate project pages
 	for _, project := range projects {
+		if err := step("project " + project.Slug); err != nil {
+			return err
+		}
 		log.Debug().Str("slug", project.Slug).Str("title", project.Title).Msg("Generating project page")
 		if err := g.generateProjectPage(project, buildTimestamp); err != nil {
 			return fmt.Errorf("failed to generate project %s: %w", project.Slug, err)
 		}
 	}
 
+	// Generate tag and category pages
+	if err := step("tag and category pages"); err != nil {
+		return err
+	}
+	log.Debug().Msg("Generating tag and category pages")
+	if err := g.generateTaxonomyPages(projects, buildTimestamp); err != nil {
+		return fmt.Errorf("failed to generate tag and category pages: %w", err)
+	}
+
+	// Generate redirects from renamed projects' former slugs
+	if err := step("redirects"); err != nil {
+		return err
+	}
+	log.Debug().Msg("Generating redirects")
+	if err := g.generateRedirects(allProjects, projects); err != nil {
+		return fmt.Errorf("failed to generate redirects: %w", err)
+	}
+
 	// Copy static assets
+	if err := step("static assets"); err != nil {
+		return err
+	}
 	log.Debug().Msg("Copying static assets")
 	if err := g.copyStaticAssets(); err != nil {
 		return fmt.Errorf("failed to copy static assets: %w", err)
 	}
 
 	// Copy favicon files
+	if err := step("favicons"); err != nil {
+		return err
+	}
 	log.Debug().Msg("Copying favicon files")
 	if err := g.copyFavicons(); err != nil {
 		return fmt.Errorf("failed to copy favicons: %w", err)
 	}
 
+	g.reportProgress("done", total, total)
 	log.Info().Msg("Site generation completed")
 
 	return nil
 }
 
-// generateIndex generates the main index page
-func (g *Generator) generateIndex(projects []*content.ProjectMetadata, buildTimestamp int64) error {
-	publicDir := g.outputDir
-	indexPath := filepath.Join(publicDir, "index.html")
-
-	file, err := os.Create(indexPath)
-	if err != nil {
-		return fmt.Errorf("failed to create index.html: %w", err)
-	}
-	defer file.Close()
-
-	// Load optional site metadata (e.g. copyright) to pass to templates
+// loadSiteMeta loads the site metadata, with defaults for the names and the
+// copyright notice shown on every page
+func (g *Generator) loadSiteMeta() *content.SiteMetadata {
 	siteMeta, err := g.contentMgr.LoadSiteMeta()
 	if err != nil {
 		log.Warn().Err(err).Msg("Failed to load site metadata")
-		siteMeta = &content.SiteMetadata{
-			Copyright:   "2025 Photography Portfolio. All rights reserved.",
-			WebsiteName: "Photography Portfolio",
-		}
+		siteMeta = &content.SiteMetadata{}
 	}
 	if siteMeta.Copyright == "" {
 		siteMeta.Copyright = "2025 Photography Portfolio. All rights reserved."
@@ -302,6 +389,22 @@ func (g *Generator) generateIndex(projects []*content.ProjectMetadata, buildTime
 	if siteMeta.LogoSecondary == "" {
 		siteMeta.LogoSecondary = "photography"
 	}
+	return siteMeta
+}
+
+// generateIndex generates the main index page
+func (g *Generator) generateIndex(projects []*content.ProjectMetadata, buildTimestamp int64) error {
+	publicDir := g.outputDir
+	indexPath := filepath.Join(publicDir, "index.html")
+
+	file, err := os.Create(indexPath)
+	if err != nil {
+		return fmt.Errorf("failed to create index.html: %w", err)
+	}
+	defer file.Close()
+
+	// Load optional site metadata (e.g. copyright) to pass to templates
+	siteMeta := g.loadSiteMeta()
 
 	imageBase := g.imageURLPrefix
 	if imageBase == "" {
@@ -343,6 +446,7 @@ func (g *Generator) generateIndex(projects []*content.ProjectMetadata, buildTime
 		"ProjectHeroes":  projectHeroes,
 		"HeroImageBases": heroImageBases,
 		"ProjectMap":     projectMap,
+		"Categories":     g.categories,
 		"IndexLayout":    indexLayout,
 		"BuildTimestamp": buildTimestamp,
 		"CustomCSS":      customCSS,
@@ -374,26 +478,7 @@ func (g *Generator) generateAbout(buildTimestamp int64) error {
 	defer file.Close()
 
 	// Load site metadata
-	siteMeta, err := g.contentMgr.LoadSiteMeta()
-	if err != nil {
-		log.Warn().Err(err).Msg("Failed to load site metadata")
-		siteMeta = &content.SiteMetadata{
-			Copyright:   "2025 Photography Portfolio. All rights reserved.",
-			WebsiteName: "Photography Portfolio",
-		}
-	}
-	if siteMeta.Copyright == "" {
-		siteMeta.Copyright = "2025 Photography Portfolio. All rights reserved."
-	}
-	if siteMeta.WebsiteName == "" {
-		siteMeta.WebsiteName = "Photography Portfolio"
-	}
-	if siteMeta.LogoPrimary == "" {
-		siteMeta.LogoPrimary = "portfolio"
-	}
-	if siteMeta.LogoSecondary == "" {
-		siteMeta.LogoSecondary = "photography"
-	}
+	siteMeta := g.loadSiteMeta()
 
 	// Get all projects for navigation
 	allProjects, err := g.contentMgr.ListProjects()
@@ -417,6 +502,7 @@ func (g *Generator) generateAbout(buildTimestamp int64) error {
 		"LogoPrimary":    siteMeta.LogoPrimary,
 		"LogoSecondary":  siteMeta.LogoSecondary,
 		"AllProjects":    projects,
+		"Categories":     g.categories,
 		"About":          siteMeta.About,
 		"Contact":        siteMeta.Contact,
 		"Copyright":      siteMeta.Copyright,
@@ -455,15 +541,32 @@ func (g *Generator) generateProjectPage(project *content.ProjectMetadata, buildT
 		return fmt.Errorf("failed to load layout: %w", err)
 	}
 
-	// Validate layout placements (bounds and overlaps)
-	if err := validateLayout(layout); err != nil {
+	// Validate layout placements (bounds and overlaps). Photos may not be on disk
+	// at build time, so hash IDs are not checked here.
+	if err := content.ValidateLayout(layout, nil).Err(); err != nil {
 		return fmt.Errorf("invalid layout for project %s: %w", project.Slug, err)
 	}
 
-	// Create a map of photos by filename for easy lookup
+	// Flag photos left out of a hand-made mobile layout, or derive one from the desktop grid
+	if missing := layout.MissingFromMobile(); len(missing) > 0 {
+		log.Warn().Str("slug", project.Slug).Strs("photos", missing).Msg("Photos placed on desktop are missing from the mobile layout")
+	}
+	layout = layouts.WithMobileLayout(layout)
+
+	// Per-photo variant widths when layout-aware widths are enabled (nil otherwise)
+	variantWidths, err := processing.ProjectWidths(g.contentMgr, project.Slug, processing.DefaultWidths)
+	if err != nil {
+		return fmt.Errorf("failed to compute variant widths: %w", err)
+	}
+
+	// Create a map of photos by filename for easy lookup, and the set of video clips by hash ID
 	photoMap := make(map[string]*content.PhotoInfo)
+	videos := make(map[string]bool)
 	for _, photo := range photos {
 		photoMap[photo.Filename] = photo
+		if photo.Video {
+			videos[photo.HashID] = true
+		}
 	}
 
 	// Get all projects for navigation tabs
@@ -493,122 +596,134 @@ func (g *Generator) generateProjectPage(project *content.ProjectMetadata, buildT
 	defer file.Close()
 
 	// Load optional site metadata
-	siteMeta, err := g.contentMgr.LoadSiteMeta()
-	if err != nil {
-		log.Warn().Err(err).Msg("Failed to load site metadata")
-		siteMeta = &content.SiteMetadata{
-			Copyright:   "2025 Photography Portfolio. All rights reserved.",
-			WebsiteName: "Photography Portfolio",
-		}
-	}
-	if siteMeta.Copyright == "" {
-		siteMeta.Copyright = "2025 Photography Portfolio. All rights reserved."
-	}
-	if siteMeta.WebsiteName == "" {
-		siteMeta.WebsiteName = "Photography Portfolio"
-	}
-	if siteMeta.LogoPrimary == "" {
-		siteMeta.LogoPrimary = "portfolio"
-	}
-	if siteMeta.LogoSecondary == "" {
-		siteMeta.LogoSecondary = "photography"
-	}
+	siteMeta := g.loadSiteMeta()
 
 	customCSS, customJS := g.customAssets()
 
+	// Photos of the lightbox, each with its own page, unless the site turns it off
+	var lightbox []*lightboxPhoto
+	if settings := siteMeta.Lightbox; settings == nil || !settings.Disabled {
+		lightbox = g.lightboxPhotos(project, layout, photos, variantWidths, settings != nil && settings.Exif)
+	}
+
 	data := map[string]interface{}{
-		"Project":        project,
-		"Photos":         photos,
-		"PhotoMap":       photoMap,
-		"Layout":         layout,
-		"BaseURL":        g.baseURL,
-		"ImageURLPrefix": g.imageURLPrefix,
-		"AllProjects":    projects,
-		"WebsiteName":    siteMeta.WebsiteName,
-		"LogoPrimary":    siteMeta.LogoPrimary,
-		"LogoSecondary":  siteMeta.LogoSecondary,
-		"Copyright":      siteMeta.Copyright,
-		"BuildTimestamp": buildTimestamp,
-		"CustomCSS":      customCSS,
-		"CustomJS":       customJS,
+		"Project":          project,
+		"Photos":           photos,
+		"PhotoMap":         photoMap,
+		"Videos":           videos,
+		"Lightbox":         lightbox,
+		"Layout":           layout,
+		"VariantWidths":    variantWidths,
+		"MobileCropWidths": processing.MobileCropWidths,
+		"BaseURL":          g.baseURL,
+		"ImageURLPrefix":   g.imageURLPrefix,
+		"AllProjects":      projects,
+		"Categories":       g.categories,
+		"WebsiteName":      siteMeta.WebsiteName,
+		"LogoPrimary":      siteMeta.LogoPrimary,
+		"LogoSecondary":    siteMeta.LogoSecondary,
+		"Copyright":        siteMeta.Copyright,
+		"BuildTimestamp":   buildTimestamp,
+		"CustomCSS":        customCSS,
+		"CustomJS":         customJS,
 	}
 
 	if err := g.templates.ExecuteTemplate(file, "project.html", data); err != nil {
 		return fmt.Errorf("failed to execute template: %w", err)
 	}
 
-	return nil
+	return g.generatePhotoPages(projectDir, lightbox, data)
 }
 
-// validateLayout checks that placements are within grid bounds and that no two
-// placements overlap. Returns an error describing the first problem found.
-func validateLayout(layout *content.LayoutConfig) error {
-	if layout == nil {
-		return fmt.Errorf("layout is nil")
+// generateRedirects writes a page redirecting to the project's current address
+// for each former slug of a renamed project, and lists the redirects in a
+// _redirects file for hosts that support one (e.g. Netlify, Cloudflare Pages).
+// Former slugs taken by another project again are skipped; redirects to
+// projects that are not published only remove the former slug's output.
+func (g *Generator) generateRedirects(allProjects, projects []*content.ProjectMetadata) error {
+	siteMeta, err := g.contentMgr.LoadSiteMeta()
+	if err != nil {
+		return fmt.Errorf("failed to load site metadata: %w", err)
 	}
-	if layout.GridWidth <= 0 {
-		return fmt.Errorf("grid_width must be > 0")
+	if len(siteMeta.Redirects) == 0 {
+		return nil
+	}
+	if siteMeta.WebsiteName == "" {
+		siteMeta.WebsiteName = "Photography Portfolio"
 	}
 
-	// occupancy map: key "x,y" -> placement index
-	occ := make(map[string]int)
+	taken := make(map[string]bool, len(allProjects))
+	for _, p := range allProjects {
+		taken[p.Slug] = true
+	}
+	published := make(map[string]bool, len(projects))
+	for _, p := range projects {
+		published[p.Slug] = true
+	}
 
-	for i, p := range layout.Placements {
-		pos := p.Position
-		// Basic bounds: configuration uses 1-based indices (inclusive)
-		if pos.TopLeftX < 1 || pos.TopLeftY < 1 {
-			return fmt.Errorf("placement %d (%s) has top-left coordinates less than 1", i, p.Filename)
+	// _redirects paths are relative to the host, so keep the path of the base URL
+	prefix := ""
+	if u, err := url.Parse(g.baseURL); err == nil {
+		prefix = strings.TrimSuffix(u.Path, "/")
+	}
+
+	var rules strings.Builder
+	for _, r := range siteMeta.Redirects {
+		if taken[r.From] || content.ReservedSlug(r.From) || !safeDirName(r.From) {
+			log.Debug().Str("from", r.From).Str("to", r.To).Msg("Skipping redirect")
+			continue
 		}
-		if pos.BottomRightX < pos.TopLeftX || pos.BottomRightY < pos.TopLeftY {
-			return fmt.Errorf("placement %d (%s) has bottom-right before top-left", i, p.Filename)
+		// Pages generated before the rename, such as photo pages, would be left behind
+		if err := os.RemoveAll(filepath.Join(g.outputDir, r.From)); err != nil {
+			return fmt.Errorf("failed to remove the output of %s: %w", r.From, err)
 		}
-		if pos.BottomRightX > layout.GridWidth {
-			return fmt.Errorf("placement %d (%s) extends beyond grid width (%d): bottom_right_x=%d", i, p.Filename, layout.GridWidth, pos.BottomRightX)
+		if !published[r.To] {
+			log.Debug().Str("from", r.From).Str("to", r.To).Msg("Skipping redirect to an unpublished project")
+			continue
 		}
-
-		// Check overlaps by marking occupied cells
-		for x := pos.TopLeftX; x <= pos.BottomRightX; x++ {
-			for y := pos.TopLeftY; y <= pos.BottomRightY; y++ {
-				key := fmt.Sprintf("%d,%d", x, y)
-				if other, ok := occ[key]; ok {
-					return fmt.Errorf("placement %d (%s) overlaps with placement %d (%s) at cell %s", i, p.Filename, other, layout.Placements[other].Filename, key)
-				}
-				occ[key] = i
-			}
+		if err := g.writeRedirectPage(r, siteMeta.WebsiteName); err != nil {
+			return err
 		}
+		fmt.Fprintf(&rules, "%s/%s %s/%s/ 301\n", prefix, r.From, prefix, r.To)
+		fmt.Fprintf(&rules, "%s/%s/* %s/%s/:splat 301\n", prefix, r.From, prefix, r.To)
 	}
-
-	// Validate Mobile Placements (Independent Grid)
-	mobileOcc := make(map[string]int)
-	mobileGridWidth := layout.MobileGridWidth
-	if mobileGridWidth <= 0 {
-		mobileGridWidth = 6 // Default fallback matching JS logic
+	if rules.Len() == 0 {
+		return nil
+	}
+	if err := os.WriteFile(filepath.Join(g.outputDir, "_redirects"), []byte(rules.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write _redirects: %w", err)
 	}
+	return nil
+}
 
-	for i, p := range layout.MobilePlacements {
-		pos := p.Position
-		if pos.TopLeftX < 1 || pos.TopLeftY < 1 {
-			return fmt.Errorf("mobile placement %d (%s) has top-left coordinates less than 1", i, p.Filename)
-		}
-		if pos.BottomRightX < pos.TopLeftX || pos.BottomRightY < pos.TopLeftY {
-			return fmt.Errorf("mobile placement %d (%s) has bottom-right before top-left", i, p.Filename)
-		}
-		if pos.BottomRightX > mobileGridWidth {
-			return fmt.Errorf("mobile placement %d (%s) extends beyond mobile grid width (%d): bottom_right_x=%d", i, p.Filename, mobileGridWidth, pos.BottomRightX)
-		}
+// safeDirName reports whether name can be used as a directory of the output
+// root, without leading elsewhere (slugs of older versions may not be valid
+// slugs anymore, but are still redirected)
+func safeDirName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, ".") && !strings.ContainsAny(name, `/\`)
+}
 
-		// Check overlaps
-		for x := pos.TopLeftX; x <= pos.BottomRightX; x++ {
-			for y := pos.TopLeftY; y <= pos.BottomRightY; y++ {
-				key := fmt.Sprintf("%d,%d", x, y)
-				if other, ok := mobileOcc[key]; ok {
-					return fmt.Errorf("mobile placement %d (%s) overlaps with mobile placement %d (%s) at cell %s", i, p.Filename, other, layout.MobilePlacements[other].Filename, key)
-				}
-				mobileOcc[key] = i
-			}
-		}
+// writeRedirectPage writes the meta-refresh page at a project's former address
+func (g *Generator) writeRedirectPage(r content.Redirect, websiteName string) error {
+	dir := filepath.Join(g.outputDir, r.From)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create redirect directory: %w", err)
 	}
+	file, err := os.Create(filepath.Join(dir, "index.html"))
+	if err != nil {
+		return fmt.Errorf("failed to create redirect page: %w", err)
+	}
+	defer file.Close()
 
+	data := map[string]interface{}{
+		"From":        r.From,
+		"To":          r.To,
+		"BaseURL":     g.baseURL,
+		"WebsiteName": websiteName,
+	}
+	if err := g.templates.ExecuteTemplate(file, "redirect.html", data); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
 	return nil
 }
 
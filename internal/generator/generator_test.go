@@ -1,12 +1,20 @@
 package generator
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"go.lorenzomilicia.dev/photography-portfolio-builder/assets"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
 )
 
 // TestCaseResult holds the validation results for a test case
@@ -337,3 +345,441 @@ func TestDirectoryStructure(t *testing.T) {
 
 	t.Log("Directory structure validation passed")
 }
+
+// TestGenerateContext checks that progress is reported and that a cancelled run stops
+func TestGenerateContext(t *testing.T) {
+	contentDir := filepath.Join("testdata", "basic_site")
+	gen := NewGenerator(contentDir, t.TempDir(), assets.TemplatesFS, assets.StaticFS)
+
+	var steps []Progress
+	gen.SetProgress(func(p Progress) { steps = append(steps, p) })
+	if err := gen.GenerateContext(context.Background(), "", ""); err != nil {
+		t.Fatalf("Failed to generate site: %v", err)
+	}
+	last := steps[len(steps)-1]
+	if len(steps) < 2 || last.Done != last.Total || steps[0].Done != 0 {
+		t.Errorf("Expected progress from 0 to total, got %+v", steps)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	gen.SetProgress(func(p Progress) {
+		if p.Done == 1 {
+			cancel() // After the index page
+		}
+	})
+	if err := gen.GenerateContext(ctx, "", ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// TestGenerateRedirects checks that former slugs of renamed projects redirect to the new pages
+func TestGenerateRedirects(t *testing.T) {
+	contentDir := t.TempDir()
+	mgr := content.NewManager(contentDir)
+	if _, err := mgr.CreateProject("Portraits", ""); err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	if _, err := mgr.RenameProject("portraits", "people"); err != nil {
+		t.Fatalf("RenameProject failed: %v", err)
+	}
+	// Redirects to missing projects are skipped
+	if err := mgr.UpdateSiteMeta(func(meta *content.SiteMetadata) error {
+		meta.Redirects = append(meta.Redirects, content.Redirect{From: "gone", To: "missing"})
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Output generated under the former slugs before the rename
+	outputDir := t.TempDir()
+	for _, stale := range []string{filepath.Join("portraits", "abc123abc123"), "gone"} {
+		if err := os.MkdirAll(filepath.Join(outputDir, stale), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gen := NewGenerator(contentDir, outputDir, assets.TemplatesFS, assets.StaticFS)
+	if err := gen.Generate("https://example.com/portfolio", ""); err != nil {
+		t.Fatalf("Failed to generate site: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "portraits", "abc123abc123")); !os.IsNotExist(err) {
+		t.Errorf("Expected the former slug's photo pages to be removed, got %v", err)
+	}
+
+	page, err := os.ReadFile(filepath.Join(outputDir, "portraits", "index.html"))
+	if err != nil {
+		t.Fatalf("Redirect page not generated: %v", err)
+	}
+	if !strings.Contains(string(page), `content="0; url=https://example.com/portfolio/people/"`) {
+		t.Errorf("Expected a meta refresh to the new page, got:\n%s", page)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "gone")); !os.IsNotExist(err) {
+		t.Errorf("Expected no redirect to a missing project, got %v", err)
+	}
+
+	rules, err := os.ReadFile(filepath.Join(outputDir, "_redirects"))
+	if err != nil {
+		t.Fatalf("_redirects not generated: %v", err)
+	}
+	want := "/portfolio/portraits /portfolio/people/ 301\n/portfolio/portraits/* /portfolio/people/:splat 301\n"
+	if string(rules) != want {
+		t.Errorf("Expected _redirects:\n%s\ngot:\n%s", want, rules)
+	}
+}
+
+// TestProjectContent checks that the project body, date, credits and theme are rendered
+func TestProjectContent(t *testing.T) {
+	contentDir := t.TempDir()
+	mgr := content.NewManager(contentDir)
+	project, err := mgr.CreateProject("Commission", "")
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	gap := 12
+	if err := mgr.UpdateProjectMeta(project.Slug, func(meta *content.ProjectMetadata) error {
+		meta.Body = "Shot for **Acme**.\n\n<script>alert(1)</script>"
+		meta.BodyPosition = content.BodyBelow
+		meta.Date = "2024-05"
+		meta.Credits = &content.Credits{Client: "Acme", Location: "Milano"}
+		meta.Theme = &content.ProjectTheme{Background: "#111111", GridGap: &gap}
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateProjectMeta failed: %v", err)
+	}
+
+	outputDir := t.TempDir()
+	gen := NewGenerator(contentDir, outputDir, assets.TemplatesFS, assets.StaticFS)
+	if err := gen.Generate("", ""); err != nil {
+		t.Fatalf("Failed to generate site: %v", err)
+	}
+	page, err := os.ReadFile(filepath.Join(outputDir, project.Slug, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(page)
+	for _, want := range []string{
+		"<strong>Acme</strong>",
+		"May 2024",
+		"<dt>Client</dt>",
+		"<dd>Milano</dd>",
+		"--bg: #111111;",
+		"--gallery-gap-desktop: 12px;",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected %q in the project page", want)
+		}
+	}
+	if strings.Contains(html, "<script>alert(1)</script>") {
+		t.Error("Expected raw HTML in the body to be left out")
+	}
+	if strings.Contains(html, "<dt>Agency</dt>") {
+		t.Error("Expected empty credits to be left out")
+	}
+	// Body is below the grid
+	if strings.Index(html, "project-text") < strings.Index(html, "empty-state") {
+		t.Error("Expected the body below the gallery")
+	}
+}
+
+// TestLayoutBlocks checks that text, video and spacer blocks are rendered in the gallery grid
+func TestLayoutBlocks(t *testing.T) {
+	contentDir := t.TempDir()
+	mgr := content.NewManager(contentDir)
+	project, err := mgr.CreateProject("Editorial", "")
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	at := func(x1, y1, x2, y2 int) content.GridPosition {
+		return content.GridPosition{TopLeftX: x1, TopLeftY: y1, BottomRightX: x2, BottomRightY: y2}
+	}
+	if err := mgr.UpdateLayout(project.Slug, &content.LayoutConfig{
+		GridWidth: 12,
+		Placements: []content.PhotoPlacement{
+			{Filename: "text-a1", Position: at(1, 1, 4, 2)},
+			{Filename: "video-b2", Position: at(5, 1, 12, 2)},
+			{Filename: "video-c3", Position: at(1, 3, 6, 4)},
+			{Filename: "spacer-d4", Position: at(7, 3, 8, 3)},
+		},
+		Blocks: map[string]content.Block{
+			"text-a1":   {Type: content.BlockText, Text: "An *editorial* series"},
+			"video-b2":  {Type: content.BlockVideo, Video: "https://vimeo.com/76979871"},
+			"video-c3":  {Type: content.BlockVideo, Video: "/videos/clip.mp4"},
+			"spacer-d4": {Type: content.BlockSpacer},
+		},
+	}); err != nil {
+		t.Fatalf("UpdateLayout failed: %v", err)
+	}
+
+	outputDir := t.TempDir()
+	gen := NewGenerator(contentDir, outputDir, assets.TemplatesFS, assets.StaticFS)
+	if err := gen.Generate("", ""); err != nil {
+		t.Fatalf("Failed to generate site: %v", err)
+	}
+	page, err := os.ReadFile(filepath.Join(outputDir, project.Slug, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(page)
+	for _, want := range []string{
+		"An <em>editorial</em> series",
+		`<iframe src="https://player.vimeo.com/video/76979871"`,
+		`<video src="/videos/clip.mp4"`,
+		"gallery-block-spacer photo-desktop-3-spacer-d4",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected %q in the project page", want)
+		}
+	}
+	if strings.Contains(html, "/images/editorial/text-a1") {
+		t.Error("Expected no image for a text block")
+	}
+}
+
+// TestLightbox checks the lightbox data of a project page and the per-photo pages
+// TestMobileCrop checks that mobile crops are served when the mobile layout is
+// derived from the desktop grid
+func TestMobileCrop(t *testing.T) {
+	contentDir := t.TempDir()
+	mgr := content.NewManager(contentDir)
+	project, err := mgr.CreateProject("Cropped", "")
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 30, 20)), nil); err != nil {
+		t.Fatal(err)
+	}
+	photo, _, err := mgr.AddPhoto(project.Slug, "photo.jpg", &buf)
+	if err != nil {
+		t.Fatalf("AddPhoto failed: %v", err)
+	}
+	layout := &content.LayoutConfig{
+		GridWidth:  12,
+		MobileCrop: "4:5",
+		Placements: []content.PhotoPlacement{
+			{Filename: photo.HashID, Position: content.GridPosition{TopLeftX: 1, TopLeftY: 1, BottomRightX: 12, BottomRightY: 1}},
+		},
+	}
+	if err := mgr.UpdateLayout(project.Slug, layout); err != nil {
+		t.Fatalf("UpdateLayout failed: %v", err)
+	}
+
+	outputDir := t.TempDir()
+	gen := NewGenerator(contentDir, outputDir, assets.TemplatesFS, assets.StaticFS)
+	if err := gen.Generate("", ""); err != nil {
+		t.Fatalf("Failed to generate site: %v", err)
+	}
+	page, err := os.ReadFile(filepath.Join(outputDir, project.Slug, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(page)
+	mobile := strings.Index(html, "mobile-only reveal-on-scroll")
+	crop := strings.Index(html, photo.HashID+"-crop4x5-480w.webp")
+	if mobile < 0 || crop < mobile {
+		t.Error("Expected the derived mobile layout to serve the cropped variants")
+	}
+	if !strings.Contains(html, "aspect-ratio: 4 / 5") {
+		t.Error("Expected mobile cells to take the crop's aspect ratio")
+	}
+}
+
+func TestLightbox(t *testing.T) {
+	contentDir := t.TempDir()
+	mgr := content.NewManager(contentDir)
+	project, err := mgr.CreateProject("Portraits", "")
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	var hashes []string
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		img := image.NewRGBA(image.Rect(0, 0, 30, 20))
+		img.Set(i, 0, color.White) // Distinct content, distinct hash
+		if err := jpeg.Encode(&buf, img, nil); err != nil {
+			t.Fatal(err)
+		}
+		photo, _, err := mgr.AddPhoto(project.Slug, fmt.Sprintf("photo-%d.jpg", i), &buf)
+		if err != nil {
+			t.Fatalf("AddPhoto failed: %v", err)
+		}
+		hashes = append(hashes, photo.HashID)
+	}
+	at := func(y int) content.GridPosition {
+		return content.GridPosition{TopLeftX: 1, TopLeftY: y, BottomRightX: 6, BottomRightY: y}
+	}
+	// The second photo is placed first in the file, but sits above the first one
+	layout := &content.LayoutConfig{
+		GridWidth: 12,
+		Placements: []content.PhotoPlacement{
+			{Filename: hashes[0], Position: at(2)},
+			{Filename: hashes[1], Position: at(1)},
+		},
+		Photos: map[string]content.PhotoSettings{hashes[0]: {Caption: "Giulia in the studio"}},
+	}
+	if err := mgr.UpdateLayout(project.Slug, layout); err != nil {
+		t.Fatalf("UpdateLayout failed: %v", err)
+	}
+
+	outputDir := t.TempDir()
+	gen := NewGenerator(contentDir, outputDir, assets.TemplatesFS, assets.StaticFS)
+	if err := gen.Generate("https://example.com", "https://cdn.example.com"); err != nil {
+		t.Fatalf("Failed to generate site: %v", err)
+	}
+	page, err := os.ReadFile(filepath.Join(outputDir, project.Slug, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(page)
+	if !strings.Contains(html, `id="lightbox-data"`) || !strings.Contains(html, `data-photo="`+hashes[0]+`"`) {
+		t.Error("Expected lightbox data and clickable photos in the project page")
+	}
+	if strings.Index(html, `"id":"`+hashes[1]+`"`) > strings.Index(html, `"id":"`+hashes[0]+`"`) {
+		t.Error("Expected the lightbox to follow the grid order")
+	}
+
+	photoPage, err := os.ReadFile(filepath.Join(outputDir, project.Slug, hashes[0], "index.html"))
+	if err != nil {
+		t.Fatalf("Expected a page for the photo: %v", err)
+	}
+	for _, want := range []string{
+		`<meta property="og:image" content="https://cdn.example.com/images/portraits/` + hashes[0] + `/` + hashes[0] + `-1200w.webp">`,
+		`<meta property="og:url" content="https://example.com/portraits/` + hashes[0] + `/">`,
+		`<p class="photo-caption">Giulia in the studio</p>`,
+		`href="https://example.com/portraits/` + hashes[1] + `/" class="photo-nav-link" rel="prev"`,
+		`href="https://example.com/portraits/#photo-` + hashes[0] + `"`,
+	} {
+		if !strings.Contains(string(photoPage), want) {
+			t.Errorf("Expected %q in the photo page", want)
+		}
+	}
+
+	// Pages of photos no longer shown are removed, and all of them when the lightbox is off
+	layout.Placements = layout.Placements[:1]
+	if err := mgr.UpdateLayout(project.Slug, layout); err != nil {
+		t.Fatalf("UpdateLayout failed: %v", err)
+	}
+	if err := gen.Generate("", ""); err != nil {
+		t.Fatalf("Failed to generate site: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, project.Slug, hashes[1])); !os.IsNotExist(err) {
+		t.Error("Expected the page of the removed photo to be deleted")
+	}
+	if err := mgr.UpdateSiteMeta(func(meta *content.SiteMetadata) error {
+		meta.Lightbox = &content.LightboxSettings{Disabled: true}
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateSiteMeta failed: %v", err)
+	}
+	if err := gen.Generate("", ""); err != nil {
+		t.Fatalf("Failed to generate site: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, project.Slug, hashes[0])); !os.IsNotExist(err) {
+		t.Error("Expected no photo pages with the lightbox disabled")
+	}
+}
+
+func TestTaxonomy(t *testing.T) {
+	contentDir := t.TempDir()
+	mgr := content.NewManager(contentDir)
+	terms := map[string]struct {
+		category string
+		tags     []string
+	}{
+		"Portraits": {"Editorial", []string{"Film", "Studio"}},
+		"Streets":   {"Personal", []string{"film"}},
+	}
+	for title, tt := range terms {
+		project, err := mgr.CreateProject(title, "")
+		if err != nil {
+			t.Fatalf("CreateProject failed: %v", err)
+		}
+		if err := mgr.UpdateProjectMeta(project.Slug, func(meta *content.ProjectMetadata) error {
+			meta.Category = tt.category
+			meta.Tags = tt.tags
+			return nil
+		}); err != nil {
+			t.Fatalf("UpdateProjectMeta failed: %v", err)
+		}
+	}
+
+	outputDir := t.TempDir()
+	gen := NewGenerator(contentDir, outputDir, assets.TemplatesFS, assets.StaticFS)
+	if err := gen.Generate("", ""); err != nil {
+		t.Fatalf("Failed to generate site: %v", err)
+	}
+
+	filmPage, err := os.ReadFile(filepath.Join(outputDir, "tags", "film", "index.html"))
+	if err != nil {
+		t.Fatalf("Expected a page for the film tag: %v", err)
+	}
+	if !strings.Contains(string(filmPage), `href="/portraits"`) || !strings.Contains(string(filmPage), `href="/streets"`) {
+		t.Error("Expected tags differing only in case to list the same projects")
+	}
+	categoryPage, err := os.ReadFile(filepath.Join(outputDir, "category", "editorial", "index.html"))
+	if err != nil {
+		t.Fatalf("Expected a page for the editorial category: %v", err)
+	}
+	if strings.Count(string(categoryPage), `class="project-link"`) != 1 {
+		t.Error("Expected the category page to list only its project")
+	}
+
+	index, err := os.ReadFile(filepath.Join(outputDir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`data-filter="personal"`, `data-category="editorial"`} {
+		if !strings.Contains(string(index), want) {
+			t.Errorf("Expected %q in the navigation", want)
+		}
+	}
+	projectPage, err := os.ReadFile(filepath.Join(outputDir, "portraits", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(projectPage), `href="/tags/studio/"`) {
+		t.Error("Expected the project page to link its tags")
+	}
+
+	// Pages of terms no longer used are removed
+	if err := mgr.UpdateProjectMeta("portraits", func(meta *content.ProjectMetadata) error {
+		meta.Tags = nil
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateProjectMeta failed: %v", err)
+	}
+	if err := gen.Generate("", ""); err != nil {
+		t.Fatalf("Failed to generate site: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "tags", "studio")); !os.IsNotExist(err) {
+		t.Error("Expected the page of the unused tag to be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "tags", "film", "index.html")); err != nil {
+		t.Error("Expected the page of a tag still in use to be kept")
+	}
+}
+
+// TestReservedSlugProject checks that a published project with the slug of the
+// site's own pages is refused rather than overwriting them
+func TestReservedSlugProject(t *testing.T) {
+	contentDir := t.TempDir()
+	metaPath := filepath.Join(contentDir, "projects", "tags", "meta.yaml")
+	if err := os.MkdirAll(filepath.Dir(metaPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(metaPath, []byte("title: Tags\nslug: tags\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	gen := NewGenerator(contentDir, t.TempDir(), assets.TemplatesFS, assets.StaticFS)
+	if err := gen.Generate("", ""); err == nil || !strings.Contains(err.Error(), "tags") {
+		t.Errorf("Expected an error naming the project, got %v", err)
+	}
+
+	if err := os.WriteFile(metaPath, []byte("title: Tags\nslug: tags\nhidden: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := gen.Generate("", ""); err != nil {
+		t.Errorf("Expected a hidden project to be left alone, got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/processing"
+)
+
+// lightboxPhoto is a photo of the lightbox on a project page, and of its own page
+type lightboxPhoto struct {
+	HashID      string            `json:"id"`
+	Src         string            `json:"src"`             // Variant shown full screen, at most 1920px wide
+	Srcset      string            `json:"srcset"`          // All variants
+	Video       string            `json:"video,omitempty"` // Web transcode of video clips, played instead of the image
+	RatioWidth  int               `json:"ratioWidth,omitempty"`
+	RatioHeight int               `json:"ratioHeight,omitempty"`
+	Caption     string            `json:"caption,omitempty"`
+	Exif        *content.ExifData `json:"exif,omitempty"` // Only when the site enables it
+	URL         string            `json:"url"`            // The photo's own page
+	ShareImage  string            `json:"-"`              // Absolute URL of the og:image, when images have one
+	Alt         string            `json:"alt"`
+}
+
+// lightboxPhotos returns the photos of a project page in the order visitors see
+// them: desktop placements row by row, then photos only placed on mobile
+func (g *Generator) lightboxPhotos(project *content.ProjectMetadata, layout *content.LayoutConfig, photos []*content.PhotoInfo, variantWidths map[string][]int, exif bool) []*lightboxPhoto {
+	photosByHash := make(map[string]*content.PhotoInfo, len(photos))
+	for _, photo := range photos {
+		photosByHash[photo.HashID] = photo
+	}
+
+	imageBase := g.imageURLPrefix
+	if imageBase == "" {
+		imageBase = g.baseURL
+	}
+
+	var result []*lightboxPhoto
+	seen := make(map[string]bool)
+	for _, placements := range [][]content.PhotoPlacement{layout.Placements, layout.MobilePlacements} {
+		for _, placement := range readingOrder(placements) {
+			hashID := placement.Filename
+			if seen[hashID] || layout.Block(hashID) != nil {
+				continue
+			}
+			seen[hashID] = true
+
+			widths := variantWidths[hashID]
+			if len(widths) == 0 {
+				widths = processing.DefaultWidths
+			}
+			base := fmt.Sprintf("%s/images/%s/%s/%s", imageBase, project.Slug, hashID, hashID)
+			p := &lightboxPhoto{
+				HashID:  hashID,
+				Src:     fmt.Sprintf("%s-%dw.webp", base, processing.FallbackWidth(widths, 1920)),
+				Srcset:  processing.Srcset(base, widths, 0),
+				Caption: layout.CaptionFor(hashID),
+				URL:     fmt.Sprintf("%s/%s/%s/", g.baseURL, project.Slug, hashID),
+				Alt:     fmt.Sprintf("Photo %d of %s", len(result)+1, project.Title),
+			}
+			if p.Caption != "" {
+				p.Alt = p.Caption
+			}
+			// Social networks need an absolute image URL
+			if strings.HasPrefix(imageBase, "http://") || strings.HasPrefix(imageBase, "https://") {
+				p.ShareImage = fmt.Sprintf("%s-%dw.webp", base, processing.FallbackWidth(widths, 1200))
+			}
+
+			// Photos may not be on disk at build time, e.g. when images are hosted elsewhere
+			if photo, ok := photosByHash[hashID]; ok {
+				p.RatioWidth, p.RatioHeight = photo.RatioWidth, photo.RatioHeight
+				if photo.Video {
+					p.Video = fmt.Sprintf("%s/images/%s/%s/%s", imageBase, project.Slug, hashID, processing.VideoFilename(hashID))
+				} else if exif {
+					p.Exif = readExif(photo.Path)
+				}
+			}
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// readingOrder returns placements sorted row by row, then from left to right
+func readingOrder(placements []content.PhotoPlacement) []content.PhotoPlacement {
+	sorted := append([]content.PhotoPlacement(nil), placements...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Position, sorted[j].Position
+		if a.TopLeftY != b.TopLeftY {
+			return a.TopLeftY < b.TopLeftY
+		}
+		return a.TopLeftX < b.TopLeftX
+	})
+	return sorted
+}
+
+// readExif returns the camera settings of a photo file, or nil if it has none
+func readExif(path string) *content.ExifData {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Failed to read EXIF data")
+		return nil
+	}
+	defer file.Close()
+	return content.ReadExif(file)
+}
+
+// photoPageDir matches the directories of photo pages, named after hash IDs
+var photoPageDir = regexp.MustCompile(`^[0-9a-f]{12}$`)
+
+// generatePhotoPages writes a page for each lightbox photo at
+// /{project}/{hashID}/, for sharing single photos, and removes the pages of
+// photos no longer shown. data holds the project page's template data.
+func (g *Generator) generatePhotoPages(projectDir string, photos []*lightboxPhoto, data map[string]interface{}) error {
+	current := make(map[string]bool, len(photos))
+	for _, photo := range photos {
+		current[photo.HashID] = true
+	}
+	entries, err := os.ReadDir(projectDir)
+	if err != nil {
+		return fmt.Errorf("failed to read project directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && photoPageDir.MatchString(entry.Name()) && !current[entry.Name()] {
+			if err := os.RemoveAll(filepath.Join(projectDir, entry.Name())); err != nil {
+				return fmt.Errorf("failed to remove stale photo page: %w", err)
+			}
+		}
+	}
+
+	for i, photo := range photos {
+		dir := filepath.Join(projectDir, photo.HashID)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create photo directory: %w", err)
+		}
+
+		pageData := make(map[string]interface{}, len(data)+4)
+		for k, v := range data {
+			pageData[k] = v
+		}
+		pageData["Photo"] = photo
+		pageData["Position"] = i + 1
+		if i > 0 {
+			pageData["Prev"] = photos[i-1]
+		}
+		if i < len(photos)-1 {
+			pageData["Next"] = photos[i+1]
+		}
+
+		if err := g.writePage(filepath.Join(dir, "index.html"), "photo.html", pageData); err != nil {
+			return fmt.Errorf("failed to generate page for photo %s: %w", photo.HashID, err)
+		}
+	}
+	return nil
+}
+
+// writePage renders a template to a file
+func (g *Generator) writePage(path, name string, data interface{}) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create page: %w", err)
+	}
+
+	if err := g.templates.ExecuteTemplate(file, name, data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write page: %w", err)
+	}
+	return nil
+}
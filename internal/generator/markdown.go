@@ -0,0 +1,23 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdown converts project text to HTML. Raw HTML and unsafe links in the
+// source are left out (goldmark's defaults), so content cannot add scripts.
+var markdown = goldmark.New(goldmark.WithExtensions(extension.Linkify, extension.Typographer))
+
+// renderMarkdown renders Markdown source for the site templates
+func renderMarkdown(source string) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		return "", fmt.Errorf("failed to render markdown: %w", err)
+	}
+	return template.HTML(buf.String()), nil
+}
@@ -0,0 +1,136 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+)
+
+// Output directories of the listing pages
+const (
+	tagsDir     = content.TagsPath
+	categoryDir = content.CategoryPath
+)
+
+// term is a tag or category with the published projects that have it
+type term struct {
+	Name     string // As first written, e.g. "Editorial"
+	Slug     string // Used in the listing page's URL
+	Projects []*content.ProjectMetadata
+}
+
+// projectTerms groups projects by the terms names returns for each of them.
+// Terms with the same slug are merged. Projects keep their order, terms are
+// sorted by name.
+func projectTerms(projects []*content.ProjectMetadata, names func(p *content.ProjectMetadata) []string) []*term {
+	bySlug := make(map[string]*term)
+	var terms []*term
+	for _, p := range projects {
+		listed := make(map[string]bool)
+		for _, name := range names(p) {
+			name = strings.TrimSpace(name)
+			slug := content.Slugify(name)
+			if slug == "" || listed[slug] {
+				continue
+			}
+			listed[slug] = true
+
+			t, ok := bySlug[slug]
+			if !ok {
+				t = &term{Name: name, Slug: slug}
+				bySlug[slug] = t
+				terms = append(terms, t)
+			}
+			t.Projects = append(t.Projects, p)
+		}
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		return strings.ToLower(terms[i].Name) < strings.ToLower(terms[j].Name)
+	})
+	return terms
+}
+
+// projectCategories returns the categories of published projects, for the navigation
+func projectCategories(projects []*content.ProjectMetadata) []*term {
+	return projectTerms(projects, func(p *content.ProjectMetadata) []string { return []string{p.Category} })
+}
+
+// generateTaxonomyPages writes a page listing the projects of each tag at
+// /tags/{tag}/ and of each category at /category/{category}/, and removes the
+// pages of terms no longer used
+func (g *Generator) generateTaxonomyPages(projects []*content.ProjectMetadata, buildTimestamp int64) error {
+	siteMeta := g.loadSiteMeta()
+	customCSS, customJS := g.customAssets()
+	data := map[string]interface{}{
+		"BaseURL":        g.baseURL,
+		"WebsiteName":    siteMeta.WebsiteName,
+		"LogoPrimary":    siteMeta.LogoPrimary,
+		"LogoSecondary":  siteMeta.LogoSecondary,
+		"AllProjects":    projects,
+		"Categories":     g.categories,
+		"Copyright":      siteMeta.Copyright,
+		"BuildTimestamp": buildTimestamp,
+		"CustomCSS":      customCSS,
+		"CustomJS":       customJS,
+	}
+
+	kinds := []struct {
+		dir, kind string
+		terms     []*term
+	}{
+		{categoryDir, "Category", g.categories},
+		{tagsDir, "Tag", projectTerms(projects, func(p *content.ProjectMetadata) []string { return p.Tags })},
+	}
+
+	for _, k := range kinds {
+		dir := filepath.Join(g.outputDir, k.dir)
+		current := make(map[string]bool, len(k.terms))
+		for _, t := range k.terms {
+			current[t.Slug] = true
+		}
+		if err := removeStaleDirs(dir, current); err != nil {
+			return err
+		}
+
+		for _, t := range k.terms {
+			if err := os.MkdirAll(filepath.Join(dir, t.Slug), 0755); err != nil {
+				return fmt.Errorf("failed to create %s directory: %w", strings.ToLower(k.kind), err)
+			}
+			pageData := make(map[string]interface{}, len(data)+3)
+			for key, v := range data {
+				pageData[key] = v
+			}
+			pageData["Kind"] = k.kind
+			pageData["Term"] = t
+			pageData["Path"] = k.dir
+
+			if err := g.writePage(filepath.Join(dir, t.Slug, "index.html"), "listing.html", pageData); err != nil {
+				return fmt.Errorf("failed to generate page for %s %s: %w", strings.ToLower(k.kind), t.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// removeStaleDirs removes the directories in dir whose names are not in keep
+func removeStaleDirs(dir string, keep map[string]bool) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !keep[entry.Name()] {
+			if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+				return fmt.Errorf("failed to remove stale page: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+package layouts
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+)
+
+// Style selects how AutoLayout arranges photos
+type Style string
+
+const (
+	// StyleJustified fills each row edge to edge with photos of equal height
+	StyleJustified Style = "justified"
+	// StyleMasonry stacks photos in equal-width columns, always filling the shortest one
+	StyleMasonry Style = "masonry"
+	// StyleGrid gives every photo the same cell size
+	StyleGrid Style = "grid"
+)
+
+// Default grid widths of generated layouts
+const (
+	AutoGridWidth       = 12
+	AutoMobileGridWidth = 6
+)
+
+// Styles lists the supported auto layout styles
+var Styles = []Style{StyleJustified, StyleMasonry, StyleGrid}
+
+// ParseStyle validates an auto layout style name
+func ParseStyle(s string) (Style, error) {
+	for _, style := range Styles {
+		if string(style) == s {
+			return style, nil
+		}
+	}
+	return "", fmt.Errorf("unknown layout style %q (expected justified, masonry or grid)", s)
+}
+
+// AutoLayout arranges photos, in order, on a 12-column desktop grid and a
+// 6-column mobile grid. Grid cells are treated as squares, so a photo's cell
+// span follows its aspect ratio as closely as whole cells allow.
+func AutoLayout(photos []*content.PhotoInfo, style Style) (*content.LayoutConfig, error) {
+	ratios := make([]float64, len(photos))
+	for i, photo := range photos {
+		ratios[i] = photoRatio(photo)
+	}
+
+	desktop, err := Arrange(ratios, style, AutoGridWidth)
+	if err != nil {
+		return nil, err
+	}
+	mobile, err := Arrange(ratios, style, AutoMobileGridWidth)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := &content.LayoutConfig{
+		GridWidth:        AutoGridWidth,
+		Placements:       make([]content.PhotoPlacement, len(photos)),
+		MobileGridWidth:  AutoMobileGridWidth,
+		MobilePlacements: make([]content.PhotoPlacement, len(photos)),
+	}
+	for i, photo := range photos {
+		layout.Placements[i] = content.PhotoPlacement{Filename: photo.HashID, Position: desktop[i]}
+		layout.MobilePlacements[i] = content.PhotoPlacement{Filename: photo.HashID, Position: mobile[i]}
+	}
+	return layout, nil
+}
+
+// PlacementOrder orders photos for AutoLayout: photos already placed on the
+// desktop grid come first in reading order, followed by the unplaced ones
+func PlacementOrder(photos []*content.PhotoInfo, placements []content.PhotoPlacement) []*content.PhotoInfo {
+	sorted := append([]content.PhotoPlacement(nil), placements...)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		pa, pb := sorted[a].Position, sorted[b].Position
+		if pa.TopLeftY != pb.TopLeftY {
+			return pa.TopLeftY < pb.TopLeftY
+		}
+		return pa.TopLeftX < pb.TopLeftX
+	})
+
+	byHash := make(map[string]*content.PhotoInfo, len(photos))
+	for _, photo := range photos {
+		byHash[photo.HashID] = photo
+	}
+
+	ordered := make([]*content.PhotoInfo, 0, len(photos))
+	for _, p := range sorted {
+		if photo, ok := byHash[p.Filename]; ok {
+			ordered = append(ordered, photo)
+			delete(byHash, p.Filename) // A photo placed twice is laid out once
+		}
+	}
+	for _, photo := range photos {
+		if _, ok := byHash[photo.HashID]; ok {
+			ordered = append(ordered, photo)
+		}
+	}
+	return ordered
+}
+
+// Arrange returns one grid position per aspect ratio (width / height) on a grid
+// of the given number of columns
+func Arrange(ratios []float64, style Style, columns int) ([]content.GridPosition, error) {
+	if columns < 1 {
+		return nil, fmt.Errorf("invalid grid width %d", columns)
+	}
+	switch style {
+	case StyleJustified:
+		return arrangeJustified(ratios, columns), nil
+	case StyleMasonry:
+		return arrangeMasonry(ratios, columns), nil
+	case StyleGrid:
+		return arrangeGrid(ratios, columns), nil
+	default:
+		return nil, fmt.Errorf("unknown layout style %q", style)
+	}
+}
+
+// photoRatio returns the width / height ratio of a photo, defaulting to square
+func photoRatio(photo *content.PhotoInfo) float64 {
+	if photo.RatioWidth > 0 && photo.RatioHeight > 0 {
+		return float64(photo.RatioWidth) / float64(photo.RatioHeight)
+	}
+	if photo.AspectRatio > 0 {
+		return photo.AspectRatio
+	}
+	return 1
+}
+
+// columnSpan returns the width of one column in the masonry and grid styles:
+// three columns on wide grids, two on narrow ones
+func columnSpan(columns int) int {
+	switch {
+	case columns >= 8:
+		return columns / 3
+	case columns >= 2:
+		return columns / 2
+	default:
+		return 1
+	}
+}
+
+// arrangeJustified breaks photos into rows that span the full grid width. Each
+// row takes photos until its height gets closest to the target row height.
+func arrangeJustified(ratios []float64, columns int) []content.GridPosition {
+	target := float64(columnSpan(columns))
+	positions := make([]content.GridPosition, 0, len(ratios))
+	y := 1
+
+	for start := 0; start < len(ratios); {
+		end := start + 1
+		sum := ratios[start]
+		for end < len(ratios) && end-start < columns {
+			height := float64(columns) / sum
+			if height <= target {
+				break
+			}
+			next := float64(columns) / (sum + ratios[end])
+			if next < target && target-next > height-target {
+				break // Stopping here is closer to the target height
+			}
+			sum += ratios[end]
+			end++
+		}
+
+		row := ratios[start:end]
+		height := float64(columns) / sum
+		widths := make([]float64, len(row))
+		offset := 0
+		if end == len(ratios) && height > target*1.5 {
+			// Short last row: keep the target height instead of stretching it, centered
+			height = target
+			total := 0
+			for i, r := range row {
+				widths[i] = r * height
+				total += int(math.Max(1, math.Round(widths[i])))
+			}
+			if total <= columns {
+				offset = (columns - total) / 2
+			} else {
+				height = float64(columns) / sum
+				for i, r := range row {
+					widths[i] = r * height
+				}
+			}
+		} else {
+			for i, r := range row {
+				widths[i] = r * height
+			}
+		}
+
+		cells := roundSpans(widths, columns)
+		rows := int(math.Max(1, math.Round(height)))
+		x := 1 + offset
+		for _, w := range cells {
+			positions = append(positions, content.GridPosition{
+				TopLeftX:     x,
+				TopLeftY:     y,
+				BottomRightX: x + w - 1,
+				BottomRightY: y + rows - 1,
+			})
+			x += w
+		}
+		y += rows
+		start = end
+	}
+	return positions
+}
+
+// roundSpans rounds widths to whole cells of at least 1. If the widths add up
+// to the grid width (a full justified row) the rounded spans do too, using the
+// largest remainders; otherwise they are capped at max.
+func roundSpans(widths []float64, max int) []int {
+	total := 0.0
+	for _, w := range widths {
+		total += w
+	}
+	spans := make([]int, len(widths))
+	sum := 0
+	for i, w := range widths {
+		spans[i] = int(math.Max(1, math.Floor(w)))
+		sum += spans[i]
+	}
+
+	full := math.Abs(total-float64(max)) < 1e-9
+	if !full {
+		// Partial row: round each span, staying within the grid
+		sum = 0
+		for i, w := range widths {
+			spans[i] = int(math.Max(1, math.Round(w)))
+			sum += spans[i]
+		}
+		for i := len(spans) - 1; sum > max && i >= 0; i-- {
+			for spans[i] > 1 && sum > max {
+				spans[i]--
+				sum--
+			}
+		}
+		return spans
+	}
+
+	// Hand out the remaining cells to the largest remainders, or take cells back
+	// from the widest spans if the minimum of 1 overshot the grid
+	order := make([]int, len(widths))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return widths[order[a]]-math.Floor(widths[order[a]]) > widths[order[b]]-math.Floor(widths[order[b]])
+	})
+	for i := 0; sum < max; i = (i + 1) % len(order) {
+		spans[order[i]]++
+		sum++
+	}
+	for sum > max {
+		widest := 0
+		for i := range spans {
+			if spans[i] > spans[widest] {
+				widest = i
+			}
+		}
+		spans[widest]--
+		sum--
+	}
+	return spans
+}
+
+// arrangeMasonry places each photo in the shortest column, with a height that
+// follows its aspect ratio
+func arrangeMasonry(ratios []float64, columns int) []content.GridPosition {
+	span := columnSpan(columns)
+	heights := make([]int, columns/span) // Rows used per column
+	positions := make([]content.GridPosition, 0, len(ratios))
+
+	for _, r := range ratios {
+		col := 0
+		for i := range heights {
+			if heights[i] < heights[col] {
+				col = i
+			}
+		}
+		rows := int(math.Max(1, math.Round(float64(span)/r)))
+		x := 1 + col*span
+		positions = append(positions, content.GridPosition{
+			TopLeftX:     x,
+			TopLeftY:     heights[col] + 1,
+			BottomRightX: x + span - 1,
+			BottomRightY: heights[col] + rows,
+		})
+		heights[col] += rows
+	}
+	return positions
+}
+
+// arrangeGrid gives every photo the same cell, shaped after the median aspect ratio
+func arrangeGrid(ratios []float64, columns int) []content.GridPosition {
+	if len(ratios) == 0 {
+		return []content.GridPosition{}
+	}
+	sorted := append([]float64(nil), ratios...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	span := columnSpan(columns)
+	perRow := columns / span
+	rows := int(math.Max(1, math.Round(float64(span)/median)))
+	positions := make([]content.GridPosition, len(ratios))
+	for i := range ratios {
+		x := 1 + (i%perRow)*span
+		y := 1 + (i/perRow)*rows
+		positions[i] = content.GridPosition{
+			TopLeftX:     x,
+			TopLeftY:     y,
+			BottomRightX: x + span - 1,
+			BottomRightY: y + rows - 1,
+		}
+	}
+	return positions
+}
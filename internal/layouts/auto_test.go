@@ -0,0 +1,82 @@
+package layouts
+
+import (
+	"testing"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+)
+
+// TestArrangeValidPlacements checks that every style yields in-grid, non-overlapping cells
+func TestArrangeValidPlacements(t *testing.T) {
+	ratios := []float64{1.5, 0.667, 1.5, 1, 1.778, 0.8, 0.667, 0.667, 1.5, 0.5625, 1.25, 1.5, 2.5}
+
+	for _, style := range Styles {
+		for _, columns := range []int{AutoGridWidth, AutoMobileGridWidth} {
+			positions, err := Arrange(ratios, style, columns)
+			if err != nil {
+				t.Fatalf("%s/%d: %v", style, columns, err)
+			}
+			if len(positions) != len(ratios) {
+				t.Fatalf("%s/%d: got %d positions, want %d", style, columns, len(positions), len(ratios))
+			}
+
+			occupied := make(map[[2]int]bool)
+			for i, pos := range positions {
+				if pos.TopLeftX < 1 || pos.TopLeftY < 1 || pos.BottomRightX > columns ||
+					pos.BottomRightX < pos.TopLeftX || pos.BottomRightY < pos.TopLeftY {
+					t.Fatalf("%s/%d: photo %d has invalid position %+v", style, columns, i, pos)
+				}
+				for x := pos.TopLeftX; x <= pos.BottomRightX; x++ {
+					for y := pos.TopLeftY; y <= pos.BottomRightY; y++ {
+						if occupied[[2]int{x, y}] {
+							t.Fatalf("%s/%d: photo %d overlaps at %d,%d", style, columns, i, x, y)
+						}
+						occupied[[2]int{x, y}] = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestJustifiedRowsFillGrid checks that full justified rows span every column
+func TestJustifiedRowsFillGrid(t *testing.T) {
+	positions, err := Arrange([]float64{1.5, 1.5, 0.667, 0.667, 0.667, 1.5, 1.5}, StyleJustified, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := make(map[int]int) // Top row -> covered columns
+	for _, pos := range positions {
+		rows[pos.TopLeftY] += pos.BottomRightX - pos.TopLeftX + 1
+	}
+	if rows[1] != 12 {
+		t.Errorf("First row covers %d columns, want 12", rows[1])
+	}
+	if len(rows) < 2 {
+		t.Errorf("Expected several rows, got %v", rows)
+	}
+}
+
+// TestAutoLayout checks both grids reference the photos in order
+func TestAutoLayout(t *testing.T) {
+	photos := []*content.PhotoInfo{
+		{HashID: "aaa", RatioWidth: 3, RatioHeight: 2},
+		{HashID: "bbb", RatioWidth: 2, RatioHeight: 3},
+	}
+	layout, err := AutoLayout(photos, StyleMasonry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layout.GridWidth != AutoGridWidth || layout.MobileGridWidth != AutoMobileGridWidth {
+		t.Errorf("Grid widths = %d/%d", layout.GridWidth, layout.MobileGridWidth)
+	}
+	for i, placements := range [][]content.PhotoPlacement{layout.Placements, layout.MobilePlacements} {
+		if len(placements) != 2 || placements[0].Filename != "aaa" || placements[1].Filename != "bbb" {
+			t.Errorf("Placements %d = %+v", i, placements)
+		}
+	}
+	if _, err := ParseStyle("mosaic"); err == nil {
+		t.Error("Expected an error for an unknown style")
+	}
+}
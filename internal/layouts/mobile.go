@@ -0,0 +1,109 @@
+package layouts
+
+import (
+	"math"
+	"sort"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+)
+
+// DeriveMobile converts the desktop placements of a layout to a mobile grid of
+// mobileGridWidth columns. Photos keep their desktop reading order (top to
+// bottom, left to right). Photos narrower than half the desktop grid take half
+// the mobile width and are paired side by side; wider ones take the full width.
+// Row spans follow the aspect ratio of the desktop cells.
+func DeriveMobile(layout *content.LayoutConfig, mobileGridWidth int) []content.PhotoPlacement {
+	gridWidth := layout.GridWidth
+	if gridWidth <= 0 {
+		gridWidth = 12
+	}
+	if mobileGridWidth <= 0 {
+		mobileGridWidth = AutoMobileGridWidth
+	}
+	half := mobileGridWidth / 2
+	if half < 1 {
+		half = 1
+	}
+
+	sorted := append([]content.PhotoPlacement(nil), layout.Placements...)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		pa, pb := sorted[a].Position, sorted[b].Position
+		if pa.TopLeftY != pb.TopLeftY {
+			return pa.TopLeftY < pb.TopLeftY
+		}
+		return pa.TopLeftX < pb.TopLeftX
+	})
+
+	type item struct {
+		filename string
+		ratio    float64 // Desktop cell height / width
+		width    int
+	}
+	rowsFor := func(it item, width int) int {
+		return int(math.Max(1, math.Round(float64(width)*it.ratio)))
+	}
+
+	placements := make([]content.PhotoPlacement, 0, len(sorted))
+	y := 1
+	var row []item
+	used := 0
+	flush := func() {
+		if len(row) == 0 {
+			return
+		}
+		if len(row) == 1 {
+			row[0].width = mobileGridWidth // A photo alone in its row fills it
+		}
+		total := 0
+		for _, it := range row {
+			total += rowsFor(it, it.width)
+		}
+		height := int(math.Max(1, math.Round(float64(total)/float64(len(row)))))
+
+		x := 1
+		for _, it := range row {
+			placements = append(placements, content.PhotoPlacement{
+				Filename: it.filename,
+				Position: content.GridPosition{
+					TopLeftX:     x,
+					TopLeftY:     y,
+					BottomRightX: x + it.width - 1,
+					BottomRightY: y + height - 1,
+				},
+			})
+			x += it.width
+		}
+		y += height
+		row = nil
+		used = 0
+	}
+
+	for _, p := range sorted {
+		cols := p.Position.BottomRightX - p.Position.TopLeftX + 1
+		rows := p.Position.BottomRightY - p.Position.TopLeftY + 1
+		it := item{filename: p.Filename, ratio: float64(rows) / float64(cols), width: mobileGridWidth}
+		if float64(cols*mobileGridWidth)/float64(gridWidth) <= float64(half) {
+			it.width = half
+		}
+		if used+it.width > mobileGridWidth {
+			flush()
+		}
+		row = append(row, it)
+		used += it.width
+	}
+	flush()
+
+	return placements
+}
+
+// WithMobileLayout returns the layout itself if it has a mobile layout, or a
+// copy with a mobile layout derived from the desktop placements
+func WithMobileLayout(layout *content.LayoutConfig) *content.LayoutConfig {
+	if layout.HasMobileLayout() || len(layout.Placements) == 0 {
+		return layout
+	}
+	derived := *layout
+	derived.MobileGridWidth = AutoMobileGridWidth
+	derived.MobilePlacements = DeriveMobile(layout, derived.MobileGridWidth)
+	return &derived
+}
@@ -0,0 +1,68 @@
+package layouts
+
+import (
+	"testing"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+)
+
+func placement(hashID string, x1, y1, x2, y2 int) content.PhotoPlacement {
+	return content.PhotoPlacement{
+		Filename: hashID,
+		Position: content.GridPosition{TopLeftX: x1, TopLeftY: y1, BottomRightX: x2, BottomRightY: y2},
+	}
+}
+
+// TestDeriveMobile checks reading order, pairing of narrow photos and full-width rows
+func TestDeriveMobile(t *testing.T) {
+	layout := &content.LayoutConfig{
+		GridWidth: 12,
+		Placements: []content.PhotoPlacement{
+			placement("wide", 1, 5, 12, 10),  // Second row, full width
+			placement("right", 7, 1, 12, 4),  // First row, right half
+			placement("left", 1, 1, 6, 4),    // First row, left half
+			placement("alone", 1, 11, 4, 16), // Narrow, but alone in its mobile row
+		},
+	}
+
+	got := DeriveMobile(layout, 6)
+	want := []content.PhotoPlacement{
+		placement("left", 1, 1, 3, 2),
+		placement("right", 4, 1, 6, 2),
+		placement("wide", 1, 3, 6, 5),
+		placement("alone", 1, 6, 6, 14),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Got %d placements, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Placement %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestWithMobileLayout checks that hand-made mobile layouts are left alone
+func TestWithMobileLayout(t *testing.T) {
+	layout := &content.LayoutConfig{
+		GridWidth:  12,
+		Placements: []content.PhotoPlacement{placement("a", 1, 1, 12, 8), placement("b", 1, 9, 6, 12)},
+	}
+
+	derived := WithMobileLayout(layout)
+	if !derived.HasMobileLayout() || len(derived.MobilePlacements) != 2 {
+		t.Fatalf("Expected a derived mobile layout, got %+v", derived)
+	}
+	if layout.HasMobileLayout() {
+		t.Error("WithMobileLayout modified the original layout")
+	}
+
+	layout.MobileGridWidth = 4
+	layout.MobilePlacements = []content.PhotoPlacement{placement("a", 1, 1, 4, 3)}
+	if WithMobileLayout(layout) != layout {
+		t.Error("Expected an existing mobile layout to be kept")
+	}
+	if missing := layout.MissingFromMobile(); len(missing) != 1 || missing[0] != "b" {
+		t.Errorf("MissingFromMobile = %v, want [b]", missing)
+	}
+}
@@ -0,0 +1,67 @@
+package processing
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+)
+
+// CropConfig configures art-directed crops rendered alongside the regular variants
+type CropConfig struct {
+	RatioWidth  int                           // Crop aspect ratio width (e.g. 4 for 4:5)
+	RatioHeight int                           // Crop aspect ratio height (e.g. 5 for 4:5)
+	Widths      []int                         // Widths to render the crop at
+	FocalPoints map[string]content.FocalPoint // Focal points keyed by hash ID (center when missing)
+}
+
+// IsActive returns true if crops should be rendered
+func (c *CropConfig) IsActive() bool {
+	return c != nil && c.RatioWidth > 0 && c.RatioHeight > 0 && len(c.Widths) > 0
+}
+
+// Name returns the variant name segment of the crop, e.g. "crop4x5"
+func (c *CropConfig) Name() string {
+	return content.CropName(c.RatioWidth, c.RatioHeight)
+}
+
+// focalPoint returns the focal point for a photo, defaulting to the center
+func (c *CropConfig) focalPoint(hashID string) content.FocalPoint {
+	if fp, ok := c.FocalPoints[hashID]; ok {
+		return fp
+	}
+	return content.FocalPoint{X: 0.5, Y: 0.5}
+}
+
+// CropFilename returns the filename of a crop variant
+func CropFilename(hashID, cropName string, width int) string {
+	return fmt.Sprintf("%s-%s-%dw.webp", hashID, cropName, width)
+}
+
+// cropToRatio cuts the largest region of the given aspect ratio out of img,
+// keeping the focal point as close to the center of the region as possible
+func cropToRatio(img image.Image, ratioW, ratioH int, fp content.FocalPoint) image.Image {
+	bounds := img.Bounds()
+	width := float64(bounds.Dx())
+	height := float64(bounds.Dy())
+	target := float64(ratioW) / float64(ratioH)
+
+	cropW, cropH := width, height
+	if width/height > target {
+		cropW = math.Round(height * target)
+	} else {
+		cropH = math.Round(width / target)
+	}
+
+	x0 := clampFloat(fp.X*width-cropW/2, 0, width-cropW)
+	y0 := clampFloat(fp.Y*height-cropH/2, 0, height-cropH)
+
+	rect := image.Rect(int(x0), int(y0), int(x0+cropW), int(y0+cropH)).Add(bounds.Min)
+	return imaging.Crop(img, rect)
+}
+
+func clampFloat(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(v, hi))
+}
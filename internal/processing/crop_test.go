@@ -0,0 +1,96 @@
+package processing
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+)
+
+// TestCropToRatio checks the crop size and how the focal point moves the crop
+// without leaving the image
+func TestCropToRatio(t *testing.T) {
+	// 100x50 landscape with a white column at x=10
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 50))
+	for y := 0; y < 50; y++ {
+		img.Set(10, y, color.White)
+	}
+
+	tests := []struct {
+		name   string
+		fp     content.FocalPoint
+		whiteX int // Expected column of the white line in the crop, -1 if cut off
+	}{
+		{"center", content.FocalPoint{X: 0.5, Y: 0.5}, -1},
+		{"left edge", content.FocalPoint{X: 0, Y: 0.5}, 10},
+		{"near the line", content.FocalPoint{X: 0.25, Y: 0.5}, 5},
+		{"right edge", content.FocalPoint{X: 1, Y: 0.5}, -1},
+	}
+	for _, tc := range tests {
+		cropped := cropToRatio(img, 4, 5, tc.fp)
+		if b := cropped.Bounds(); b.Dx() != 40 || b.Dy() != 50 {
+			t.Fatalf("%s: expected a 40x50 crop, got %v", tc.name, b.Size())
+		}
+		whiteX := -1
+		for x := 0; x < 40; x++ {
+			if r, _, _, _ := cropped.At(x, 0).RGBA(); r > 0 {
+				whiteX = x
+			}
+		}
+		if whiteX != tc.whiteX {
+			t.Errorf("%s: expected the line at x=%d, got %d", tc.name, tc.whiteX, whiteX)
+		}
+	}
+
+	// Portraits are cut vertically
+	if b := cropToRatio(image.NewNRGBA(image.Rect(0, 0, 40, 100)), 1, 1, content.FocalPoint{X: 0.5, Y: 0.5}).Bounds(); b.Dx() != 40 || b.Dy() != 40 {
+		t.Errorf("Expected a 40x40 crop of a portrait, got %v", b.Size())
+	}
+}
+
+// TestCropNames checks the crop variant filenames
+func TestCropNames(t *testing.T) {
+	crop := &CropConfig{RatioWidth: 4, RatioHeight: 5, Widths: []int{480}}
+	if got := crop.Name(); got != "crop4x5" {
+		t.Errorf("Name() = %q, want crop4x5", got)
+	}
+	if got := CropFilename("a1b2c3d4e5f6", crop.Name(), 480); got != "a1b2c3d4e5f6-crop4x5-480w.webp" {
+		t.Errorf("CropFilename() = %q", got)
+	}
+	if (&CropConfig{RatioWidth: 4, RatioHeight: 5}).IsActive() {
+		t.Error("Expected a crop without widths to be inactive")
+	}
+}
+
+// TestFingerprintFor checks that moving a focal point only invalidates that photo
+func TestFingerprintFor(t *testing.T) {
+	fingerprints := func(focal map[string]content.FocalPoint) (string, string) {
+		t.Helper()
+		p := NewProcessor(ProcessConfig{Crop: &CropConfig{RatioWidth: 4, RatioHeight: 5, Widths: []int{480}, FocalPoints: focal}})
+		if err := p.prepare(); err != nil {
+			t.Fatal(err)
+		}
+		return p.fingerprintFor("aaaaaaaaaaaa"), p.fingerprintFor("bbbbbbbbbbbb")
+	}
+
+	a, b := fingerprints(nil)
+	if a != b {
+		t.Error("Expected photos with the default focal point to share a fingerprint")
+	}
+	movedA, movedB := fingerprints(map[string]content.FocalPoint{"aaaaaaaaaaaa": {X: 0.2, Y: 0.5}})
+	if movedA == a {
+		t.Error("Expected a new focal point to change the photo's fingerprint")
+	}
+	if movedB != b {
+		t.Error("Expected other photos to keep their fingerprint")
+	}
+
+	plain := NewProcessor(ProcessConfig{})
+	if err := plain.prepare(); err != nil {
+		t.Fatal(err)
+	}
+	if plain.fingerprintFor("aaaaaaaaaaaa") != plain.fingerprint || plain.fingerprint == a {
+		t.Error("Expected the crop to be part of the fingerprint only when configured")
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// fingerprintFilename stores the settings fingerprint of a photo's variants
+const fingerprintFilename = ".fingerprint"
+
 // Destination provides explicit methods for writing processed images and thumbnails
 // to their respective locations
 type Destination struct {
@@ -23,6 +27,35 @@ func (d *Destination) CreateVariant(hashID, filename string) (io.WriteCloser, er
 	return os.Create(fullPath)
 }
 
+// SaveVariant writes the content of r to OutputDir/{hashID}/{filename}. The
+// file is written next to its target first, so that an interrupted write
+// leaves no partial variant behind.
+func (d *Destination) SaveVariant(hashID, filename string, r io.Reader) error {
+	fullPath := filepath.Join(d.OutputDir, hashID, filename)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("failed to create variant directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), "."+filename+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), fullPath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save %s: %w", filename, err)
+	}
+	return nil
+}
+
 // VariantExists checks if a variant file exists
 func (d *Destination) VariantExists(hashID, filename string) bool {
 	path := filepath.Join(d.OutputDir, hashID, filename)
@@ -30,6 +63,41 @@ func (d *Destination) VariantExists(hashID, filename string) bool {
 	return err == nil
 }
 
+// RemoveVariant deletes a variant file if it exists
+func (d *Destination) RemoveVariant(hashID, filename string) error {
+	err := os.Remove(filepath.Join(d.OutputDir, hashID, filename))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Fingerprint returns the settings fingerprint the variants of hashID were
+// generated with, or an empty string if none was recorded
+func (d *Destination) Fingerprint(hashID string) string {
+	data, err := os.ReadFile(filepath.Join(d.OutputDir, hashID, fingerprintFilename))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// WriteFingerprint records the settings fingerprint for the variants of hashID.
+// An empty fingerprint removes any previously recorded value.
+func (d *Destination) WriteFingerprint(hashID, fingerprint string) error {
+	path := filepath.Join(d.OutputDir, hashID, fingerprintFilename)
+	if fingerprint == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create variant directory: %w", err)
+	}
+	return os.WriteFile(path, []byte(fingerprint+"\n"), 0644)
+}
+
 // CreateThumbnail creates a file for a thumbnail in OutputDir/.thumbs/{filename}
 func (d *Destination) CreateThumbnail(filename string) (io.WriteCloser, error) {
 	fullPath := filepath.Join(d.OutputDir, ".thumbs", filename)
@@ -45,3 +113,40 @@ func (d *Destination) ThumbnailExists(filename string) bool {
 	_, err := os.Stat(path)
 	return err == nil
 }
+
+// RemovePhoto deletes all variants and the thumbnail of a photo
+func (d *Destination) RemovePhoto(hashID string) error {
+	if err := os.RemoveAll(filepath.Join(d.OutputDir, hashID)); err != nil {
+		return fmt.Errorf("failed to remove variants: %w", err)
+	}
+	thumb := filepath.Join(d.OutputDir, ".thumbs", fmt.Sprintf("thumb-%s.webp", hashID))
+	if err := os.Remove(thumb); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove thumbnail: %w", err)
+	}
+	return nil
+}
+
+// MovePhoto moves the variants and thumbnail of a photo to another destination.
+// Files that were never generated are skipped.
+func (d *Destination) MovePhoto(hashID string, to *Destination) error {
+	thumbName := fmt.Sprintf("thumb-%s.webp", hashID)
+	moves := [][2]string{
+		{filepath.Join(d.OutputDir, hashID), filepath.Join(to.OutputDir, hashID)},
+		{filepath.Join(d.OutputDir, ".thumbs", thumbName), filepath.Join(to.OutputDir, ".thumbs", thumbName)},
+	}
+	for _, move := range moves {
+		if _, err := os.Stat(move[0]); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(move[1]), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.RemoveAll(move[1]); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", move[1], err)
+		}
+		if err := os.Rename(move[0], move[1]); err != nil {
+			return fmt.Errorf("failed to move %s: %w", move[0], err)
+		}
+	}
+	return nil
+}
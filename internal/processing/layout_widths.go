@@ -0,0 +1,154 @@
+package processing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/layouts"
+)
+
+// DefaultWidths are the variant widths rendered for every photo unless configured otherwise
+var DefaultWidths = []int{480, 800, 1200, 1920}
+
+// MobileCropWidths are the widths art-directed mobile crops are rendered at
+var MobileCropWidths = []int{480, 800, 1200}
+
+// Display assumptions used to size variants from grid spans
+const (
+	layoutContainerWidth = 1400 // Max desktop gallery width in CSS pixels
+	layoutMobileWidth    = 430  // Large phone viewport in CSS pixels
+	layoutPixelDensity   = 2    // Render for high-DPI screens
+)
+
+// LayoutWidths returns, for each photo placed in the layout, the subset of widths
+// needed to cover the largest cell the photo occupies on desktop or mobile.
+// Photos not in the layout are not included.
+func LayoutWidths(layout *content.LayoutConfig, widths []int) map[string][]int {
+	required := make(map[string]int)
+	need := func(hashID string, pixels int) {
+		if layout.Block(hashID) != nil {
+			return // Blocks have no image variants
+		}
+		if pixels > required[hashID] {
+			required[hashID] = pixels
+		}
+	}
+
+	gridWidth := layout.GridWidth
+	if gridWidth <= 0 {
+		gridWidth = 12
+	}
+	for _, placement := range layout.Placements {
+		need(placement.Filename, spanPixels(placement, gridWidth, layoutContainerWidth))
+		if !layout.HasMobileLayout() {
+			// Default mobile layout is a single full-width column
+			need(placement.Filename, layoutMobileWidth*layoutPixelDensity)
+		}
+	}
+
+	if layout.HasMobileLayout() {
+		mobileGridWidth := layout.MobileGridWidth
+		if mobileGridWidth <= 0 {
+			mobileGridWidth = 6
+		}
+		for _, placement := range layout.MobilePlacements {
+			need(placement.Filename, spanPixels(placement, mobileGridWidth, layoutMobileWidth))
+		}
+	}
+
+	result := make(map[string][]int, len(required))
+	for hashID, pixels := range required {
+		result[hashID] = WidthsCovering(pixels, widths)
+	}
+	return result
+}
+
+// spanPixels returns the device pixels needed to fill a placement's column span
+func spanPixels(placement content.PhotoPlacement, gridWidth, containerWidth int) int {
+	colSpan := placement.Position.BottomRightX - placement.Position.TopLeftX + 1
+	cssPixels := float64(colSpan*containerWidth) / float64(gridWidth)
+	return int(math.Ceil(cssPixels * layoutPixelDensity))
+}
+
+// WidthsCovering returns the widths up to and including the smallest one that is
+// at least pixels wide (all widths if none is large enough)
+func WidthsCovering(pixels int, widths []int) []int {
+	sorted := append([]int{}, widths...)
+	sort.Ints(sorted)
+	for i, w := range sorted {
+		if w >= pixels {
+			return sorted[:i+1]
+		}
+	}
+	return sorted
+}
+
+// Srcset builds a srcset attribute value for the variants of base (e.g.
+// "https://host/images/slug/hash/hash") at the given widths, skipping widths above max (0 = no limit)
+func Srcset(base string, widths []int, max int) string {
+	var entries []string
+	for _, w := range widths {
+		if max > 0 && w > max {
+			continue
+		}
+		entries = append(entries, fmt.Sprintf("%s-%dw.webp %dw", base, w, w))
+	}
+	return strings.Join(entries, ", ")
+}
+
+// FallbackWidth returns the width used for the plain src attribute: preferred if
+// available, otherwise the largest width not above it (or the smallest width)
+func FallbackWidth(widths []int, preferred int) int {
+	best := 0
+	for _, w := range widths {
+		if w == preferred {
+			return w
+		}
+		if w < preferred && w > best {
+			best = w
+		}
+	}
+	if best == 0 && len(widths) > 0 {
+		best = widths[0]
+		for _, w := range widths {
+			if w < best {
+				best = w
+			}
+		}
+	}
+	return best
+}
+
+// ProjectWidths returns the per-photo variant widths of a project when layout-aware
+// widths are enabled in site.yaml, or nil when every photo uses the full widths.
+// The project's hero photo always keeps the full widths since it is shown outside the grid.
+func ProjectWidths(contentMgr *content.Manager, slug string, widths []int) (map[string][]int, error) {
+	siteMeta, err := contentMgr.LoadSiteMeta()
+	if err != nil {
+		return nil, err
+	}
+	if !siteMeta.LayoutAwareWidths {
+		return nil, nil
+	}
+
+	layout, err := contentMgr.GetLayout(slug)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// Photos without a project layout are not placed anywhere yet
+			return nil, nil
+		}
+		return nil, err
+	}
+	// Size variants for the mobile layout the site is built with
+	result := LayoutWidths(layouts.WithMobileLayout(layout), widths)
+
+	if project, err := contentMgr.GetProject(slug); err == nil && project.HeroPhoto != "" {
+		delete(result, project.HeroPhoto)
+	}
+	return result, nil
+}
@@ -0,0 +1,65 @@
+package processing
+
+import (
+	"reflect"
+	"testing"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+)
+
+func placement(hashID string, fromX, toX int) content.PhotoPlacement {
+	return content.PhotoPlacement{
+		Filename: hashID,
+		Position: content.GridPosition{TopLeftX: fromX, TopLeftY: 1, BottomRightX: toX, BottomRightY: 1},
+	}
+}
+
+func TestLayoutWidths(t *testing.T) {
+	layout := &content.LayoutConfig{
+		GridWidth: 12,
+		Placements: []content.PhotoPlacement{
+			placement("small", 1, 3),
+			placement("wide", 4, 12),
+		},
+	}
+
+	got := LayoutWidths(layout, DefaultWidths)
+
+	// Small cells still cover the default full-width mobile column
+	if want := []int{480, 800, 1200}; !reflect.DeepEqual(got["small"], want) {
+		t.Errorf("small: got %v, want %v", got["small"], want)
+	}
+	if want := DefaultWidths; !reflect.DeepEqual(got["wide"], want) {
+		t.Errorf("wide: got %v, want %v", got["wide"], want)
+	}
+}
+
+func TestLayoutWidthsMobileLayout(t *testing.T) {
+	layout := &content.LayoutConfig{
+		GridWidth:        12,
+		Placements:       []content.PhotoPlacement{placement("a", 1, 2)},
+		MobileGridWidth:  6,
+		MobilePlacements: []content.PhotoPlacement{placement("a", 1, 2)},
+	}
+
+	got := LayoutWidths(layout, DefaultWidths)
+	if want := []int{480}; !reflect.DeepEqual(got["a"], want) {
+		t.Errorf("got %v, want %v", got["a"], want)
+	}
+}
+
+func TestFallbackWidth(t *testing.T) {
+	tests := []struct {
+		widths []int
+		want   int
+	}{
+		{[]int{480, 800, 1200}, 800},
+		{[]int{480}, 480},
+		{[]int{1200, 1920}, 1200},
+	}
+	for _, tt := range tests {
+		if got := FallbackWidth(tt.widths, 800); got != tt.want {
+			t.Errorf("FallbackWidth(%v, 800) = %d, want %d", tt.widths, got, tt.want)
+		}
+	}
+}
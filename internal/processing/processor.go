@@ -1,14 +1,19 @@
 package processing
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"image"
 	"io"
+	"os"
+	"sync"
 
 	"github.com/chai2010/webp"
 	"github.com/disintegration/imaging"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
 )
 
 // ImageSource abstracts the reading of an image
@@ -24,17 +29,28 @@ type ProcessConfig struct {
 	Force              bool // Overwrite existing files
 	GenerateThumbnails bool
 	ThumbnailWidth     int
+	Watermark          *content.WatermarkSettings // Optional overlay for variants (never applied to thumbnails)
+	Sharpen            *SharpenConfig             // Optional unsharp mask applied after resizing
+	QualityCurve       QualityCurve               // Optional per-width quality for variants (falls back to Quality)
+	Crop               *CropConfig                // Optional art-directed crops (e.g. for mobile cells)
+	VariantWidths      map[string][]int           // Optional per-photo widths keyed by hash ID (layout-aware mode); others use Widths
+	FFmpeg             string                     // ffmpeg binary used for video clips (default: ffmpeg on the PATH)
 }
 
 // Processor handles the image processing pipeline
 type Processor struct {
 	Config ProcessConfig
+
+	prepareOnce sync.Once
+	prepareErr  error
+	watermark   *watermarker
+	fingerprint string
 }
 
 // NewProcessor creates a new processor
 func NewProcessor(config ProcessConfig) *Processor {
 	if len(config.Widths) == 0 {
-		config.Widths = []int{480, 800, 1200, 1920}
+		config.Widths = DefaultWidths
 	}
 	if config.Quality == 0 {
 		config.Quality = 80
@@ -61,41 +77,209 @@ func (p *Processor) ComputeHash(src ImageSource) (string, error) {
 	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
-// ProcessImage processes a single image: hash -> resize -> convert -> save
-func (p *Processor) ProcessImage(src ImageSource, dst *Destination) error {
-	// 1. Compute Hash
-	hash, err := p.ComputeHash(src)
-	if err != nil {
-		return err
+// prepare sets up the optional pipeline stages and the settings fingerprint once per processor
+func (p *Processor) prepare() error {
+	p.prepareOnce.Do(func() {
+		if err := p.Config.Sharpen.Validate(); err != nil {
+			p.prepareErr = err
+			return
+		}
+
+		if p.Config.Watermark.IsActive() {
+			wm, err := newWatermarker(p.Config.Watermark)
+			if err != nil {
+				p.prepareErr = err
+				return
+			}
+			p.watermark = wm
+		}
+
+		fingerprint, err := p.settingsFingerprint()
+		if err != nil {
+			p.prepareErr = err
+			return
+		}
+		p.fingerprint = fingerprint
+	})
+	return p.prepareErr
+}
+
+// Fingerprint identifies the settings that alter variant pixels beyond plain
+// resizing (quality, watermark, sharpening, quality curve, crops)
+func (p *Processor) Fingerprint() (string, error) {
+	if err := p.prepare(); err != nil {
+		return "", err
 	}
+	return p.fingerprint, nil
+}
 
-	hashID := hash[:12]
+// settingsFingerprint hashes the encoding quality, the optional stage settings
+// and the watermark image content
+func (p *Processor) settingsFingerprint() (string, error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "quality %d", p.Config.Quality)
 
-	// 2. Check if processing is needed (skip if all files exist and not forcing)
-	if !p.Config.Force {
-		allExist := true
+	if p.watermark != nil {
+		settings := p.Config.Watermark
+		data, err := json.Marshal(settings)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode watermark settings: %w", err)
+		}
+		hash.Write(data)
 
-		// Check all variant files
-		for _, width := range p.Config.Widths {
-			filename := fmt.Sprintf("%s-%dw.webp", hashID, width)
-			if !dst.VariantExists(hashID, filename) {
-				allExist = false
-				break
+		if settings.Image != "" {
+			file, err := os.Open(settings.Image)
+			if err != nil {
+				return "", fmt.Errorf("failed to open watermark image: %w", err)
+			}
+			defer file.Close()
+			if _, err := io.Copy(hash, file); err != nil {
+				return "", fmt.Errorf("failed to hash watermark image: %w", err)
 			}
 		}
+	}
 
-		// Check thumbnail if enabled
-		if allExist && p.Config.GenerateThumbnails {
-			thumbFilename := fmt.Sprintf("thumb-%s.webp", hashID)
-			if !dst.ThumbnailExists(thumbFilename) {
-				allExist = false
-			}
+	if p.Config.Sharpen.IsActive() {
+		data, err := json.Marshal(p.Config.Sharpen)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode sharpen settings: %w", err)
 		}
+		hash.Write([]byte("sharpen"))
+		hash.Write(data)
+	}
 
-		if allExist {
-			// All files exist, skip processing
-			return nil
+	if len(p.Config.QualityCurve) > 0 {
+		// json.Marshal sorts map keys, so the encoding is stable
+		data, err := json.Marshal(p.Config.QualityCurve)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode quality curve: %w", err)
 		}
+		hash.Write([]byte("quality"))
+		hash.Write(data)
+	}
+
+	if p.Config.Crop.IsActive() {
+		hash.Write([]byte(fmt.Sprintf("crop %s %v", p.Config.Crop.Name(), p.Config.Crop.Widths)))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:16], nil
+}
+
+// fingerprintFor returns the settings fingerprint of a single photo. Crops also
+// depend on the photo's focal point, so moving it invalidates that photo only.
+func (p *Processor) fingerprintFor(hashID string) string {
+	if !p.Config.Crop.IsActive() {
+		return p.fingerprint
+	}
+	fp := p.Config.Crop.focalPoint(hashID)
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s %.4f %.4f", p.fingerprint, fp.X, fp.Y)))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// widthsFor returns the variant widths to render for a photo
+func (p *Processor) widthsFor(hashID string) []int {
+	if widths, ok := p.Config.VariantWidths[hashID]; ok && len(widths) > 0 {
+		return widths
+	}
+	return p.Config.Widths
+}
+
+// removeStaleVariants deletes variants at configured widths the photo no longer
+// needs (e.g. after it moved to a smaller cell in layout-aware mode)
+func (p *Processor) removeStaleVariants(dst *Destination, hashID string, widths []int) error {
+	needed := make(map[int]bool, len(widths))
+	for _, w := range widths {
+		needed[w] = true
+	}
+	for _, w := range p.Config.Widths {
+		if needed[w] {
+			continue
+		}
+		if err := dst.RemoveVariant(hashID, fmt.Sprintf("%s-%dw.webp", hashID, w)); err != nil {
+			return fmt.Errorf("failed to remove stale variant: %w", err)
+		}
+	}
+	return nil
+}
+
+// Processing states of a photo, as reported by Status
+const (
+	StatusProcessed   = "processed"    // Variants and thumbnail are up to date
+	StatusUnprocessed = "unprocessed"  // No variants yet
+	StatusOutdated    = "outdated"     // Variants are missing widths or were made with other settings
+	StatusNoThumbnail = "no_thumbnail" // Variants are up to date, but the builder thumbnail is missing
+)
+
+// Status reports whether a photo's processed files in dst are up to date with
+// the processor's settings, without reading the photo itself
+func (p *Processor) Status(dst *Destination, hashID string) (string, error) {
+	if err := p.prepare(); err != nil {
+		return "", fmt.Errorf("invalid processing configuration: %w", err)
+	}
+	return p.status(dst, hashID, p.widthsFor(hashID)), nil
+}
+
+// status checks the fingerprint, variants, crops and thumbnail of a photo
+func (p *Processor) status(dst *Destination, hashID string, widths []int) string {
+	fingerprint := dst.Fingerprint(hashID)
+	existing, missing := 0, 0
+	check := func(filename string) {
+		if dst.VariantExists(hashID, filename) {
+			existing++
+		} else {
+			missing++
+		}
+	}
+	for _, width := range widths {
+		check(fmt.Sprintf("%s-%dw.webp", hashID, width))
+	}
+	if p.Config.Crop.IsActive() {
+		for _, width := range p.Config.Crop.Widths {
+			check(CropFilename(hashID, p.Config.Crop.Name(), width))
+		}
+	}
+
+	switch {
+	case existing == 0 && fingerprint == "":
+		return StatusUnprocessed
+	case missing > 0 || fingerprint != p.fingerprintFor(hashID):
+		return StatusOutdated
+	case p.Config.GenerateThumbnails && !dst.ThumbnailExists(fmt.Sprintf("thumb-%s.webp", hashID)):
+		return StatusNoThumbnail
+	}
+	return StatusProcessed
+}
+
+// ProcessImage processes a single image: hash -> resize -> convert -> save.
+// Video clips are transcoded and their poster frame processed like an image.
+func (p *Processor) ProcessImage(src ImageSource, dst *Destination) error {
+	return p.ProcessImageContext(context.Background(), src, dst)
+}
+
+// ProcessImageContext is ProcessImage with a context that stops video
+// transcodes when cancelled
+func (p *Processor) ProcessImageContext(ctx context.Context, src ImageSource, dst *Destination) error {
+	if err := p.prepare(); err != nil {
+		return fmt.Errorf("invalid processing configuration: %w", err)
+	}
+
+	// 1. Compute Hash
+	hash, err := p.ComputeHash(src)
+	if err != nil {
+		return err
+	}
+
+	hashID := hash[:12]
+	widths := p.widthsFor(hashID)
+
+	if content.IsVideo(src.Name()) {
+		return p.processVideo(ctx, src, dst, hashID, widths)
+	}
+
+	// 2. Check if processing is needed (skip if all files exist, were produced
+	// with the current settings and not forcing)
+	if !p.Config.Force && p.status(dst, hashID, widths) == StatusProcessed {
+		return p.removeStaleVariants(dst, hashID, widths)
 	}
 
 	// 3. Decode Image
@@ -105,62 +289,154 @@ func (p *Processor) ProcessImage(src ImageSource, dst *Destination) error {
 	}
 	defer reader.Close()
 
-	img, _, err := image.Decode(reader)
+	// Apply the EXIF orientation while decoding: encoded variants carry no
+	// metadata, so the pixels themselves must be upright
+	img, err := imaging.Decode(reader, imaging.AutoOrientation(true))
 	if err != nil {
 		return fmt.Errorf("failed to decode image: %w", err)
 	}
 
+	return p.renderVariants(img, dst, hashID, widths)
+}
+
+// renderVariants saves the variants, crops, settings fingerprint and thumbnail
+// of a decoded image
+func (p *Processor) renderVariants(img image.Image, dst *Destination, hashID string, widths []int) error {
 	// 4. Generate and save all image variants
-	for _, width := range p.Config.Widths {
+	for _, width := range widths {
 		// Calculate height maintaining aspect ratio
 		bounds := img.Bounds()
 		ratio := float64(bounds.Dy()) / float64(bounds.Dx())
 		height := int(float64(width) * ratio)
 
-		// Resize image
-		resized := p.resizeImage(img, width, height)
+		// Resize (and sharpen) image
+		resized := p.RenderVariant(img, width, height)
+
+		// Apply watermark to variants only; builder thumbnails stay clean
+		if p.watermark != nil {
+			resized = p.watermark.Apply(resized)
+		}
 
 		// Save variant: dist/images/project/{hashID}/{hashID}-{width}w.webp
 		filename := fmt.Sprintf("%s-%dw.webp", hashID, width)
-		if err := p.saveVariant(resized, dst, hashID, filename); err != nil {
+		if err := p.saveVariant(resized, dst, hashID, filename, p.Config.QualityCurve.QualityFor(width, p.Config.Quality)); err != nil {
 			return fmt.Errorf("failed to save variant %s: %w", filename, err)
 		}
 	}
 
-	// 5. Generate and save thumbnail
-	if p.Config.GenerateThumbnails {
-		width := p.Config.ThumbnailWidth
-		bounds := img.Bounds()
-		ratio := float64(bounds.Dy()) / float64(bounds.Dx())
-		height := int(float64(width) * ratio)
+	// 5. Generate and save art-directed crops around the focal point
+	if p.Config.Crop.IsActive() {
+		crop := p.Config.Crop
+		cropped := cropToRatio(img, crop.RatioWidth, crop.RatioHeight, crop.focalPoint(hashID))
+		for _, width := range crop.Widths {
+			height := width * crop.RatioHeight / crop.RatioWidth
 
-		// Resize for thumbnail
-		resized := p.resizeImage(img, width, height)
+			resized := p.RenderVariant(cropped, width, height)
+			if p.watermark != nil {
+				resized = p.watermark.Apply(resized)
+			}
 
-		// Save thumbnail: photos/project/.thumbs/thumb-{hashID}.webp
-		filename := fmt.Sprintf("thumb-%s.webp", hashID)
-		if err := p.saveThumbnail(resized, dst, filename); err != nil {
-			return fmt.Errorf("failed to save thumbnail %s: %w", filename, err)
+			filename := CropFilename(hashID, crop.Name(), width)
+			if err := p.saveVariant(resized, dst, hashID, filename, p.Config.QualityCurve.QualityFor(width, p.Config.Quality)); err != nil {
+				return fmt.Errorf("failed to save crop %s: %w", filename, err)
+			}
+		}
+	}
+
+	if err := p.removeStaleVariants(dst, hashID, widths); err != nil {
+		return err
+	}
+
+	if err := dst.WriteFingerprint(hashID, p.fingerprintFor(hashID)); err != nil {
+		return fmt.Errorf("failed to record settings fingerprint: %w", err)
+	}
+
+	// 6. Generate and save thumbnail
+	if p.Config.GenerateThumbnails {
+		if err := p.writeThumbnail(img, dst, hashID); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// ProcessThumbnail generates only the builder thumbnail of an image (e.g. right
+// after an upload) and returns the photo's hash ID. Existing thumbnails are kept
+// unless forcing.
+func (p *Processor) ProcessThumbnail(src ImageSource, dst *Destination) (string, error) {
+	hash, err := p.ComputeHash(src)
+	if err != nil {
+		return "", err
+	}
+	hashID := hash[:12]
+
+	if !p.Config.Force && dst.ThumbnailExists(fmt.Sprintf("thumb-%s.webp", hashID)) {
+		return hashID, nil
+	}
+
+	reader, err := src.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open source for decoding: %w", err)
+	}
+	defer reader.Close()
+
+	img, err := imaging.Decode(reader, imaging.AutoOrientation(true))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if err := p.writeThumbnail(img, dst, hashID); err != nil {
+		return "", err
+	}
+	return hashID, nil
+}
+
+// writeThumbnail resizes img to the thumbnail width and saves it as thumb-{hashID}.webp
+func (p *Processor) writeThumbnail(img image.Image, dst *Destination, hashID string) error {
+	width := p.Config.ThumbnailWidth
+	if width == 0 {
+		width = 300 // Default thumbnail width
+	}
+	bounds := img.Bounds()
+	ratio := float64(bounds.Dy()) / float64(bounds.Dx())
+	height := int(float64(width) * ratio)
+
+	// Resize for thumbnail
+	resized := p.resizeImage(img, width, height)
+
+	// Save thumbnail: photos/project/.thumbs/thumb-{hashID}.webp
+	filename := fmt.Sprintf("thumb-%s.webp", hashID)
+	if err := p.saveThumbnail(resized, dst, filename); err != nil {
+		return fmt.Errorf("failed to save thumbnail %s: %w", filename, err)
+	}
+	return nil
+}
+
 // resizeImage resizes the image to the specified dimensions using Lanczos filter
 func (p *Processor) resizeImage(img image.Image, width, height int) image.Image {
 	return imaging.Resize(img, width, height, imaging.Lanczos)
 }
 
+// RenderVariant resizes the image for a variant and applies the configured
+// sharpening. The watermark is not part of it.
+func (p *Processor) RenderVariant(img image.Image, width, height int) image.Image {
+	resized := p.resizeImage(img, width, height)
+	if p.Config.Sharpen.IsActive() {
+		return unsharpMask(resized, *p.Config.Sharpen)
+	}
+	return resized
+}
+
 // saveVariant saves an image variant to the output directory
-func (p *Processor) saveVariant(img image.Image, dst *Destination, hashID, filename string) error {
+func (p *Processor) saveVariant(img image.Image, dst *Destination, hashID, filename string, quality int) error {
 	writer, err := dst.CreateVariant(hashID, filename)
 	if err != nil {
 		return fmt.Errorf("failed to create variant file: %w", err)
 	}
 	defer writer.Close()
 
-	if err := webp.Encode(writer, img, &webp.Options{Quality: float32(p.Config.Quality)}); err != nil {
+	if err := webp.Encode(writer, img, &webp.Options{Quality: float32(quality)}); err != nil {
 		return fmt.Errorf("failed to encode WebP: %w", err)
 	}
 
@@ -0,0 +1,77 @@
+package processing
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestStatus checks that Status follows a photo from unprocessed to processed and back to outdated
+func TestStatus(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "photo.png")
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for x := 0; x < 64; x++ {
+		img.Set(x, x%48, color.RGBA{R: uint8(x * 4), A: 255})
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	src := &FileSource{Path: path}
+	dst := &Destination{OutputDir: filepath.Join(dir, "out")}
+	processor := NewProcessor(ProcessConfig{Widths: []int{16, 32}, GenerateThumbnails: true, ThumbnailWidth: 8})
+	hash, err := processor.ComputeHash(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashID := hash[:12]
+
+	status := func(p *Processor) string {
+		t.Helper()
+		s, err := p.Status(dst, hashID)
+		if err != nil {
+			t.Fatalf("Status failed: %v", err)
+		}
+		return s
+	}
+
+	if s := status(processor); s != StatusUnprocessed {
+		t.Errorf("Expected %s before processing, got %s", StatusUnprocessed, s)
+	}
+	if err := processor.ProcessImage(src, dst); err != nil {
+		t.Fatalf("ProcessImage failed: %v", err)
+	}
+	if s := status(processor); s != StatusProcessed {
+		t.Errorf("Expected %s after processing, got %s", StatusProcessed, s)
+	}
+
+	// A new width, or other settings, make the variants outdated
+	wider := NewProcessor(ProcessConfig{Widths: []int{16, 32, 48}, GenerateThumbnails: true, ThumbnailWidth: 8})
+	if s := status(wider); s != StatusOutdated {
+		t.Errorf("Expected %s with a new width, got %s", StatusOutdated, s)
+	}
+	sharper := NewProcessor(ProcessConfig{Widths: []int{16, 32}, GenerateThumbnails: true, Sharpen: &SharpenConfig{Amount: 1, Radius: 1}})
+	if s := status(sharper); s != StatusOutdated {
+		t.Errorf("Expected %s with other settings, got %s", StatusOutdated, s)
+	}
+	lower := NewProcessor(ProcessConfig{Widths: []int{16, 32}, GenerateThumbnails: true, ThumbnailWidth: 8, Quality: 70})
+	if s := status(lower); s != StatusOutdated {
+		t.Errorf("Expected %s with another quality, got %s", StatusOutdated, s)
+	}
+
+	if err := os.Remove(filepath.Join(dst.OutputDir, ".thumbs", "thumb-"+hashID+".webp")); err != nil {
+		t.Fatal(err)
+	}
+	if s := status(processor); s != StatusNoThumbnail {
+		t.Errorf("Expected %s without thumbnail, got %s", StatusNoThumbnail, s)
+	}
+}
@@ -0,0 +1,102 @@
+package processing
+
+import (
+	"fmt"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+)
+
+// ProjectConfig returns config with the site's and the project's settings
+// applied: the quality and sharpening from site.yaml, the effective
+// watermark, the mobile crop of its layout and, when enabled in site.yaml,
+// layout-aware widths
+func ProjectConfig(contentMgr *content.Manager, slug string, config ProcessConfig) (ProcessConfig, error) {
+	meta, err := contentMgr.LoadSiteMeta()
+	if err != nil {
+		return config, err
+	}
+	config, err = applySettings(config, meta.Processing)
+	if err != nil {
+		return config, fmt.Errorf("invalid processing settings in site.yaml: %w", err)
+	}
+
+	watermark, err := contentMgr.EffectiveWatermark(slug)
+	if err != nil {
+		return config, err
+	}
+	crop, err := MobileCropConfig(contentMgr, slug)
+	if err != nil {
+		return config, err
+	}
+	widths := config.Widths
+	if len(widths) == 0 {
+		widths = DefaultWidths
+	}
+	variantWidths, err := ProjectWidths(contentMgr, slug, widths)
+	if err != nil {
+		return config, err
+	}
+
+	config.Watermark = watermark
+	config.Crop = crop
+	config.VariantWidths = variantWidths
+	return config, nil
+}
+
+// applySettings returns config with the quality and sharpening of settings,
+// where they are set
+func applySettings(config ProcessConfig, settings *content.ProcessingSettings) (ProcessConfig, error) {
+	if settings == nil {
+		return config, nil
+	}
+	if settings.Quality != 0 {
+		if settings.Quality < 1 || settings.Quality > 100 {
+			return config, fmt.Errorf("quality must be between 1 and 100")
+		}
+		config.Quality = settings.Quality
+	}
+	if settings.QualityCurve != "" {
+		curve, err := ParseQualityCurve(settings.QualityCurve)
+		if err != nil {
+			return config, err
+		}
+		config.QualityCurve = curve
+	}
+	if sharpen := settings.Sharpen; sharpen != nil && sharpen.Amount > 0 {
+		config.Sharpen = &SharpenConfig{Amount: sharpen.Amount, Radius: sharpen.Radius, Threshold: sharpen.Threshold}
+		if config.Sharpen.Radius == 0 {
+			config.Sharpen.Radius = 0.5
+		}
+		if err := config.Sharpen.Validate(); err != nil {
+			return config, err
+		}
+	}
+	return config, nil
+}
+
+// MobileCropConfig returns the crop settings for a project's mobile_crop layout
+// option, or nil when the project has no layout or no mobile crop
+func MobileCropConfig(contentMgr *content.Manager, slug string) (*CropConfig, error) {
+	layout, err := contentMgr.GetLayout(slug)
+	if err != nil || layout.MobileCrop == "" {
+		return nil, nil
+	}
+	ratioW, ratioH, err := content.ParseRatio(layout.MobileCrop)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mobile_crop in layout: %w", err)
+	}
+
+	focalPoints := make(map[string]content.FocalPoint)
+	for hashID, settings := range layout.Photos {
+		if settings.FocalPoint != nil {
+			focalPoints[hashID] = *settings.FocalPoint
+		}
+	}
+
+	return &CropConfig{
+		RatioWidth:  ratioW,
+		RatioHeight: ratioH,
+		Widths:      MobileCropWidths,
+		FocalPoints: focalPoints,
+	}, nil
+}
@@ -0,0 +1,38 @@
+package processing
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+)
+
+// TestProjectConfigProcessing checks that the processing settings in site.yaml are applied
+func TestProjectConfigProcessing(t *testing.T) {
+	contentDir := t.TempDir()
+	site := "processing:\n  quality: 70\n  quality_curve: 480=90,1920=80\n  sharpen:\n    amount: 0.5\n"
+	if err := os.WriteFile(filepath.Join(contentDir, "site.yaml"), []byte(site), 0644); err != nil {
+		t.Fatal(err)
+	}
+	contentMgr := content.NewManagerWithPhotosDir(contentDir, t.TempDir())
+
+	config, err := ProjectConfig(contentMgr, "p", ProcessConfig{Quality: 85})
+	if err != nil {
+		t.Fatalf("ProjectConfig failed: %v", err)
+	}
+	if config.Quality != 70 || !reflect.DeepEqual(config.QualityCurve, QualityCurve{480: 90, 1920: 80}) {
+		t.Errorf("Expected quality 70 and the curve, got %d and %v", config.Quality, config.QualityCurve)
+	}
+	if config.Sharpen == nil || config.Sharpen.Amount != 0.5 || config.Sharpen.Radius != 0.5 {
+		t.Errorf("Expected sharpening with the default radius, got %+v", config.Sharpen)
+	}
+
+	if err := os.WriteFile(filepath.Join(contentDir, "site.yaml"), []byte("processing:\n  quality: 150\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ProjectConfig(contentMgr, "p", ProcessConfig{Quality: 85}); err == nil {
+		t.Error("Expected an error for quality 150")
+	}
+}
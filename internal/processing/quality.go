@@ -0,0 +1,68 @@
+package processing
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// QualityCurve maps variant widths to WebP quality. Widths between two points
+// are linearly interpolated; widths outside the curve use the nearest point.
+type QualityCurve map[int]int
+
+// ParseQualityCurve parses a curve in the form "480=90,1200=85,1920=80"
+func ParseQualityCurve(s string) (QualityCurve, error) {
+	curve := QualityCurve{}
+	if strings.TrimSpace(s) == "" {
+		return curve, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		widthStr, qualityStr, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid quality curve entry %q: expected width=quality", part)
+		}
+		width, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(widthStr), "w"))
+		if err != nil || width <= 0 {
+			return nil, fmt.Errorf("invalid width in quality curve entry %q", part)
+		}
+		quality, err := strconv.Atoi(strings.TrimSpace(qualityStr))
+		if err != nil || quality < 1 || quality > 100 {
+			return nil, fmt.Errorf("invalid quality in quality curve entry %q: must be 1-100", part)
+		}
+		curve[width] = quality
+	}
+
+	return curve, nil
+}
+
+// QualityFor returns the quality for a variant width, or fallback if the curve is empty
+func (c QualityCurve) QualityFor(width, fallback int) int {
+	if len(c) == 0 {
+		return fallback
+	}
+	if q, ok := c[width]; ok {
+		return q
+	}
+
+	widths := make([]int, 0, len(c))
+	for w := range c {
+		widths = append(widths, w)
+	}
+	sort.Ints(widths)
+
+	if width <= widths[0] {
+		return c[widths[0]]
+	}
+	if width >= widths[len(widths)-1] {
+		return c[widths[len(widths)-1]]
+	}
+
+	// Interpolate between the surrounding points
+	i := sort.SearchInts(widths, width)
+	lowW, highW := widths[i-1], widths[i]
+	lowQ, highQ := float64(c[lowW]), float64(c[highW])
+	t := float64(width-lowW) / float64(highW-lowW)
+	return int(lowQ + t*(highQ-lowQ) + 0.5)
+}
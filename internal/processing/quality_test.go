@@ -0,0 +1,37 @@
+package processing
+
+import "testing"
+
+// TestQualityCurve checks parsing, exact matches, interpolation and clamping
+func TestQualityCurve(t *testing.T) {
+	curve, err := ParseQualityCurve("480=90, 1920w=80")
+	if err != nil {
+		t.Fatalf("Failed to parse quality curve: %v", err)
+	}
+
+	testCases := []struct {
+		width    int
+		expected int
+	}{
+		{320, 90},  // below the curve: nearest point
+		{480, 90},  // exact
+		{1200, 85}, // halfway between 480 and 1920
+		{1920, 80}, // exact
+		{3840, 80}, // above the curve: nearest point
+	}
+	for _, tc := range testCases {
+		if got := curve.QualityFor(tc.width, 75); got != tc.expected {
+			t.Errorf("QualityFor(%d) = %d, want %d", tc.width, got, tc.expected)
+		}
+	}
+
+	if got := (QualityCurve{}).QualityFor(800, 75); got != 75 {
+		t.Errorf("empty curve should return the fallback, got %d", got)
+	}
+
+	for _, invalid := range []string{"480", "abc=80", "480=0", "480=101"} {
+		if _, err := ParseQualityCurve(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
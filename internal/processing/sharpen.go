@@ -0,0 +1,68 @@
+package processing
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// SharpenConfig configures the unsharp mask applied after downscaling
+type SharpenConfig struct {
+	Amount    float64 `json:"amount"`    // Strength of the effect (e.g. 0.5 adds 50% of the detail back)
+	Radius    float64 `json:"radius"`    // Gaussian blur sigma in pixels
+	Threshold float64 `json:"threshold"` // Minimum per-channel difference (0-255) before sharpening applies
+}
+
+// IsActive returns true if the configuration sharpens anything
+func (s *SharpenConfig) IsActive() bool {
+	return s != nil && s.Amount > 0 && s.Radius > 0
+}
+
+// Validate checks the sharpening parameters
+func (s *SharpenConfig) Validate() error {
+	if s == nil {
+		return nil
+	}
+	if s.Amount < 0 || s.Radius < 0 || s.Threshold < 0 {
+		return fmt.Errorf("sharpen amount, radius and threshold must be >= 0")
+	}
+	if s.Threshold > 255 {
+		return fmt.Errorf("sharpen threshold must be <= 255")
+	}
+	return nil
+}
+
+// unsharpMask sharpens img by adding back the difference between the image and
+// a blurred copy of it, skipping differences below the threshold to keep noise down
+func unsharpMask(img image.Image, cfg SharpenConfig) *image.NRGBA {
+	src := imaging.Clone(img)
+	blurred := imaging.Blur(src, cfg.Radius)
+	out := image.NewNRGBA(src.Bounds())
+
+	for i := 0; i < len(src.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			orig := float64(src.Pix[i+c])
+			diff := orig - float64(blurred.Pix[i+c])
+			if math.Abs(diff) < cfg.Threshold {
+				out.Pix[i+c] = src.Pix[i+c]
+				continue
+			}
+			out.Pix[i+c] = clampUint8(orig + cfg.Amount*diff)
+		}
+		out.Pix[i+3] = src.Pix[i+3]
+	}
+
+	return out
+}
+
+func clampUint8(v float64) uint8 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(math.Round(v))
+}
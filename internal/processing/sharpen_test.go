@@ -0,0 +1,58 @@
+package processing
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// edgeImage returns a gray image with a dark left half and a light right half
+func edgeImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			v := uint8(80)
+			if x >= 8 {
+				v = 160
+			}
+			img.SetNRGBA(x, y, color.NRGBA{R: v, G: v, B: v, A: 200})
+		}
+	}
+	return img
+}
+
+// TestUnsharpMask checks that edges gain contrast, flat areas and alpha are
+// left alone, and the threshold skips small differences
+func TestUnsharpMask(t *testing.T) {
+	src := edgeImage()
+	out := unsharpMask(src, SharpenConfig{Amount: 1, Radius: 1})
+
+	if got := out.NRGBAAt(7, 4).R; got >= 80 {
+		t.Errorf("Expected the dark side of the edge to get darker, got %d", got)
+	}
+	if got := out.NRGBAAt(8, 4).R; got <= 160 {
+		t.Errorf("Expected the light side of the edge to get lighter, got %d", got)
+	}
+	if got := out.NRGBAAt(0, 4); got.R != 80 || got.A != 200 {
+		t.Errorf("Expected flat areas and alpha to be unchanged, got %+v", got)
+	}
+
+	thresholded := unsharpMask(src, SharpenConfig{Amount: 1, Radius: 1, Threshold: 255})
+	for i, v := range thresholded.Pix {
+		if v != src.Pix[i] {
+			t.Fatal("Expected a threshold above every difference to leave the image unchanged")
+		}
+	}
+}
+
+// TestSharpenConfigValidate checks the accepted parameter ranges
+func TestSharpenConfigValidate(t *testing.T) {
+	if err := (&SharpenConfig{Amount: 0.5, Radius: 0.8, Threshold: 3}).Validate(); err != nil {
+		t.Errorf("Expected valid settings, got %v", err)
+	}
+	for _, invalid := range []SharpenConfig{{Amount: -1}, {Radius: -1}, {Threshold: 256}} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected an error for %+v", invalid)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package processing
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// SSIM computes the mean structural similarity index between two images of the
+// same size, on luminance, using 8x8 windows with a stride of 4 pixels.
+// 1.0 means identical; values above ~0.98 are usually visually lossless.
+func SSIM(a, b image.Image) (float64, error) {
+	if a.Bounds().Dx() != b.Bounds().Dx() || a.Bounds().Dy() != b.Bounds().Dy() {
+		return 0, fmt.Errorf("image sizes differ: %v vs %v", a.Bounds().Size(), b.Bounds().Size())
+	}
+
+	la := luminance(a)
+	lb := luminance(b)
+	width := a.Bounds().Dx()
+	height := a.Bounds().Dy()
+
+	const (
+		window = 8
+		stride = 4
+		c1     = (0.01 * 255) * (0.01 * 255)
+		c2     = (0.03 * 255) * (0.03 * 255)
+	)
+
+	if width < window || height < window {
+		return 0, fmt.Errorf("images too small for SSIM (minimum %dx%d)", window, window)
+	}
+
+	var total float64
+	var count int
+	n := float64(window * window)
+
+	for y := 0; y+window <= height; y += stride {
+		for x := 0; x+window <= width; x += stride {
+			var sumA, sumB, sumAA, sumBB, sumAB float64
+			for wy := 0; wy < window; wy++ {
+				row := (y + wy) * width
+				for wx := 0; wx < window; wx++ {
+					va := la[row+x+wx]
+					vb := lb[row+x+wx]
+					sumA += va
+					sumB += vb
+					sumAA += va * va
+					sumBB += vb * vb
+					sumAB += va * vb
+				}
+			}
+			meanA := sumA / n
+			meanB := sumB / n
+			varA := sumAA/n - meanA*meanA
+			varB := sumBB/n - meanB*meanB
+			cov := sumAB/n - meanA*meanB
+
+			total += ((2*meanA*meanB + c1) * (2*cov + c2)) /
+				((meanA*meanA + meanB*meanB + c1) * (varA + varB + c2))
+			count++
+		}
+	}
+
+	return total / float64(count), nil
+}
+
+// luminance returns the Rec. 601 luma of every pixel, row by row
+func luminance(img image.Image) []float64 {
+	src := imaging.Clone(img)
+	out := make([]float64, 0, src.Bounds().Dx()*src.Bounds().Dy())
+	for i := 0; i < len(src.Pix); i += 4 {
+		out = append(out, 0.299*float64(src.Pix[i])+0.587*float64(src.Pix[i+1])+0.114*float64(src.Pix[i+2]))
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package processing
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/chai2010/webp"
+)
+
+// texturedImage returns an image with enough detail for lossy encoding to lose some
+func texturedImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * y), G: uint8(x*7 + y*3), B: uint8((x ^ y) * 4), A: 255})
+		}
+	}
+	return img
+}
+
+// TestSSIM checks identical images score 1 and that lower encoding qualities,
+// as compared by images compare, score lower
+func TestSSIM(t *testing.T) {
+	img := texturedImage()
+	if score, err := SSIM(img, img); err != nil || score < 0.9999 {
+		t.Errorf("Expected identical images to score 1, got %f, %v", score, err)
+	}
+
+	score := func(quality int) float64 {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := webp.Encode(&buf, img, &webp.Options{Quality: float32(quality)}); err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := webp.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := SSIM(img, decoded)
+		if err != nil {
+			t.Fatalf("SSIM failed: %v", err)
+		}
+		return s
+	}
+	low, high := score(10), score(95)
+	if !(low < high && high < 1) {
+		t.Errorf("Expected SSIM to grow with quality, got %f at 10 and %f at 95", low, high)
+	}
+
+	if _, err := SSIM(img, image.NewNRGBA(image.Rect(0, 0, 32, 64))); err == nil {
+		t.Error("Expected an error for images of different sizes")
+	}
+	tiny := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	if _, err := SSIM(tiny, tiny); err == nil {
+		t.Error("Expected an error for images smaller than the window")
+	}
+}
@@ -0,0 +1,176 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// Maximum widths of the video transcodes; smaller clips keep their size
+const (
+	videoWebWidth  = 1920 // Web transcode, with sound
+	videoLoopWidth = 1280 // Looping muted variant shown in the gallery
+)
+
+// ErrFFmpegNotFound is returned when a video clip is processed without an ffmpeg binary
+var ErrFFmpegNotFound = errors.New("ffmpeg not found (install it to process video clips)")
+
+// VideoFilename returns the filename of the web transcode of a video clip
+func VideoFilename(hashID string) string {
+	return hashID + ".mp4"
+}
+
+// LoopFilename returns the filename of the looping muted variant of a video clip
+func LoopFilename(hashID string) string {
+	return hashID + "-loop.mp4"
+}
+
+// VideoStatus is Status for a video clip: the variants of its poster frame and
+// both transcodes must be up to date
+func (p *Processor) VideoStatus(dst *Destination, hashID string) (string, error) {
+	status, err := p.Status(dst, hashID)
+	if err != nil || status == StatusUnprocessed {
+		return status, err
+	}
+	if !dst.VariantExists(hashID, VideoFilename(hashID)) || !dst.VariantExists(hashID, LoopFilename(hashID)) {
+		return StatusOutdated, nil
+	}
+	return status, nil
+}
+
+// processVideo transcodes a video clip for the web, renders its looping muted
+// variant and processes a representative frame as its poster, like an image
+func (p *Processor) processVideo(ctx context.Context, src ImageSource, dst *Destination, hashID string, widths []int) error {
+	if !p.Config.Force {
+		if status, _ := p.VideoStatus(dst, hashID); status == StatusProcessed {
+			return p.removeStaleVariants(dst, hashID, widths)
+		}
+	}
+
+	ffmpeg := p.Config.FFmpeg
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+	ffmpeg, err := exec.LookPath(ffmpeg)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFFmpegNotFound, err)
+	}
+
+	input, cleanup, err := localPath(src)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// ffmpeg writes into a scratch directory; finished files are then saved
+	// through the destination
+	work, err := os.MkdirTemp("", "video-")
+	if err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(work)
+
+	transcodes := []struct {
+		filename string
+		args     []string
+	}{
+		{VideoFilename(hashID), []string{
+			"-map", "0:v:0", "-map", "0:a:0?",
+			"-vf", scaleFilter(videoWebWidth),
+			"-c:v", "libx264", "-preset", "slow", "-crf", "23", "-pix_fmt", "yuv420p",
+			"-c:a", "aac", "-b:a", "128k",
+		}},
+		{LoopFilename(hashID), []string{
+			"-map", "0:v:0", "-an",
+			"-vf", scaleFilter(videoLoopWidth),
+			"-c:v", "libx264", "-preset", "slow", "-crf", "28", "-pix_fmt", "yuv420p",
+		}},
+	}
+	for _, t := range transcodes {
+		out := filepath.Join(work, t.filename)
+		args := append([]string{"-i", input}, t.args...)
+		args = append(args, "-movflags", "+faststart", "-f", "mp4", out)
+		if err := runFFmpeg(ctx, ffmpeg, args...); err != nil {
+			return fmt.Errorf("failed to transcode %s: %w", t.filename, err)
+		}
+		if err := saveFile(dst, hashID, t.filename, out); err != nil {
+			return err
+		}
+	}
+
+	// The thumbnail filter picks a representative frame among the first ones
+	frame := filepath.Join(work, "poster.png")
+	if err := runFFmpeg(ctx, ffmpeg, "-i", input, "-vf", "thumbnail", "-frames:v", "1", "-f", "image2", frame); err != nil {
+		return fmt.Errorf("failed to extract poster frame: %w", err)
+	}
+	img, err := imaging.Open(frame)
+	if err != nil {
+		return fmt.Errorf("failed to decode poster frame: %w", err)
+	}
+	return p.renderVariants(img, dst, hashID, widths)
+}
+
+// scaleFilter returns an ffmpeg filter that scales videos wider than maxWidth
+// down to it, keeping the even dimensions H.264 needs
+func scaleFilter(maxWidth int) string {
+	return fmt.Sprintf("scale='trunc(min(%d,iw)/2)*2':-2", maxWidth)
+}
+
+// saveFile saves the file at path as a variant of hashID
+func saveFile(dst *Destination, hashID, filename, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer file.Close()
+	return dst.SaveVariant(hashID, filename, file)
+}
+
+// runFFmpeg runs ffmpeg, overwriting its output, and returns its error output on
+// failure. ffmpeg is killed when ctx is cancelled.
+func runFFmpeg(ctx context.Context, ffmpeg string, args ...string) error {
+	args = append([]string{"-hide_banner", "-loglevel", "error", "-nostdin", "-y"}, args...)
+	output, err := exec.CommandContext(ctx, ffmpeg, args...).CombinedOutput()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// localPath returns the path of a file source, or of a temporary copy of other
+// sources, for ffmpeg to read. The returned function removes the copy.
+func localPath(src ImageSource) (string, func(), error) {
+	if file, ok := src.(*FileSource); ok {
+		return file.Path, func() {}, nil
+	}
+
+	reader, err := src.Open()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open source: %w", err)
+	}
+	defer reader.Close()
+
+	tmp, err := os.CreateTemp("", "video-*"+filepath.Ext(src.Name()))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	_, err = io.Copy(tmp, reader)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", nil, fmt.Errorf("failed to copy source: %w", err)
+	}
+	return tmp.Name(), func() { os.Remove(tmp.Name()) }, nil
+}
@@ -0,0 +1,100 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestProcessVideoWithoutFFmpeg checks that clips fail with ErrFFmpegNotFound when ffmpeg is missing
+func TestProcessVideoWithoutFFmpeg(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "clip.mp4")
+	if err := os.WriteFile(path, []byte("clip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	processor := NewProcessor(ProcessConfig{Widths: []int{16}, FFmpeg: filepath.Join(dir, "missing-ffmpeg")})
+	err := processor.ProcessImage(&FileSource{Path: path}, &Destination{OutputDir: filepath.Join(dir, "out")})
+	if !errors.Is(err, ErrFFmpegNotFound) {
+		t.Errorf("Expected ErrFFmpegNotFound, got %v", err)
+	}
+}
+
+// TestProcessVideoCancel checks that cancelling the context stops a running
+// transcode and leaves no output behind
+func TestProcessVideoCancel(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "clip.mp4")
+	if err := os.WriteFile(path, []byte("clip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A stand-in for ffmpeg that never finishes
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(ffmpeg, []byte("#!/bin/sh\nexec sleep 60\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	dst := &Destination{OutputDir: filepath.Join(dir, "out")}
+	processor := NewProcessor(ProcessConfig{Widths: []int{16}, FFmpeg: ffmpeg})
+	started := time.Now()
+	err := processor.ProcessImageContext(ctx, &FileSource{Path: path}, dst)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the transcode to be cancelled, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 10*time.Second {
+		t.Errorf("Expected ffmpeg to be killed, it ran for %s", elapsed)
+	}
+	if _, err := os.Stat(dst.OutputDir); !os.IsNotExist(err) {
+		t.Errorf("Expected no output for a cancelled transcode, got %v", err)
+	}
+}
+
+// TestProcessVideo checks that a clip gets both transcodes and poster variants,
+// and that VideoStatus notices a missing transcode
+func TestProcessVideo(t *testing.T) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "clip.mp4")
+	if err := runFFmpeg(context.Background(), ffmpeg, "-f", "lavfi", "-i", "testsrc=duration=1:size=64x48:rate=10", "-pix_fmt", "yuv420p", path); err != nil {
+		t.Fatalf("failed to create test clip: %v", err)
+	}
+
+	src := &FileSource{Path: path}
+	dst := &Destination{OutputDir: filepath.Join(dir, "out")}
+	processor := NewProcessor(ProcessConfig{Widths: []int{16, 32}, GenerateThumbnails: true, ThumbnailWidth: 8})
+	hash, err := processor.ComputeHash(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashID := hash[:12]
+
+	if err := processor.ProcessImage(src, dst); err != nil {
+		t.Fatalf("ProcessImage failed: %v", err)
+	}
+	if s, _ := processor.VideoStatus(dst, hashID); s != StatusProcessed {
+		t.Errorf("Expected %s after processing, got %s", StatusProcessed, s)
+	}
+	for _, name := range []string{VideoFilename(hashID), LoopFilename(hashID), hashID + "-32w.webp"} {
+		if !dst.VariantExists(hashID, name) {
+			t.Errorf("Expected %s to exist", name)
+		}
+	}
+
+	if err := os.Remove(filepath.Join(dst.OutputDir, hashID, LoopFilename(hashID))); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := processor.VideoStatus(dst, hashID); s != StatusOutdated {
+		t.Errorf("Expected %s without the loop, got %s", StatusOutdated, s)
+	}
+}
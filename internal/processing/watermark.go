@@ -0,0 +1,152 @@
+package processing
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	defaultWatermarkOpacity = 0.5
+	defaultWatermarkScale   = 0.2
+	watermarkMarginRatio    = 0.02 // Margin from the image edges, relative to the variant width
+)
+
+// watermarker renders a watermark overlay onto image variants
+type watermarker struct {
+	settings content.WatermarkSettings
+	overlay  image.Image // Source overlay (decoded image or text rendered at reference size)
+}
+
+// newWatermarker prepares the overlay described by the settings
+func newWatermarker(settings *content.WatermarkSettings) (*watermarker, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	w := &watermarker{settings: *settings}
+	if w.settings.Opacity == 0 {
+		w.settings.Opacity = defaultWatermarkOpacity
+	}
+	if w.settings.Scale == 0 {
+		w.settings.Scale = defaultWatermarkScale
+	}
+	if w.settings.Position == "" {
+		w.settings.Position = content.WatermarkBottomRight
+	}
+
+	if w.settings.Image != "" {
+		overlay, err := imaging.Open(w.settings.Image)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open watermark image: %w", err)
+		}
+		w.overlay = overlay
+		return w, nil
+	}
+
+	textColor, err := parseHexColor(w.settings.Color)
+	if err != nil {
+		return nil, err
+	}
+	overlay, err := renderText(w.settings.Text, textColor)
+	if err != nil {
+		return nil, err
+	}
+	w.overlay = overlay
+	return w, nil
+}
+
+// Apply draws the watermark onto img if it is at least MinWidth wide
+func (w *watermarker) Apply(img image.Image) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() < w.settings.MinWidth {
+		return img
+	}
+
+	overlayWidth := int(math.Round(float64(bounds.Dx()) * w.settings.Scale))
+	if overlayWidth < 1 {
+		return img
+	}
+	overlay := imaging.Resize(w.overlay, overlayWidth, 0, imaging.Lanczos)
+
+	margin := int(math.Round(float64(bounds.Dx()) * watermarkMarginRatio))
+	ob := overlay.Bounds()
+
+	var pos image.Point
+	switch w.settings.Position {
+	case content.WatermarkTopLeft:
+		pos = image.Pt(margin, margin)
+	case content.WatermarkTopRight:
+		pos = image.Pt(bounds.Dx()-ob.Dx()-margin, margin)
+	case content.WatermarkBottomLeft:
+		pos = image.Pt(margin, bounds.Dy()-ob.Dy()-margin)
+	case content.WatermarkCenter:
+		pos = image.Pt((bounds.Dx()-ob.Dx())/2, (bounds.Dy()-ob.Dy())/2)
+	default:
+		pos = image.Pt(bounds.Dx()-ob.Dx()-margin, bounds.Dy()-ob.Dy()-margin)
+	}
+
+	return imaging.Overlay(img, overlay, pos.Add(bounds.Min), w.settings.Opacity)
+}
+
+// renderText rasterizes text with the embedded Go font at a reference size.
+// The result is scaled to the variant width when applied.
+func renderText(text string, c color.Color) (image.Image, error) {
+	const referenceSize = 96
+
+	parsed, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse watermark font: %w", err)
+	}
+	face, err := opentype.NewFace(parsed, &opentype.FaceOptions{Size: referenceSize, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watermark font face: %w", err)
+	}
+	defer face.Close()
+
+	metrics := face.Metrics()
+	width := font.MeasureString(face, text).Ceil()
+	height := (metrics.Ascent + metrics.Descent).Ceil()
+	if width == 0 || height == 0 {
+		return nil, fmt.Errorf("watermark text renders to an empty image")
+	}
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, width, height))
+	drawer := &font.Drawer{
+		Dst:  canvas,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.Point26_6{X: 0, Y: metrics.Ascent},
+	}
+	draw.Draw(canvas, canvas.Bounds(), image.Transparent, image.Point{}, draw.Src)
+	drawer.DrawString(text)
+
+	return canvas, nil
+}
+
+// parseHexColor parses a #rrggbb color, defaulting to white when empty
+func parseHexColor(s string) (color.Color, error) {
+	if s == "" {
+		return color.White, nil
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 {
+		return nil, fmt.Errorf("invalid watermark color %q: expected #rrggbb", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid watermark color %q: %w", s, err)
+	}
+	return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
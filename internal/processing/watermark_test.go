@@ -0,0 +1,97 @@
+package processing
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.lorenzomilicia.dev/photography-portfolio-builder/internal/content"
+)
+
+// TestWatermarkApply checks the overlay position and the MinWidth threshold
+func TestWatermarkApply(t *testing.T) {
+	wm, err := newWatermarker(&content.WatermarkSettings{Text: "WATERMARK", Color: "#ffffff", Opacity: 1, Scale: 0.5})
+	if err != nil {
+		t.Fatalf("newWatermarker failed: %v", err)
+	}
+	black := func(width, height int) *image.NRGBA {
+		img := image.NewNRGBA(image.Rect(0, 0, width, height))
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 0xff
+		}
+		return img
+	}
+	brightness := func(img image.Image, r image.Rectangle) int {
+		total := 0
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				c := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+				total += int(c.Y)
+			}
+		}
+		return total
+	}
+
+	marked := wm.Apply(black(200, 100))
+	if brightness(marked, image.Rect(100, 50, 200, 100)) == 0 {
+		t.Error("Expected the text in the bottom-right corner")
+	}
+	if brightness(marked, image.Rect(0, 0, 100, 50)) != 0 {
+		t.Error("Expected the top-left corner to be untouched")
+	}
+
+	wm.settings.MinWidth = 300
+	if brightness(wm.Apply(black(200, 100)), image.Rect(0, 0, 200, 100)) != 0 {
+		t.Error("Expected variants narrower than MinWidth to be left untouched")
+	}
+}
+
+// TestWatermarkFingerprint checks that watermark changes, including a new
+// overlay image at the same path, invalidate processed variants
+func TestWatermarkFingerprint(t *testing.T) {
+	logo := filepath.Join(t.TempDir(), "logo.png")
+	writeLogo := func(c color.Color) {
+		t.Helper()
+		img := image.NewNRGBA(image.Rect(0, 0, 8, 4))
+		for x := 0; x < 8; x++ {
+			img.Set(x, 1, c)
+		}
+		file, err := os.Create(logo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if err := png.Encode(file, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fingerprint := func(settings *content.WatermarkSettings) string {
+		t.Helper()
+		fp, err := NewProcessor(ProcessConfig{Watermark: settings}).Fingerprint()
+		if err != nil {
+			t.Fatalf("Fingerprint failed: %v", err)
+		}
+		return fp
+	}
+
+	plain := fingerprint(nil)
+	if fp := fingerprint(&content.WatermarkSettings{Text: "©", Disabled: true}); fp != plain {
+		t.Error("Expected a disabled watermark to leave the fingerprint unchanged")
+	}
+
+	writeLogo(color.White)
+	first := fingerprint(&content.WatermarkSettings{Image: logo})
+	if first == plain {
+		t.Fatal("Expected a watermark to change the fingerprint")
+	}
+	if fp := fingerprint(&content.WatermarkSettings{Image: logo, Opacity: 0.8}); fp == first {
+		t.Error("Expected other settings to change the fingerprint")
+	}
+	writeLogo(color.Black)
+	if fp := fingerprint(&content.WatermarkSettings{Image: logo}); fp == first {
+		t.Error("Expected a new overlay image to change the fingerprint")
+	}
+}
@@ -223,6 +223,8 @@ func DetectContentType(filename string) string {
 		return "image/gif"
 	case ".svg":
 		return "image/svg+xml"
+	case ".mp4":
+		return "video/mp4"
 	case ".css":
 		return "text/css"
 	case ".js":
@@ -1,25 +1,123 @@
 package util
 
 import (
+	"bytes"
+	"fmt"
 	"os"
+	"reflect"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
-// LoadYAML loads a YAML file into the provided structure
+// BackupSuffix is appended to a file's name for the copy of its previous version
+const BackupSuffix = ".bak"
+
+// CorruptSuffix is appended to a file's name when it is replaced by its backup
+const CorruptSuffix = ".corrupt"
+
+// Recovery records a file that LoadYAML could not parse and read from its
+// backup instead
+type Recovery struct {
+	Path     string    `json:"path"`
+	Restored bool      `json:"restored"`          // The backup was put back in place of the file
+	Corrupt  string    `json:"corrupt,omitempty"` // Where the unreadable version was kept, when restored
+	Err      string    `json:"error"`
+	Time     time.Time `json:"time"`
+}
+
+var (
+	recoveriesMu   sync.Mutex
+	recoveries     []Recovery
+	restoreBackups bool
+)
+
+// SetRestoreBackups makes LoadYAML put the backup back in place of an
+// unreadable file. The builder does; elsewhere, such as in website build and
+// check, files are only read and an unreadable one is left as it is.
+func SetRestoreBackups(restore bool) {
+	recoveriesMu.Lock()
+	defer recoveriesMu.Unlock()
+	restoreBackups = restore
+}
+
+// Recoveries returns the files read from their backups since the last DismissRecoveries
+func Recoveries() []Recovery {
+	recoveriesMu.Lock()
+	defer recoveriesMu.Unlock()
+	return append([]Recovery(nil), recoveries...)
+}
+
+// DismissRecoveries forgets the recorded recoveries
+func DismissRecoveries() {
+	recoveriesMu.Lock()
+	defer recoveriesMu.Unlock()
+	recoveries = nil
+}
+
+// LoadYAML loads a YAML file into the provided structure. When the file cannot
+// be parsed, its backup is loaded instead and the recovery is listed by
+// Recoveries. With SetRestoreBackups, the backup is also restored in place of
+// the file and the unreadable file is kept next to it with CorruptSuffix. An
+// empty file is valid and loads as the zero value.
 func LoadYAML(path string, v interface{}) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
-	return yaml.Unmarshal(data, v)
+	parseErr := yaml.Unmarshal(data, v)
+	if parseErr == nil {
+		return nil
+	}
+
+	backup, err := os.ReadFile(path + BackupSuffix)
+	if err != nil || len(bytes.TrimSpace(backup)) == 0 {
+		return parseErr // Nothing to recover from
+	}
+	// Drop whatever the unreadable file partially decoded
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv.Elem().Set(reflect.Zero(rv.Elem().Type()))
+	}
+	if err := yaml.Unmarshal(backup, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w (backup is unreadable too: %v)", path, parseErr, err)
+	}
+
+	recoveriesMu.Lock()
+	defer recoveriesMu.Unlock()
+	recovery := Recovery{Path: path, Err: parseErr.Error(), Time: time.Now()}
+	if restoreBackups {
+		if err := restoreBackup(path, data, backup); err != nil {
+			return fmt.Errorf("failed to restore %s from backup: %w", path, err)
+		}
+		recovery.Restored = true
+		recovery.Corrupt = path + CorruptSuffix
+	} else {
+		for _, r := range recoveries {
+			if r.Path == path && !r.Restored {
+				return nil // Already flagged; the file is read from its backup every time
+			}
+		}
+	}
+	recoveries = append(recoveries, recovery)
+	return nil
+}
+
+// restoreBackup keeps the unreadable data aside and puts the backup back in place
+func restoreBackup(path string, data, backup []byte) error {
+	mode := fileMode(path, 0644)
+	if err := writeAtomic(path+CorruptSuffix, data, mode); err != nil {
+		return err
+	}
+	return writeAtomic(path, backup, mode)
 }
 
-// SaveYAML saves a structure to a YAML file
+// SaveYAML saves a structure to a YAML file, atomically and with a backup of
+// the previous version (see WriteFileAtomic)
 func SaveYAML(path string, v interface{}) error {
 	data, err := yaml.Marshal(v)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	return WriteFileAtomic(path, data, 0644)
 }
@@ -0,0 +1,135 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type doc struct {
+	Name  string `yaml:"name"`
+	Items []int  `yaml:"items"`
+}
+
+// TestSaveYAMLBackup checks that saves keep the previous version as backup
+func TestSaveYAMLBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "site.yaml")
+	for _, name := range []string{"first", "second"} {
+		if err := SaveYAML(path, &doc{Name: name}); err != nil {
+			t.Fatalf("SaveYAML failed: %v", err)
+		}
+	}
+
+	var current, backup doc
+	if err := LoadYAML(path, &current); err != nil || current.Name != "second" {
+		t.Errorf("Expected second, got %+v (%v)", current, err)
+	}
+	if err := LoadYAML(path+BackupSuffix, &backup); err != nil || backup.Name != "first" {
+		t.Errorf("Expected first in backup, got %+v (%v)", backup, err)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 2 {
+		t.Errorf("Expected only the file and its backup, got %d entries", len(entries))
+	}
+}
+
+// saveBroken saves two versions of a document and then overwrites the file
+// with broken data, returning its path
+func saveBroken(t *testing.T, broken string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "layout.yaml")
+	if err := SaveYAML(path, &doc{Name: "good", Items: []int{1, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveYAML(path, &doc{Name: "newer"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const unparsable = "name: [unterminated\nitems: {"
+
+// TestLoadYAMLRecovery checks that the builder restores unreadable files from their backups
+func TestLoadYAMLRecovery(t *testing.T) {
+	DismissRecoveries()
+	SetRestoreBackups(true)
+	defer SetRestoreBackups(false)
+	path := saveBroken(t, unparsable)
+
+	var loaded doc
+	if err := LoadYAML(path, &loaded); err != nil {
+		t.Fatalf("LoadYAML failed: %v", err)
+	}
+	if loaded.Name != "good" || len(loaded.Items) != 2 {
+		t.Errorf("Expected the backup's contents, got %+v", loaded)
+	}
+
+	recoveries := Recoveries()
+	if len(recoveries) != 1 || recoveries[0].Path != path || !recoveries[0].Restored {
+		t.Fatalf("Expected one restore of %s, got %+v", path, recoveries)
+	}
+	if data, err := os.ReadFile(recoveries[0].Corrupt); err != nil || string(data) != unparsable {
+		t.Errorf("Expected the unreadable file to be kept, got %q (%v)", data, err)
+	}
+
+	// The file itself is repaired
+	var reloaded doc
+	if err := LoadYAML(path, &reloaded); err != nil || reloaded.Name != "good" || len(Recoveries()) != 1 {
+		t.Errorf("Expected the restored file to load without another recovery, got %+v (%v)", reloaded, err)
+	}
+}
+
+// TestLoadYAMLReadOnly checks that unreadable files are read from their
+// backups but left untouched outside the builder
+func TestLoadYAMLReadOnly(t *testing.T) {
+	DismissRecoveries()
+	path := saveBroken(t, unparsable)
+
+	for i := 0; i < 2; i++ {
+		var loaded doc
+		if err := LoadYAML(path, &loaded); err != nil || loaded.Name != "good" {
+			t.Errorf("Expected the backup's contents, got %+v (%v)", loaded, err)
+		}
+	}
+	if recoveries := Recoveries(); len(recoveries) != 1 || recoveries[0].Restored || recoveries[0].Corrupt != "" {
+		t.Errorf("Expected the file to be flagged once without a restore, got %+v", recoveries)
+	}
+	if data, _ := os.ReadFile(path); string(data) != unparsable {
+		t.Errorf("Expected the file to be left as it was, got %q", data)
+	}
+	if _, err := os.Stat(path + CorruptSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected no %s file, got %v", CorruptSuffix, err)
+	}
+}
+
+// TestLoadYAMLEmpty checks that an emptied file is loaded as it is, not replaced by its backup
+func TestLoadYAMLEmpty(t *testing.T) {
+	DismissRecoveries()
+	SetRestoreBackups(true)
+	defer SetRestoreBackups(false)
+	path := saveBroken(t, "")
+
+	var loaded doc
+	if err := LoadYAML(path, &loaded); err != nil || loaded.Name != "" || len(Recoveries()) != 0 {
+		t.Errorf("Expected the empty file to load without a recovery, got %+v (%v, %+v)", loaded, err, Recoveries())
+	}
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Errorf("Expected the file to stay empty, got %q", data)
+	}
+}
+
+// TestLoadYAMLWithoutBackup checks that parse errors are returned when there is nothing to recover from
+func TestLoadYAMLWithoutBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meta.yaml")
+	if err := os.WriteFile(path, []byte("name: [oops"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var loaded doc
+	if err := LoadYAML(path, &loaded); err == nil {
+		t.Error("Expected a parse error")
+	}
+}